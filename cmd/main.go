@@ -2,13 +2,17 @@ package main
 
 import (
 	"context"
+	"errors"
 	"gift-bot"
 	"gift-bot/internal/handler"
 	"gift-bot/internal/repository"
 	"gift-bot/internal/service"
 	"gift-bot/pkg/config"
 	"gift-bot/pkg/postgres"
+	"gift-bot/pkg/scheduler"
+	"gift-bot/pkg/sqlite"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -31,76 +35,151 @@ func main() {
 		Jitter:      0.2,
 	}
 
-	dbManager, err := postgres.NewManager(ctx, retryCfg, log.Printf)
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		// Подкоманде некого ждать: если БД недоступна минуту, лучше сообщить об ошибке
+		retryCfg.MaxElapsed = time.Minute
+		os.Exit(runMigrate(ctx, retryCfg, os.Args[2:]))
+	}
+
+	dbManager, pgManager, err := openStorage(ctx, retryCfg)
 	if err != nil {
-		log.Fatalf("can't create new postgres db: %s", err.Error())
+		log.Fatalf("can't open %s db: %s", config.GlobalСonfig.Storage, err.Error())
+	}
+	if config.GlobalСonfig.MigrateOnStart {
+		if err = migrateUp(ctx, dbManager.DB()); err != nil {
+			log.Fatalf("couldn't run database migrations: %s", err.Error())
+		}
+	}
+	if pgManager != nil {
+		go pgManager.MonitorAndReconnect(ctx, 5*time.Second)
 	}
-	postgres.MigrateDB(dbManager.DB(), config.GlobalСonfig.DB.Name)
-	go dbManager.MonitorAndReconnect(ctx, 5*time.Second)
 
 	repos := repository.NewRepositories(dbManager)
 	services := service.NewServices(repos)
 	handlers := handler.NewHandlers(services)
 
-	// UTC+3
-	loc, err := time.LoadLocation("Europe/Moscow")
-	if err != nil {
+	// Новая установка получает владельца из конфига; если он ещё не зарегистрирован, роль выдаётся при регистрации
+	if ownerID := config.GlobalСonfig.Telegram.OwnerTelegramID; ownerID != 0 {
+		if promoted, err := services.UserService.BootstrapOwner(ctx, ownerID); err != nil {
+			log.Printf("Failed to bootstrap owner %d: %v", ownerID, err)
+		} else if promoted {
+			log.Printf("User %d is now the bot owner", ownerID)
+		}
+	}
+
+	// UTC+3; в этом поясе считаются расписания задач
+	if _, err := time.LoadLocation(config.GlobalСonfig.ServerConfig.Timezone); err != nil {
 		log.Fatalf("Failed to load location: %v", err)
 	}
 
-	log.Println("Timezone set to Europe/Moscow")
+	log.Printf("Timezone set to %s", config.GlobalСonfig.ServerConfig.Timezone)
 
-	go services.TelegramService.Start()
+	if n, err := services.HolidayService.LoadHolidaysFromFile(ctx, config.GlobalСonfig.Holidays.File); err != nil {
+		log.Printf("Failed to load holidays from %s: %v", config.GlobalСonfig.Holidays.File, err)
+	} else {
+		log.Printf("Loaded %d holiday(s) from %s", n, config.GlobalСonfig.Holidays.File)
+	}
 
-	// Запуск горутины для периодического выполнения проверки ближайших дней рождений
-	go func() {
-		for {
-			now := time.Now().In(loc)
-			nextRun := time.Date(now.Year(), now.Month(), now.Day(), 9, 00, 0, 0, loc)
-			if now.After(nextRun) {
-				nextRun = nextRun.Add(24 * time.Hour)
-			}
-			time.Sleep(time.Until(nextRun))
-
-			log.Println("Running scheduled task")
-			services.TelegramService.NotifyUpcomingBirthdays()
+	go services.TelegramService.Start(ctx)
+
+	// Периодические задачи: расписания задаются в конфиге, каждый запуск пишется в job_runs.
+	// Напоминания и поздравления после простоя догоняются за пропущенные даты — дедупликация
+	// в таблицах уведомлений не даёт отправить одно и то же дважды
+	jobs := []struct {
+		name string
+		spec string
+		run  scheduler.Job
+		opts []scheduler.Option
+	}{
+		{"occasion_reminders", config.GlobalСonfig.Jobs.OccasionReminders, func(ctx context.Context, at time.Time) error {
+			return services.TelegramService.NotifyUpcomingOccasions(ctx, at)
+		}, []scheduler.Option{scheduler.CatchUp(config.GlobalСonfig.Jobs.OccasionRemindersCatchUp)}},
+		{"holiday_greetings", config.GlobalСonfig.Jobs.HolidayGreetings, func(ctx context.Context, at time.Time) error {
+			services.TelegramService.SendHolidayGreetings(ctx, at)
+			return nil
+		}, []scheduler.Option{scheduler.CatchUp(config.GlobalСonfig.Jobs.HolidayGreetingsCatchUp)}},
+		{"profile_sync", config.GlobalСonfig.Jobs.ProfileSync, func(ctx context.Context, _ time.Time) error {
+			return services.TelegramService.SyncUserProfiles(ctx)
+		}, nil},
+		{"due_broadcasts", config.GlobalСonfig.Jobs.DueBroadcasts, func(ctx context.Context, _ time.Time) error {
+			services.TelegramService.SendDueBroadcasts(ctx)
+			return nil
+		}, nil},
+	}
+	for _, job := range jobs {
+		if err := services.JobService.AddJob(job.name, job.spec, job.run, job.opts...); err != nil {
+			log.Fatalf("Failed to schedule job: %v", err)
 		}
-	}()
+	}
+	services.JobService.StartJobs(ctx)
+
+	// Несколько экземпляров с общей БД: Telegram опрашивает и задачи выполняет только держатель
+	// advisory-блокировки, остальные обслуживают HTTP API и ждут своей очереди. Блокировка отпускается
+	// после остановки задач, чтобы новый ведущий не начал их, пока старый не закончил
+	electorCtx, stopElector := context.WithCancel(context.Background())
+	electorDone := make(chan struct{})
+	if pgManager != nil {
+		elector := pgManager.NewElector(config.GlobalСonfig.DB.LeaderLockKey, 5*time.Second)
+		go func() {
+			defer close(electorDone)
+			elector.Run(electorCtx, services.LeaderService.SetLeader)
+		}()
+	} else {
+		// Файл SQLite принадлежит одному экземпляру, и он всегда ведущий
+		services.LeaderService.SetLeader(true)
+		close(electorDone)
+	}
 
-	// Ежедневная синхронизация профилей пользователей (ник/имя/фамилия)
+	gin.SetMode(config.GlobalСonfig.ServerConfig.GinMode)
+	srv := new(wifi.Server)
 	go func() {
-		for {
-			now := time.Now().In(loc)
-			nextRun := time.Date(now.Year(), now.Month(), now.Day(), 4, 0, 0, 0, loc)
-
-			if now.After(nextRun) {
-				nextRun = nextRun.Add(24 * time.Hour)
-			}
-			time.Sleep(time.Until(nextRun))
-
-			log.Println("Running daily user profile sync")
-			services.TelegramService.SyncUserProfiles()
+		if err := srv.Run(ctx, config.GlobalСonfig.ServerConfig.Host, config.GlobalСonfig.ServerConfig.Port, handlers.InitRoutes()); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Error occurred while running http server, %s", err.Error())
 		}
 	}()
-
-	gin.SetMode(config.GlobalСonfig.ServerConfig.GinMode)
-	srv := new(wifi.Server)
-	if err := srv.Run(config.GlobalСonfig.ServerConfig.Port, handlers.InitRoutes()); err != nil {
-		log.Fatalf("Error occurred while running http server, %s", err.Error())
-	}
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
 	<-quit
-	cancel()
 
 	log.Print("GiftBot project Shutting Down")
 
-	if err = srv.Shutdown(context.Background()); err != nil {
-		log.Fatalf("error occured on server shutting down: %s", err.Error())
+	// Отмена ctx прерывает запросы к БД в обработчиках HTTP и Telegram и в выполняющихся задачах
+	cancel()
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelShutdown()
+	if err = srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("error occured on server shutting down: %s", err.Error())
 	}
+	services.JobService.StopJobs()
+	stopElector()
+	<-electorDone
 
 	if err = dbManager.Close(); err != nil {
 		log.Fatalf("error occured on db connection close: %s", err.Error())
 	}
 
 }
+
+// storage — соединение с БД выбранного хранилища
+type storage interface {
+	repository.DBProvider
+	Close() error
+}
+
+// openStorage подключается к хранилищу из конфига. Postgres нужен для нескольких экземпляров бота;
+// небольшой команде хватит встроенного SQLite. Для Postgres возвращается и менеджер: через него
+// работают переподключение и выбор ведущего
+func openStorage(ctx context.Context, retryCfg postgres.RetryConfig) (storage, *postgres.Manager, error) {
+	if config.GlobalСonfig.Storage == config.StorageSQLite {
+		sqliteManager, err := sqlite.Open(ctx, config.GlobalСonfig.SQLite.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+		return sqliteManager, nil, nil
+	}
+	pgManager, err := postgres.NewManager(ctx, retryCfg, log.Printf)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pgManager, pgManager, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gift-bot/pkg/config"
+	"gift-bot/pkg/migrations"
+	"gift-bot/pkg/postgres"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/jmoiron/sqlx"
+)
+
+const migrateUsage = `usage: gift-bot migrate <command>
+
+commands:
+  up              apply all pending migrations
+  down [N]        roll back the last N migrations (default 1)
+  status          print the applied and the latest embedded migration version
+  force VERSION   mark VERSION as applied and clear the dirty flag without running migrations`
+
+// migrateUp применяет встроенные миграции при запуске бота
+func migrateUp(ctx context.Context, db *sqlx.DB) error {
+	m, err := migrations.New(ctx, db, config.GlobalСonfig.Storage)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil {
+		var dirty migrate.ErrDirty
+		if errors.As(err, &dirty) {
+			return fmt.Errorf("migration %d failed midway; fix the schema and run `gift-bot migrate force <version>`", dirty.Version)
+		}
+		return err
+	}
+	st, err := m.Status()
+	if err != nil {
+		return err
+	}
+	log.Printf("database schema is at version %d", st.Version)
+	return nil
+}
+
+// runMigrate выполняет подкоманду migrate и возвращает код выхода
+func runMigrate(ctx context.Context, retryCfg postgres.RetryConfig, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	// Аргументы проверяются до подключения к БД
+	var n int
+	switch cmd := args[0]; {
+	case cmd == "up" && len(args) == 1, cmd == "status" && len(args) == 1:
+	case cmd == "down" && len(args) <= 2:
+		n = 1
+		if len(args) == 2 {
+			var err error
+			if n, err = strconv.Atoi(args[1]); err != nil || n <= 0 {
+				fmt.Fprintf(os.Stderr, "invalid number of migrations %q\n\n%s\n", args[1], migrateUsage)
+				return 2
+			}
+		}
+	case cmd == "force" && len(args) == 2:
+		var err error
+		if n, err = strconv.Atoi(args[1]); err != nil || n < 0 {
+			fmt.Fprintf(os.Stderr, "invalid version %q\n\n%s\n", args[1], migrateUsage)
+			return 2
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	dbManager, _, err := openStorage(ctx, retryCfg)
+	if err != nil {
+		log.Printf("can't open %s db: %s", config.GlobalСonfig.Storage, err.Error())
+		return 1
+	}
+	defer dbManager.Close()
+
+	m, err := migrations.New(ctx, dbManager.DB(), config.GlobalСonfig.Storage)
+	if err != nil {
+		log.Printf("couldn't prepare migrations: %s", err.Error())
+		return 1
+	}
+	defer m.Close()
+
+	switch args[0] {
+	case "up":
+		err = m.Up()
+	case "down":
+		err = m.Down(n)
+	case "force":
+		err = m.Force(n)
+	}
+	if err != nil {
+		log.Printf("migrate %s failed: %s", args[0], err.Error())
+		return 1
+	}
+
+	st, err := m.Status()
+	if err != nil {
+		log.Printf("couldn't read migration status: %s", err.Error())
+		return 1
+	}
+	fmt.Printf("storage: %s\nversion: %d\nlatest:  %d\n", config.GlobalСonfig.Storage, st.Version, st.Latest)
+	if st.Dirty {
+		fmt.Printf("dirty:   migration %d failed midway; fix the schema and run `gift-bot migrate force <version>`\n", st.Version)
+	}
+	return 0
+}
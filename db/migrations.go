@@ -0,0 +1,14 @@
+// Package db встраивает миграции в бинарник: бот не зависит от рабочего каталога и каталога db/ рядом с собой.
+package db
+
+import "embed"
+
+// PostgresMigrations — миграции Postgres из каталога migrations.
+//
+//go:embed migrations/*.sql
+var PostgresMigrations embed.FS
+
+// SQLiteMigrations — миграции SQLite из каталога migrations_sqlite.
+//
+//go:embed migrations_sqlite/*.sql
+var SQLiteMigrations embed.FS
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS santa_pairs;
+DROP TABLE IF EXISTS santa_exclusions;
+DROP TABLE IF EXISTS santa_participants;
+DROP TABLE IF EXISTS santa_events;
+ALTER TABLE users DROP COLUMN team;
@@ -0,0 +1,39 @@
+ALTER TABLE users ADD COLUMN team VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE TABLE santa_events (
+    id SERIAL PRIMARY KEY,
+    title VARCHAR(255) NOT NULL,
+    year INT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'registration',
+    exclude_same_team BOOLEAN NOT NULL DEFAULT TRUE,
+    exclude_previous_pairs BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by BIGINT NOT NULL,
+    draw_digest VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    drawn_at TIMESTAMP
+);
+
+CREATE TABLE santa_participants (
+    event_id INT NOT NULL REFERENCES santa_events (id) ON DELETE CASCADE,
+    telegram_id BIGINT NOT NULL,
+    joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (event_id, telegram_id)
+);
+
+CREATE TABLE santa_exclusions (
+    event_id INT NOT NULL REFERENCES santa_events (id) ON DELETE CASCADE,
+    first_telegram_id BIGINT NOT NULL,
+    second_telegram_id BIGINT NOT NULL,
+    PRIMARY KEY (event_id, first_telegram_id, second_telegram_id)
+);
+
+CREATE TABLE santa_pairs (
+    event_id INT NOT NULL REFERENCES santa_events (id) ON DELETE CASCADE,
+    giver_telegram_id BIGINT NOT NULL,
+    recipient_telegram_id BIGINT NOT NULL,
+    notified_at TIMESTAMP,
+    PRIMARY KEY (event_id, giver_telegram_id)
+);
+
+CREATE UNIQUE INDEX santa_pairs_recipient_unique
+    ON santa_pairs (event_id, recipient_telegram_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS occasion_notifications;
+DROP TABLE IF EXISTS occasions;
+DROP TABLE IF EXISTS occasion_types;
@@ -0,0 +1,38 @@
+CREATE TABLE occasion_types (
+    code VARCHAR(50) PRIMARY KEY,
+    title VARCHAR(255) NOT NULL,
+    recurring BOOLEAN NOT NULL DEFAULT TRUE,
+    lead_days INT NOT NULL DEFAULT 2,
+    reminder_template TEXT NOT NULL
+);
+
+INSERT INTO occasion_types (code, title, recurring, lead_days, reminder_template) VALUES
+    ('birthday', 'День рождения', TRUE, 2, 'У нашего коллеги {user} скоро день рождения! Не забудьте его поздравить!'),
+    ('work_anniversary', 'Годовщина работы', TRUE, 3, 'У коллеги {user} {date} — {years}-я годовщина работы в компании!'),
+    ('name_day', 'Именины', TRUE, 1, 'У коллеги {user} {date} именины!'),
+    ('custom', 'Ежегодное событие', TRUE, 2, 'У коллеги {user} {date} — {title}.'),
+    ('one_off', 'Разовое событие', FALSE, 2, 'У коллеги {user} {date} — {title}.');
+
+CREATE TABLE occasions (
+    id SERIAL PRIMARY KEY,
+    user_telegram_id BIGINT NOT NULL,
+    type_code VARCHAR(50) NOT NULL REFERENCES occasion_types (code),
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    occasion_date DATE NOT NULL,
+    created_by BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX occasions_user_telegram_id_idx
+    ON occasions (user_telegram_id);
+
+CREATE TABLE occasion_notifications (
+    id SERIAL PRIMARY KEY,
+    admin_telegram_id BIGINT NOT NULL,
+    occasion_id INT NOT NULL REFERENCES occasions (id) ON DELETE CASCADE,
+    notify_date DATE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX occasion_notifications_unique
+    ON occasion_notifications (admin_telegram_id, occasion_id, notify_date);
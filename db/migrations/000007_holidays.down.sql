@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS holiday_deliveries;
+DROP TABLE IF EXISTS holidays;
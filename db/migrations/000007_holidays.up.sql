@@ -0,0 +1,23 @@
+CREATE TABLE holidays (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(100) UNIQUE NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    month INT NOT NULL,
+    day INT NOT NULL,
+    year INT NOT NULL DEFAULT 0,
+    greeting TEXT NOT NULL,
+    greeting_override TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE holiday_deliveries (
+    id SERIAL PRIMARY KEY,
+    holiday_id INT NOT NULL REFERENCES holidays (id) ON DELETE CASCADE,
+    telegram_id BIGINT NOT NULL,
+    greet_date DATE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX holiday_deliveries_unique
+    ON holiday_deliveries (holiday_id, telegram_id, greet_date);
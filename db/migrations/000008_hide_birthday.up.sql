@@ -0,0 +1 @@
+ALTER TABLE users ADD COLUMN hide_birthday BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS users_calendar_token_hash_unique;
+ALTER TABLE users DROP COLUMN calendar_token_hash;
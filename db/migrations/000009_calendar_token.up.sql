@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN calendar_token_hash VARCHAR(64);
+
+CREATE UNIQUE INDEX users_calendar_token_hash_unique
+    ON users (calendar_token_hash);
@@ -0,0 +1,13 @@
+CREATE TABLE invite_codes (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(64) UNIQUE NOT NULL,
+    created_by BIGINT NOT NULL,
+    team VARCHAR(255) NOT NULL DEFAULT '',
+    max_uses INT NOT NULL DEFAULT 1,
+    uses INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    revoked BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+ALTER TABLE users ADD COLUMN invite_code_id INT REFERENCES invite_codes (id) ON DELETE SET NULL;
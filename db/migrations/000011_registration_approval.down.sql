@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN approved_at;
+ALTER TABLE users DROP COLUMN approved_by;
+ALTER TABLE users DROP COLUMN status;
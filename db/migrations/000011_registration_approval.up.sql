@@ -0,0 +1,3 @@
+ALTER TABLE users ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'active';
+ALTER TABLE users ADD COLUMN approved_by BIGINT;
+ALTER TABLE users ADD COLUMN approved_at TIMESTAMP;
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN notify_reminders;
+ALTER TABLE users DROP COLUMN notify_holidays;
+ALTER TABLE users DROP COLUMN display_name;
@@ -0,0 +1,8 @@
+CREATE TABLE login_attempts (
+    telegram_id BIGINT PRIMARY KEY,
+    username VARCHAR(255) NOT NULL DEFAULT '',
+    failed_attempts INT NOT NULL DEFAULT 0,
+    lockouts INT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP,
+    last_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS users_single_owner;
+
+UPDATE users SET role = 'admin' WHERE role = 'owner';
+UPDATE users SET role = 'user' WHERE role = 'organiser';
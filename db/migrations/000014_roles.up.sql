@@ -0,0 +1,6 @@
+-- Первый по времени регистрации администратор становится владельцем бота
+UPDATE users SET role = 'owner'
+WHERE id = (SELECT id FROM users WHERE role = 'admin' ORDER BY created_at, id LIMIT 1)
+  AND NOT EXISTS (SELECT 1 FROM users WHERE role = 'owner');
+
+CREATE UNIQUE INDEX users_single_owner ON users (role) WHERE role = 'owner';
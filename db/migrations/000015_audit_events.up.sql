@@ -0,0 +1,13 @@
+CREATE TABLE audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    actor_telegram_id BIGINT NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    target_telegram_id BIGINT,
+    payload JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
+CREATE INDEX audit_events_action_idx ON audit_events (action);
+CREATE INDEX audit_events_actor_idx ON audit_events (actor_telegram_id);
+CREATE INDEX audit_events_target_idx ON audit_events (target_telegram_id);
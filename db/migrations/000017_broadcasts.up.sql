@@ -0,0 +1,27 @@
+CREATE TABLE broadcasts (
+    id BIGSERIAL PRIMARY KEY,
+    text TEXT NOT NULL,
+    is_template BOOLEAN NOT NULL DEFAULT false,
+    audience VARCHAR(20) NOT NULL,
+    teams TEXT[] NOT NULL DEFAULT '{}',
+    user_ids BIGINT[] NOT NULL DEFAULT '{}',
+    excluded_ids BIGINT[] NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+    scheduled_at TIMESTAMP NOT NULL,
+    created_by BIGINT NOT NULL,
+    api_key_id BIGINT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX broadcasts_due_idx ON broadcasts (scheduled_at) WHERE status = 'scheduled';
+
+CREATE TABLE broadcast_deliveries (
+    broadcast_id BIGINT NOT NULL REFERENCES broadcasts (id) ON DELETE CASCADE,
+    user_telegram_id BIGINT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    error TEXT NOT NULL DEFAULT '',
+    sent_at TIMESTAMP,
+    PRIMARY KEY (broadcast_id, user_telegram_id)
+);
@@ -0,0 +1,6 @@
+-- Логическое время запуска: по нему догоняются запуски, пропущенные, пока бот не работал
+ALTER TABLE job_runs ADD COLUMN scheduled_for TIMESTAMP;
+UPDATE job_runs SET scheduled_for = started_at;
+ALTER TABLE job_runs ALTER COLUMN scheduled_for SET NOT NULL;
+
+CREATE INDEX job_runs_job_scheduled_idx ON job_runs (job, scheduled_for DESC);
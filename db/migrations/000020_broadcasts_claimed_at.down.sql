@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS broadcasts_claimed_idx;
+ALTER TABLE broadcasts DROP COLUMN IF EXISTS claimed_at;
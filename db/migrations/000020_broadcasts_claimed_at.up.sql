@@ -0,0 +1,5 @@
+-- Время последнего подтверждения отправки: рассылку, которая давно не подтверждалась, забирает другой обработчик
+ALTER TABLE broadcasts ADD COLUMN claimed_at TIMESTAMP;
+UPDATE broadcasts SET claimed_at = started_at WHERE status = 'sending';
+
+CREATE INDEX broadcasts_claimed_idx ON broadcasts (claimed_at) WHERE status = 'sending';
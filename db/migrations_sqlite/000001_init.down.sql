@@ -0,0 +1,18 @@
+DROP TABLE IF EXISTS job_runs;
+DROP TABLE IF EXISTS broadcast_deliveries;
+DROP TABLE IF EXISTS broadcasts;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS audit_events;
+DROP TABLE IF EXISTS login_attempts;
+DROP TABLE IF EXISTS holiday_deliveries;
+DROP TABLE IF EXISTS holidays;
+DROP TABLE IF EXISTS occasion_notifications;
+DROP TABLE IF EXISTS occasions;
+DROP TABLE IF EXISTS occasion_types;
+DROP TABLE IF EXISTS santa_pairs;
+DROP TABLE IF EXISTS santa_exclusions;
+DROP TABLE IF EXISTS santa_participants;
+DROP TABLE IF EXISTS santa_events;
+DROP TABLE IF EXISTS birthday_notifications;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS invite_codes;
@@ -0,0 +1,228 @@
+-- Схема SQLite соответствует миграциям Postgres 000001–000019.
+-- Массивы (TEXT[], BIGINT[]) хранятся строкой в формате массивов Postgres ({a,b}): репозитории читают
+-- и пишут их через pq.Array одинаково для обоих хранилищ. Время хранится строкой в местном поясе.
+
+CREATE TABLE invite_codes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    code VARCHAR(64) UNIQUE NOT NULL,
+    created_by BIGINT NOT NULL,
+    team VARCHAR(255) NOT NULL DEFAULT '',
+    max_uses INT NOT NULL DEFAULT 1,
+    uses INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    revoked BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    telegram_id BIGINT UNIQUE NOT NULL,
+    username VARCHAR(255),
+    first_name VARCHAR(255),
+    last_name VARCHAR(255),
+    role VARCHAR(50) NOT NULL DEFAULT 'user',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    birthdate DATE,
+    blocked BOOLEAN DEFAULT FALSE,
+    team VARCHAR(255) NOT NULL DEFAULT '',
+    hide_birthday BOOLEAN NOT NULL DEFAULT FALSE,
+    calendar_token_hash VARCHAR(64),
+    invite_code_id INT REFERENCES invite_codes (id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    approved_by BIGINT,
+    approved_at TIMESTAMP,
+    display_name VARCHAR(64) NOT NULL DEFAULT '',
+    notify_holidays BOOLEAN NOT NULL DEFAULT TRUE,
+    notify_reminders BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+CREATE UNIQUE INDEX users_calendar_token_hash_unique ON users (calendar_token_hash);
+CREATE UNIQUE INDEX users_single_owner ON users (role) WHERE role = 'owner';
+
+CREATE TABLE birthday_notifications (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    admin_telegram_id BIGINT NOT NULL,
+    user_telegram_id BIGINT NOT NULL,
+    notify_date DATE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX birthday_notifications_unique
+    ON birthday_notifications (admin_telegram_id, user_telegram_id, notify_date);
+CREATE INDEX birthday_notifications_notify_date_idx ON birthday_notifications (notify_date);
+
+CREATE TABLE santa_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    title VARCHAR(255) NOT NULL,
+    year INT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'registration',
+    exclude_same_team BOOLEAN NOT NULL DEFAULT TRUE,
+    exclude_previous_pairs BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by BIGINT NOT NULL,
+    draw_digest VARCHAR(64) NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    drawn_at TIMESTAMP
+);
+
+CREATE TABLE santa_participants (
+    event_id INT NOT NULL REFERENCES santa_events (id) ON DELETE CASCADE,
+    telegram_id BIGINT NOT NULL,
+    joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (event_id, telegram_id)
+);
+
+CREATE TABLE santa_exclusions (
+    event_id INT NOT NULL REFERENCES santa_events (id) ON DELETE CASCADE,
+    first_telegram_id BIGINT NOT NULL,
+    second_telegram_id BIGINT NOT NULL,
+    PRIMARY KEY (event_id, first_telegram_id, second_telegram_id)
+);
+
+CREATE TABLE santa_pairs (
+    event_id INT NOT NULL REFERENCES santa_events (id) ON DELETE CASCADE,
+    giver_telegram_id BIGINT NOT NULL,
+    recipient_telegram_id BIGINT NOT NULL,
+    notified_at TIMESTAMP,
+    PRIMARY KEY (event_id, giver_telegram_id)
+);
+
+CREATE UNIQUE INDEX santa_pairs_recipient_unique ON santa_pairs (event_id, recipient_telegram_id);
+
+CREATE TABLE occasion_types (
+    code VARCHAR(50) PRIMARY KEY,
+    title VARCHAR(255) NOT NULL,
+    recurring BOOLEAN NOT NULL DEFAULT TRUE,
+    lead_days INT NOT NULL DEFAULT 2,
+    reminder_template TEXT NOT NULL
+);
+
+INSERT INTO occasion_types (code, title, recurring, lead_days, reminder_template) VALUES
+    ('birthday', 'День рождения', TRUE, 2, 'У нашего коллеги {user} скоро день рождения! Не забудьте его поздравить!'),
+    ('work_anniversary', 'Годовщина работы', TRUE, 3, 'У коллеги {user} {date} — {years}-я годовщина работы в компании!'),
+    ('name_day', 'Именины', TRUE, 1, 'У коллеги {user} {date} именины!'),
+    ('custom', 'Ежегодное событие', TRUE, 2, 'У коллеги {user} {date} — {title}.'),
+    ('one_off', 'Разовое событие', FALSE, 2, 'У коллеги {user} {date} — {title}.');
+
+CREATE TABLE occasions (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_telegram_id BIGINT NOT NULL,
+    type_code VARCHAR(50) NOT NULL REFERENCES occasion_types (code),
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    occasion_date DATE NOT NULL,
+    created_by BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX occasions_user_telegram_id_idx ON occasions (user_telegram_id);
+
+CREATE TABLE occasion_notifications (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    admin_telegram_id BIGINT NOT NULL,
+    occasion_id INT NOT NULL REFERENCES occasions (id) ON DELETE CASCADE,
+    notify_date DATE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX occasion_notifications_unique
+    ON occasion_notifications (admin_telegram_id, occasion_id, notify_date);
+
+CREATE TABLE holidays (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    code VARCHAR(100) UNIQUE NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    month INT NOT NULL,
+    day INT NOT NULL,
+    year INT NOT NULL DEFAULT 0,
+    greeting TEXT NOT NULL,
+    greeting_override TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE holiday_deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    holiday_id INT NOT NULL REFERENCES holidays (id) ON DELETE CASCADE,
+    telegram_id BIGINT NOT NULL,
+    greet_date DATE NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX holiday_deliveries_unique ON holiday_deliveries (holiday_id, telegram_id, greet_date);
+
+CREATE TABLE login_attempts (
+    telegram_id BIGINT PRIMARY KEY,
+    username VARCHAR(255) NOT NULL DEFAULT '',
+    failed_attempts INT NOT NULL DEFAULT 0,
+    lockouts INT NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP,
+    last_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE audit_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    actor_telegram_id BIGINT NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    target_telegram_id BIGINT,
+    payload TEXT NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
+CREATE INDEX audit_events_action_idx ON audit_events (action);
+CREATE INDEX audit_events_actor_idx ON audit_events (actor_telegram_id);
+CREATE INDEX audit_events_target_idx ON audit_events (target_telegram_id);
+
+CREATE TABLE api_keys (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(100) NOT NULL,
+    key_prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    created_by BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE TABLE broadcasts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    text TEXT NOT NULL,
+    is_template BOOLEAN NOT NULL DEFAULT FALSE,
+    audience VARCHAR(20) NOT NULL,
+    teams TEXT NOT NULL DEFAULT '{}',
+    user_ids TEXT NOT NULL DEFAULT '{}',
+    excluded_ids TEXT NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled',
+    scheduled_at TIMESTAMP NOT NULL,
+    created_by BIGINT NOT NULL,
+    api_key_id BIGINT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX broadcasts_due_idx ON broadcasts (scheduled_at) WHERE status = 'scheduled';
+
+CREATE TABLE broadcast_deliveries (
+    broadcast_id BIGINT NOT NULL REFERENCES broadcasts (id) ON DELETE CASCADE,
+    user_telegram_id BIGINT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    error TEXT NOT NULL DEFAULT '',
+    sent_at TIMESTAMP,
+    PRIMARY KEY (broadcast_id, user_telegram_id)
+);
+
+CREATE TABLE job_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    job VARCHAR(64) NOT NULL,
+    trigger VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'running',
+    error TEXT NOT NULL DEFAULT '',
+    scheduled_for TIMESTAMP NOT NULL,
+    started_at TIMESTAMP NOT NULL,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX job_runs_job_started_idx ON job_runs (job, started_at DESC);
+CREATE INDEX job_runs_job_scheduled_idx ON job_runs (job, scheduled_for DESC);
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"errors"
+	"gift-bot/internal/service"
+	"gift-bot/pkg/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// apiKeyContextKey — ключ gin.Context, под которым лежит проверенный models.APIKey.
+const apiKeyContextKey = "api_key"
+
+// requireAPIKey пропускает запросы с действующим ключом в заголовке Authorization: Bearer <ключ>.
+func (h *Handlers) requireAPIKey(c *gin.Context) {
+	plain, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		abortWithError(c, http.StatusUnauthorized, "missing api key")
+		return
+	}
+	key, err := h.services.APIKeyService.AuthenticateAPIKey(c.Request.Context(), strings.TrimSpace(plain))
+	if errors.Is(err, service.ErrAPIKeyInvalid) {
+		abortWithError(c, http.StatusUnauthorized, "invalid api key")
+		return
+	}
+	if err != nil {
+		log.Errorf("authenticate api key err: %v", err)
+		abortWithError(c, http.StatusInternalServerError, "internal error")
+		return
+	}
+	c.Set(apiKeyContextKey, key)
+	c.Next()
+}
+
+// requireScope проверяет область доступа ключа. Ставится после requireAPIKey.
+func requireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !service.APIKeyHasScope(apiKey(c), scope) {
+			abortWithError(c, http.StatusForbidden, "api key lacks scope "+scope)
+			return
+		}
+		c.Next()
+	}
+}
+
+func apiKey(c *gin.Context) models.APIKey {
+	key, _ := c.MustGet(apiKeyContextKey).(models.APIKey)
+	return key
+}
+
+// audit записывает в журнал действие, выполненное через API, с указанием ключа.
+func (h *Handlers) audit(c *gin.Context, action string, targetTelegramID int64, payload map[string]interface{}) {
+	h.services.AuditService.Record(c.Request.Context(), models.AuditActorAPI, action, targetTelegramID, apiKeyPayload(c, payload))
+}
+
+// apiKeyPayload дополняет payload события журнала ключом, с которым пришёл запрос.
+func apiKeyPayload(c *gin.Context, payload map[string]interface{}) map[string]interface{} {
+	if payload == nil {
+		payload = map[string]interface{}{}
+	}
+	key := apiKey(c)
+	payload["api_key_id"] = key.ID
+	payload["api_key"] = key.Name
+	return payload
+}
+
+func abortWithError(c *gin.Context, status int, message string) {
+	c.AbortWithStatusJSON(status, gin.H{"error": message})
+}
+
+// page — общий формат ответа для списков: элементы страницы и общее число записей под фильтром.
+type page struct {
+	Items  interface{} `json:"items"`
+	Total  int         `json:"total"`
+	Limit  int         `json:"limit"`
+	Offset int         `json:"offset"`
+}
+
+// pageParams читает limit и offset и приводит их к границам, которые применяет сервис.
+func pageParams(c *gin.Context) (limit, offset int, ok bool) {
+	l, err := queryInt64(c, "limit")
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "invalid limit")
+		return 0, 0, false
+	}
+	o, err := queryInt64(c, "offset")
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "invalid offset")
+		return 0, 0, false
+	}
+	limit, offset = service.PageBounds(int(l), int(o))
+	return limit, offset, true
+}
+
+func queryInt64(c *gin.Context, key string) (int64, error) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func queryTime(c *gin.Context, key string) (time.Time, error) {
+	value := c.Query(key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"gift-bot/pkg/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// auditEvents отдаёт журнал действий администраторов:
+// GET /api/audit?action=&actor=&target=&since=&until=&limit=&offset=
+// since и until принимаются в формате RFC 3339.
+func (h *Handlers) auditEvents(c *gin.Context) {
+	filter := models.AuditFilter{Action: c.Query("action")}
+	var err error
+	if filter.ActorTelegramID, err = queryInt64(c, "actor"); err != nil {
+		abortWithError(c, http.StatusBadRequest, "invalid actor")
+		return
+	}
+	if filter.TargetTelegramID, err = queryInt64(c, "target"); err != nil {
+		abortWithError(c, http.StatusBadRequest, "invalid target")
+		return
+	}
+	if filter.Since, err = queryTime(c, "since"); err != nil {
+		abortWithError(c, http.StatusBadRequest, "invalid since")
+		return
+	}
+	if filter.Until, err = queryTime(c, "until"); err != nil {
+		abortWithError(c, http.StatusBadRequest, "invalid until")
+		return
+	}
+	var ok bool
+	if filter.Limit, filter.Offset, ok = pageParams(c); !ok {
+		return
+	}
+
+	events, total, err := h.services.AuditService.GetAuditEvents(c.Request.Context(), filter)
+	if err != nil {
+		log.Errorf("get audit events err: %v", err)
+		abortWithError(c, http.StatusInternalServerError, "internal error")
+		return
+	}
+	c.JSON(http.StatusOK, page{Items: events, Total: total, Limit: filter.Limit, Offset: filter.Offset})
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"gift-bot/internal/service"
+	"gift-bot/pkg/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// broadcastRequest — тело POST /api/broadcasts. Нужно передать ровно одно из text и template.
+type broadcastRequest struct {
+	Text        string     `json:"text"`
+	Template    string     `json:"template"`
+	Audience    string     `json:"audience"`
+	Teams       []string   `json:"teams"`
+	UserIDs     []int64    `json:"user_ids"`
+	ScheduledAt *time.Time `json:"scheduled_at"`
+}
+
+type broadcastResponse struct {
+	models.Broadcast
+	Delivery models.BroadcastStats `json:"delivery"`
+}
+
+// createBroadcast ставит рассылку в очередь и сразу возвращает её ID. Рассылки без времени отправки уходят немедленно.
+func (h *Handlers) createBroadcast(c *gin.Context) {
+	var req broadcastRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, "invalid body")
+		return
+	}
+	if (req.Text == "") == (req.Template == "") {
+		abortWithError(c, http.StatusBadRequest, "exactly one of text and template is required")
+		return
+	}
+
+	key := apiKey(c)
+	broadcast := models.Broadcast{
+		Text:       req.Text,
+		IsTemplate: req.Template != "",
+		Audience:   strings.ToLower(req.Audience),
+		Teams:      req.Teams,
+		UserIDs:    req.UserIDs,
+		CreatedBy:  models.AuditActorAPI,
+		APIKeyID:   &key.ID,
+	}
+	if broadcast.IsTemplate {
+		broadcast.Text = req.Template
+	}
+	if broadcast.Audience == "" {
+		broadcast.Audience = models.BroadcastAudienceAll
+	}
+	if req.ScheduledAt != nil {
+		broadcast.ScheduledAt = *req.ScheduledAt
+	}
+
+	broadcast, err := h.services.BroadcastService.CreateBroadcast(c.Request.Context(), broadcast)
+	if errors.Is(err, service.ErrBroadcastInvalid) {
+		abortWithError(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		log.Errorf("create broadcast err: %v", err)
+		abortWithError(c, http.StatusInternalServerError, "internal error")
+		return
+	}
+	h.audit(c, models.AuditActionBroadcast, 0, map[string]interface{}{
+		"broadcast_id": broadcast.ID, "audience": broadcast.Audience, "scheduled_at": broadcast.ScheduledAt,
+	})
+
+	if !broadcast.ScheduledAt.After(time.Now()) {
+		go h.services.TelegramService.SendDueBroadcasts(context.WithoutCancel(c.Request.Context()))
+	}
+	c.JSON(http.StatusAccepted, broadcastResponse{Broadcast: broadcast})
+}
+
+// getBroadcast отдаёт рассылку и состояние доставки: GET /api/broadcasts/:id
+func (h *Handlers) getBroadcast(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	broadcast, stats, err := h.services.BroadcastService.GetBroadcast(c.Request.Context(), id)
+	if errors.Is(err, service.ErrBroadcastNotFound) {
+		abortWithError(c, http.StatusNotFound, "broadcast not found")
+		return
+	}
+	if err != nil {
+		log.Errorf("get broadcast err: %v", err)
+		abortWithError(c, http.StatusInternalServerError, "internal error")
+		return
+	}
+	c.JSON(http.StatusOK, broadcastResponse{Broadcast: broadcast, Delivery: stats})
+}
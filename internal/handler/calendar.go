@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"errors"
+	"gift-bot/internal/service"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// calendarFeed отдаёт iCalendar-ленту по личному токену: GET /calendar/<token>.ics
+func (h *Handlers) calendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	if token == "" {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	feed, err := h.services.CalendarService.RenderCalendarFeed(c.Request.Context(), token)
+	if errors.Is(err, service.ErrCalendarTokenNotFound) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Errorf("render calendar feed err: %v", err)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", feed)
+}
@@ -1,12 +1,22 @@
 package handler
 
 import (
+	"fmt"
 	"gift-bot/internal/service"
+	"gift-bot/pkg/config"
+	"gift-bot/pkg/metrics"
+	"gift-bot/pkg/models"
 	"gift-bot/pkg/util"
 	"github.com/gin-contrib/pprof"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+	"time"
 )
 
+// requestTimeout совпадает с WriteTimeout HTTP-сервера: дольше ответ всё равно не дойдёт до клиента.
+const requestTimeout = 10 * time.Second
+
 type Handlers struct {
 	services *service.Services
 }
@@ -17,11 +27,61 @@ func NewHandlers(services *service.Services) *Handlers {
 }
 
 func (h *Handlers) InitRoutes() *gin.Engine {
+	router, err := h.routes()
+	if err != nil {
+		log.Fatal(err)
+	}
+	return router
+}
+
+// routes собирает маршруты. Ошибка означает, что не загрузились шаблоны или спецификация
+// либо маршруты /api разошлись со спецификацией.
+func (h *Handlers) routes() (*gin.Engine, error) {
 	router := gin.Default()
-	pprof.Register(router)
-	router.Use(util.CORS())
+	router.Use(metrics.Gin())
+	router.Use(util.CORS(config.GlobalСonfig.ServerConfig.CORSOrigins))
+	router.Use(util.RequestTimeout(requestTimeout))
 
 	router.GET("/ping", func(c *gin.Context) {})
+	router.GET("/healthz", h.healthz)
+	router.GET("/readyz", h.readyz)
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	router.GET("/calendar/:token", h.calendarFeed)
 
-	return router
+	spec, err := loadOpenAPI()
+	if err != nil {
+		return nil, fmt.Errorf("load openapi spec: %w", err)
+	}
+	router.GET("/openapi.json", spec.serveSpec)
+
+	web, err := newWebUI(h.services)
+	if err != nil {
+		return nil, fmt.Errorf("load web templates: %w", err)
+	}
+	web.register(router)
+
+	api := router.Group("/api", h.requireAPIKey, spec.validate)
+	readUsers := requireScope(models.APIScopeReadUsers)
+	writeUsers := requireScope(models.APIScopeWriteUsers)
+	broadcast := requireScope(models.APIScopeBroadcast)
+	admin := requireScope(models.APIScopeAdmin)
+
+	api.GET("/users", readUsers, h.listUsers)
+	api.GET("/users/:telegram_id", readUsers, h.getUser)
+	api.GET("/birthdays", readUsers, h.upcomingBirthdays)
+	api.PATCH("/users/:telegram_id", writeUsers, h.updateUser)
+	api.POST("/users/:telegram_id/block", writeUsers, h.blockUser)
+	api.POST("/users/:telegram_id/unblock", writeUsers, h.unblockUser)
+	api.PUT("/users/:telegram_id/role", admin, h.setUserRole)
+	api.POST("/broadcasts", broadcast, h.createBroadcast)
+	api.GET("/broadcasts/:id", broadcast, h.getBroadcast)
+	api.GET("/audit", admin, h.auditEvents)
+
+	// Профилирование раскрывает внутренности процесса, поэтому доступно только ключам с областью admin
+	pprof.RouteRegister(router.Group("", h.requireAPIKey, admin))
+
+	if err := spec.checkRoutes(router.Routes(), "/api/"); err != nil {
+		return nil, err
+	}
+	return router, nil
 }
@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"gift-bot/pkg/models"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// healthz — проверка живости: 503, если поллер Telegram умер или периодическая задача зависла. Такой бот нужно перезапустить.
+func (h *Handlers) healthz(c *gin.Context) {
+	writeHealth(c, h.services.HealthService.Liveness(c.Request.Context()))
+}
+
+// readyz — проверка готовности: 503, пока недоступна БД или Telegram.
+func (h *Handlers) readyz(c *gin.Context) {
+	writeHealth(c, h.services.HealthService.Readiness(c.Request.Context()))
+}
+
+func writeHealth(c *gin.Context, report models.HealthReport) {
+	status := http.StatusOK
+	if report.Status != models.HealthStatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
@@ -0,0 +1,163 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// openAPISpec — контракт HTTP API. Меняется вместе с обработчиками: расхождение маршрутов останавливает запуск,
+// а ответы, не совпадающие со схемой, попадают в лог (в режиме debug — возвращаются как 500).
+//
+//go:embed openapi.yaml
+var openAPISpec []byte
+
+type openAPI struct {
+	doc    *openapi3.T
+	router routers.Router
+	json   []byte
+}
+
+func loadOpenAPI() (*openAPI, error) {
+	loader := openapi3.NewLoader()
+	doc, err := loader.LoadFromData(openAPISpec)
+	if err != nil {
+		return nil, fmt.Errorf("parse openapi spec: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid openapi spec: %w", err)
+	}
+	router, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("build openapi router: %w", err)
+	}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("marshal openapi spec: %w", err)
+	}
+	return &openAPI{doc: doc, router: router, json: raw}, nil
+}
+
+// serveSpec отдаёт спецификацию: GET /openapi.json
+func (o *openAPI) serveSpec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", o.json)
+}
+
+// validate проверяет запрос по спецификации до обработчика, а ответ — после.
+// Аутентификацию и области доступа проверяют requireAPIKey и requireScope.
+func (o *openAPI) validate(c *gin.Context) {
+	route, pathParams, err := o.router.FindRoute(c.Request)
+	if err != nil {
+		log.Errorf("%s %s is not described in openapi spec: %v", c.Request.Method, c.FullPath(), err)
+		abortWithError(c, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	input := &openapi3filter.RequestValidationInput{
+		Request:    c.Request,
+		PathParams: pathParams,
+		Route:      route,
+		Options:    &openapi3filter.Options{AuthenticationFunc: openapi3filter.NoopAuthenticationFunc},
+	}
+	if err := openapi3filter.ValidateRequest(c.Request.Context(), input); err != nil {
+		abortWithError(c, http.StatusBadRequest, firstLine(err.Error()))
+		return
+	}
+
+	// Ответ буферизуется, чтобы в режиме debug можно было заменить его ошибкой
+	writer := c.Writer
+	recorder := &bufferedWriter{ResponseWriter: writer}
+	c.Writer = recorder
+	c.Next()
+	c.Writer = writer
+
+	err = openapi3filter.ValidateResponse(c.Request.Context(), &openapi3filter.ResponseValidationInput{
+		RequestValidationInput: input,
+		Status:                 writer.Status(),
+		Header:                 writer.Header(),
+		Body:                   io.NopCloser(bytes.NewReader(recorder.body.Bytes())),
+	})
+	if err != nil {
+		log.Errorf("response of %s %s does not match openapi spec: %v", c.Request.Method, c.FullPath(), err)
+		if gin.IsDebugging() {
+			writer.WriteHeader(http.StatusInternalServerError)
+			recorder.body.Reset()
+			body, _ := json.Marshal(gin.H{"error": "response does not match openapi spec: " + firstLine(err.Error())})
+			recorder.body.Write(body)
+		}
+	}
+	writer.Write(recorder.body.Bytes())
+}
+
+// checkRoutes сверяет маршруты gin с путями спецификации в обе стороны.
+func (o *openAPI) checkRoutes(routes gin.RoutesInfo, prefix string) error {
+	registered := map[string]bool{}
+	var problems []string
+	for _, r := range routes {
+		if !strings.HasPrefix(r.Path, prefix) {
+			continue
+		}
+		path := openAPIPath(r.Path)
+		registered[r.Method+" "+path] = true
+		item := o.doc.Paths.Value(path)
+		if item == nil || item.GetOperation(r.Method) == nil {
+			problems = append(problems, fmt.Sprintf("%s %s is not in the spec", r.Method, path))
+		}
+	}
+	for path, item := range o.doc.Paths.Map() {
+		for method := range item.Operations() {
+			if !registered[method+" "+path] {
+				problems = append(problems, fmt.Sprintf("%s %s has no handler", method, path))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("openapi spec drift: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// openAPIPath переводит путь gin (/users/:id) в путь OpenAPI (/users/{id}).
+func openAPIPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") {
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gift-bot/internal/repository"
+	"gift-bot/internal/service"
+	"gift-bot/pkg/config"
+	"gift-bot/pkg/migrations"
+	"gift-bot/pkg/models"
+	"gift-bot/pkg/sqlite"
+	"github.com/gin-gonic/gin"
+)
+
+// newTestHandlers собирает обработчики поверх SQLite во временном каталоге.
+// Сервиса Telegram нет: API обращается к нему только за немедленной рассылкой.
+func newTestHandlers(t *testing.T) (*Handlers, *service.Services) {
+	t.Helper()
+	ctx := context.Background()
+	db, err := sqlite.Open(ctx, filepath.Join(t.TempDir(), "gift-bot.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	m, err := migrations.New(ctx, db.DB(), config.StorageSQLite)
+	if err != nil {
+		t.Fatalf("prepare migrations: %v", err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	repos := repository.NewRepositories(db)
+	audit := service.NewAuditService(repos.AuditRepository)
+	occasions := service.NewOccasionService(repos.OccasionRepository, repos.UserRepository, repos.Transactor)
+	leader := service.NewLeaderService()
+	services := &service.Services{
+		UserService:      service.NewUserService(repos.UserRepository, repos.Transactor, audit),
+		OccasionService:  occasions,
+		CalendarService:  service.NewCalendarService(repos.UserRepository, occasions),
+		AuditService:     audit,
+		APIKeyService:    service.NewAPIKeyService(repos.APIKeyRepository),
+		BroadcastService: service.NewBroadcastService(repos.BroadcastRepository, repos.UserRepository),
+		LeaderService:    leader,
+		HealthService:    service.NewHealthService(repos.HealthRepository, leader),
+	}
+	return NewHandlers(services), services
+}
+
+func TestRoutesMatchOpenAPISpec(t *testing.T) {
+	h, _ := newTestHandlers(t)
+	if _, err := h.routes(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckRoutesReportsDrift(t *testing.T) {
+	spec, err := loadOpenAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	noop := func(c *gin.Context) {}
+	router.GET("/api/users", noop)
+	router.GET("/api/extra", noop)
+	router.GET("/outside", noop)
+
+	err = spec.checkRoutes(router.Routes(), "/api/")
+	if err == nil {
+		t.Fatal("want drift error")
+	}
+	for _, want := range []string{"GET /api/extra is not in the spec", "GET /api/users/{telegram_id} has no handler"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("drift error %q does not mention %q", err, want)
+		}
+	}
+	if strings.Contains(err.Error(), "GET /api/users ") || strings.Contains(err.Error(), "/outside") {
+		t.Errorf("drift error %q reports a matching or foreign route", err)
+	}
+}
+
+// TestAPIContract прогоняет запросы через настоящие обработчики и validate. В режиме debug ответ,
+// не совпадающий со схемой, превращается в 500, поэтому сверка статусов ловит и нарушения схемы.
+func TestAPIContract(t *testing.T) {
+	gin.SetMode(gin.DebugMode)
+	h, services := newTestHandlers(t)
+	router, err := h.routes()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	birthdate := time.Now().AddDate(-30, 0, 3)
+	for _, user := range []models.User{
+		{TelegramID: 1, Username: "owner", Role: models.RoleOwner},
+		{TelegramID: 2, Username: "alice", FirstName: "Alice", Role: models.RoleUser, Team: "dev", Birthdate: birthdate},
+	} {
+		if err := services.UserService.CreateUser(ctx, user); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+	_, adminKey, err := services.APIKeyService.CreateAPIKey(ctx, "admin", []string{
+		models.APIScopeReadUsers, models.APIScopeWriteUsers, models.APIScopeBroadcast, models.APIScopeAdmin,
+	}, 1)
+	if err != nil {
+		t.Fatalf("create api key: %v", err)
+	}
+	_, readKey, err := services.APIKeyService.CreateAPIKey(ctx, "reader", []string{models.APIScopeReadUsers}, 1)
+	if err != nil {
+		t.Fatalf("create api key: %v", err)
+	}
+
+	later := time.Now().Add(time.Hour).Format(time.RFC3339)
+	// Случаи выполняются по порядку: рассылка 1 появляется после её создания
+	cases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		key    string
+		want   int
+	}{
+		{"no key", http.MethodGet, "/api/users", "", "", http.StatusUnauthorized},
+		{"missing scope", http.MethodPost, "/api/users/2/block", "", readKey, http.StatusForbidden},
+		{"list users", http.MethodGet, "/api/users?q=ali&team=dev&limit=10", "", adminKey, http.StatusOK},
+		{"list users bad limit", http.MethodGet, "/api/users?limit=abc", "", adminKey, http.StatusBadRequest},
+		{"list users unknown role", http.MethodGet, "/api/users?role=god", "", adminKey, http.StatusBadRequest},
+		{"get user", http.MethodGet, "/api/users/2", "", readKey, http.StatusOK},
+		{"get user not found", http.MethodGet, "/api/users/99", "", adminKey, http.StatusNotFound},
+		{"get user bad id", http.MethodGet, "/api/users/abc", "", adminKey, http.StatusBadRequest},
+		{"patch user", http.MethodPatch, "/api/users/2", `{"display_name":"Алиса","notify_holidays":false}`, adminKey, http.StatusOK},
+		{"patch user long name", http.MethodPatch, "/api/users/2", `{"display_name":"` + strings.Repeat("я", 65) + `"}`, adminKey, http.StatusBadRequest},
+		{"patch user multiline name", http.MethodPatch, "/api/users/2", `{"display_name":"Али\nса"}`, adminKey, http.StatusBadRequest},
+		{"patch user unknown field", http.MethodPatch, "/api/users/2", `{"role":"admin"}`, adminKey, http.StatusBadRequest},
+		{"patch user bad date", http.MethodPatch, "/api/users/2", `{"birthdate":"31.12.1990"}`, adminKey, http.StatusBadRequest},
+		{"birthdays", http.MethodGet, "/api/birthdays?days=10", "", readKey, http.StatusOK},
+		{"birthdays out of range", http.MethodGet, "/api/birthdays?days=400", "", readKey, http.StatusBadRequest},
+		{"block user", http.MethodPost, "/api/users/2/block", "", adminKey, http.StatusOK},
+		{"block owner", http.MethodPost, "/api/users/1/block", "", adminKey, http.StatusConflict},
+		{"unblock user", http.MethodPost, "/api/users/2/unblock", "", adminKey, http.StatusOK},
+		{"set role", http.MethodPut, "/api/users/2/role", `{"role":"organiser"}`, adminKey, http.StatusOK},
+		{"set unknown role", http.MethodPut, "/api/users/2/role", `{"role":"god"}`, adminKey, http.StatusBadRequest},
+		{"create broadcast", http.MethodPost, "/api/broadcasts", `{"template":"Привет, {name}!","audience":"teams","teams":["dev"],"scheduled_at":"` + later + `"}`, adminKey, http.StatusAccepted},
+		{"create broadcast bad audience", http.MethodPost, "/api/broadcasts", `{"text":"hi","audience":"everyone"}`, adminKey, http.StatusBadRequest},
+		{"get broadcast", http.MethodGet, "/api/broadcasts/1", "", adminKey, http.StatusOK},
+		{"get broadcast not found", http.MethodGet, "/api/broadcasts/99", "", adminKey, http.StatusNotFound},
+		{"audit", http.MethodGet, "/api/audit?target=2&limit=5", "", adminKey, http.StatusOK},
+		{"audit bad since", http.MethodGet, "/api/audit?since=yesterday", "", adminKey, http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		if tc.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if tc.key != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.key)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if rec.Code != tc.want {
+			t.Errorf("%s: %s %s = %d, want %d; body: %s", tc.name, tc.method, tc.path, rec.Code, tc.want, rec.Body)
+			continue
+		}
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Errorf("%s: response is not a json object: %v; body: %s", tc.name, err, rec.Body)
+		}
+	}
+}
+
+func TestValidateRejectsResponseOutsideSchema(t *testing.T) {
+	gin.SetMode(gin.DebugMode)
+	spec, err := loadOpenAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := gin.New()
+	router.GET("/api/users/:telegram_id", spec.validate, func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"id": "not a number"})
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users/1", nil))
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "does not match openapi spec") {
+		t.Fatalf("got %d %s, want 500 with schema error", rec.Code, rec.Body)
+	}
+}
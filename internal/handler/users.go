@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"errors"
+	"gift-bot/internal/service"
+	"gift-bot/pkg/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const apiDateLayout = "2006-01-02"
+
+// userPatchRequest — тело PATCH /api/users/:telegram_id. Отсутствующие поля не меняются.
+type userPatchRequest struct {
+	FirstName       *string `json:"first_name"`
+	LastName        *string `json:"last_name"`
+	DisplayName     *string `json:"display_name"`
+	Team            *string `json:"team"`
+	Birthdate       *string `json:"birthdate"`
+	HideBirthday    *bool   `json:"hide_birthday"`
+	NotifyHolidays  *bool   `json:"notify_holidays"`
+	NotifyReminders *bool   `json:"notify_reminders"`
+}
+
+type roleRequest struct {
+	Role string `json:"role"`
+}
+
+type birthdayItem struct {
+	TelegramID int64  `json:"telegram_id"`
+	Username   string `json:"username"`
+	Name       string `json:"name"`
+	Team       string `json:"team"`
+	NextDate   string `json:"next_date"`
+	DaysLeft   int    `json:"days_left"`
+}
+
+// listUsers ищет пользователей: GET /api/users?q=&role=&team=&status=&blocked=&limit=&offset=
+func (h *Handlers) listUsers(c *gin.Context) {
+	filter := models.UserFilter{
+		Query:  strings.TrimSpace(c.Query("q")),
+		Role:   c.Query("role"),
+		Team:   c.Query("team"),
+		Status: c.Query("status"),
+	}
+	if value := c.Query("blocked"); value != "" {
+		blocked, err := strconv.ParseBool(value)
+		if err != nil {
+			abortWithError(c, http.StatusBadRequest, "invalid blocked")
+			return
+		}
+		filter.Blocked = &blocked
+	}
+	var ok bool
+	if filter.Limit, filter.Offset, ok = pageParams(c); !ok {
+		return
+	}
+
+	users, total, err := h.services.UserService.SearchUsers(c.Request.Context(), filter)
+	if err != nil {
+		log.Errorf("search users err: %v", err)
+		abortWithError(c, http.StatusInternalServerError, "internal error")
+		return
+	}
+	c.JSON(http.StatusOK, page{Items: users, Total: total, Limit: filter.Limit, Offset: filter.Offset})
+}
+
+func (h *Handlers) getUser(c *gin.Context) {
+	telegramID, ok := telegramIDParam(c)
+	if !ok {
+		return
+	}
+	user, err := h.services.UserService.GetUserByTelegramID(c.Request.Context(), telegramID)
+	if err != nil {
+		abortWithUserError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *Handlers) updateUser(c *gin.Context) {
+	telegramID, ok := telegramIDParam(c)
+	if !ok {
+		return
+	}
+	var req userPatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, "invalid body")
+		return
+	}
+
+	patch := models.UserPatch{
+		FirstName:       req.FirstName,
+		LastName:        req.LastName,
+		DisplayName:     req.DisplayName,
+		Team:            req.Team,
+		HideBirthday:    req.HideBirthday,
+		NotifyHolidays:  req.NotifyHolidays,
+		NotifyReminders: req.NotifyReminders,
+	}
+	if req.Birthdate != nil {
+		birthdate, err := time.Parse(apiDateLayout, *req.Birthdate)
+		if err != nil || birthdate.After(time.Now()) {
+			abortWithError(c, http.StatusBadRequest, "invalid birthdate")
+			return
+		}
+		patch.Birthdate = &birthdate
+	}
+
+	user, err := h.services.UserService.PatchUser(c.Request.Context(), telegramID, patch, models.AuditActorAPI, apiKeyPayload(c, nil))
+	if err != nil {
+		abortWithUserError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *Handlers) blockUser(c *gin.Context) {
+	telegramID, ok := telegramIDParam(c)
+	if !ok {
+		return
+	}
+	user, err := h.services.UserService.BlockUser(c.Request.Context(), telegramID, models.AuditActorAPI, apiKeyPayload(c, nil))
+	if err != nil {
+		abortWithUserError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *Handlers) unblockUser(c *gin.Context) {
+	telegramID, ok := telegramIDParam(c)
+	if !ok {
+		return
+	}
+	user, err := h.services.UserService.UnblockUser(c.Request.Context(), telegramID, models.AuditActorAPI, apiKeyPayload(c, nil))
+	if err != nil {
+		abortWithUserError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+func (h *Handlers) setUserRole(c *gin.Context) {
+	telegramID, ok := telegramIDParam(c)
+	if !ok {
+		return
+	}
+	var req roleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortWithError(c, http.StatusBadRequest, "invalid body")
+		return
+	}
+	role := strings.ToLower(strings.TrimSpace(req.Role))
+
+	user, err := h.services.UserService.ChangeUserRole(c.Request.Context(), telegramID, role, models.AuditActorAPI, apiKeyPayload(c, nil))
+	if err != nil {
+		abortWithUserError(c, err)
+		return
+	}
+	user.Role = role
+	c.JSON(http.StatusOK, user)
+}
+
+// upcomingBirthdays отдаёт ближайшие дни рождения: GET /api/birthdays?days=30&limit=&offset=
+// Как и в /birthdays, скрытые дни рождения и год рождения не отдаются.
+func (h *Handlers) upcomingBirthdays(c *gin.Context) {
+	days := 30
+	if value := c.Query("days"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 || n > 366 {
+			abortWithError(c, http.StatusBadRequest, "invalid days")
+			return
+		}
+		days = n
+	}
+	limit, offset, ok := pageParams(c)
+	if !ok {
+		return
+	}
+
+	birthdays, err := h.services.OccasionService.GetUpcomingBirthdays(c.Request.Context(), time.Now(), days)
+	if err != nil {
+		log.Errorf("get upcoming birthdays err: %v", err)
+		abortWithError(c, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	items := []birthdayItem{}
+	for i := offset; i < len(birthdays) && i < offset+limit; i++ {
+		b := birthdays[i]
+		items = append(items, birthdayItem{
+			TelegramID: b.User.TelegramID,
+			Username:   b.User.Username,
+			Name:       service.FormatUserName(b.User),
+			Team:       b.User.Team,
+			NextDate:   b.NextDate.Format(apiDateLayout),
+			DaysLeft:   b.DaysLeft,
+		})
+	}
+	c.JSON(http.StatusOK, page{Items: items, Total: len(birthdays), Limit: limit, Offset: offset})
+}
+
+func telegramIDParam(c *gin.Context) (int64, bool) {
+	telegramID, err := strconv.ParseInt(c.Param("telegram_id"), 10, 64)
+	if err != nil {
+		abortWithError(c, http.StatusBadRequest, "invalid telegram_id")
+		return 0, false
+	}
+	return telegramID, true
+}
+
+// abortWithUserError переводит ошибки UserService в HTTP-статусы.
+func abortWithUserError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		abortWithError(c, http.StatusNotFound, "user not found")
+	case errors.Is(err, service.ErrUnknownRole):
+		abortWithError(c, http.StatusBadRequest, "unknown role")
+	case errors.Is(err, service.ErrInvalidDisplayName):
+		abortWithError(c, http.StatusBadRequest, "invalid display_name")
+	case errors.Is(err, service.ErrOwnerProtected):
+		abortWithError(c, http.StatusConflict, "owner is protected")
+	default:
+		log.Errorf("user api err: %v", err)
+		abortWithError(c, http.StatusInternalServerError, "internal error")
+	}
+}
@@ -0,0 +1,656 @@
+package handler
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"gift-bot/internal/service"
+	"gift-bot/pkg/config"
+	"gift-bot/pkg/models"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// Веб-панель администратора: серверные шаблоны из web/templates и статика из web/static.
+//
+//go:embed web
+var webFS embed.FS
+
+const (
+	webSessionCookie = "gift_bot_session"
+	webPageSize      = 50
+	webDateLayout    = "02.01.2006 15:04"
+)
+
+// webPermissions — права, с которыми пользователь может войти в панель. Каждая страница проверяет своё право.
+var webPermissions = []service.Permission{
+	service.PermissionManageUsers, service.PermissionViewBirthdays, service.PermissionBroadcast, service.PermissionViewAudit,
+}
+
+type webUI struct {
+	services  *service.Services
+	templates map[string]*template.Template
+}
+
+// webPage — данные, общие для всех страниц панели.
+type webPage struct {
+	Title  string
+	User   models.User
+	CSRF   string
+	Notice string
+	Error  string
+	Data   interface{}
+}
+
+func newWebUI(services *service.Services) (*webUI, error) {
+	funcs := template.FuncMap{
+		"can": func(user models.User, permission string) bool {
+			return service.HasPermission(user, service.Permission(permission))
+		},
+		"roleTitle": service.RoleTitle,
+		"roles":     service.AssignableRoles,
+		"date": func(t time.Time) string {
+			if t.IsZero() {
+				return "—"
+			}
+			return t.Format(webDateLayout)
+		},
+		"day": func(t time.Time) string {
+			if t.IsZero() {
+				return "—"
+			}
+			return t.Format("02.01.2006")
+		},
+		"userName": webUserName,
+	}
+
+	pages, err := fs.Glob(webFS, "web/templates/*.html")
+	if err != nil {
+		return nil, err
+	}
+	templates := map[string]*template.Template{}
+	for _, page := range pages {
+		name := strings.TrimSuffix(strings.TrimPrefix(page, "web/templates/"), ".html")
+		if name == "layout" {
+			continue
+		}
+		t, err := template.New(name).Funcs(funcs).ParseFS(webFS, "web/templates/layout.html", page)
+		if err != nil {
+			return nil, fmt.Errorf("parse template %s: %w", page, err)
+		}
+		templates[name] = t
+	}
+	return &webUI{services: services, templates: templates}, nil
+}
+
+func (w *webUI) register(router *gin.Engine) {
+	static, _ := fs.Sub(webFS, "web/static")
+	admin := router.Group("/admin")
+	admin.StaticFS("/static", http.FS(static))
+	admin.GET("/login", w.loginPage)
+	admin.GET("/auth", w.telegramAuth)
+
+	panel := admin.Group("", w.requireSession)
+	panel.GET("", w.home)
+	panel.POST("/logout", w.requireCSRF, w.logout)
+	panel.GET("/users", w.requirePermission(service.PermissionManageUsers), w.usersPage)
+	panel.POST("/users/:telegram_id/role", w.requireCSRF, w.requirePermission(service.PermissionManageRoles), w.setRole)
+	panel.POST("/users/:telegram_id/block", w.requireCSRF, w.requirePermission(service.PermissionBlock), w.setBlocked(true))
+	panel.POST("/users/:telegram_id/unblock", w.requireCSRF, w.requirePermission(service.PermissionBlock), w.setBlocked(false))
+	panel.GET("/birthdays", w.requirePermission(service.PermissionViewBirthdays), w.birthdaysPage)
+	panel.GET("/broadcasts/new", w.requirePermission(service.PermissionBroadcast), w.broadcastForm)
+	panel.POST("/broadcasts", w.requireCSRF, w.requirePermission(service.PermissionBroadcast), w.createBroadcast)
+	panel.GET("/broadcasts/:id", w.requirePermission(service.PermissionBroadcast), w.broadcastPage)
+	panel.GET("/audit", w.requirePermission(service.PermissionViewAudit), w.auditPage)
+}
+
+func (w *webUI) render(c *gin.Context, status int, name string, page webPage) {
+	if user, ok := c.Get("web_user"); ok {
+		page.User = user.(models.User)
+		page.CSRF = service.WebCSRFToken(c.GetString("web_session"))
+	}
+	if page.Notice == "" {
+		page.Notice = c.Query("notice")
+	}
+	c.Status(status)
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	if err := w.templates[name].ExecuteTemplate(c.Writer, "layout", page); err != nil {
+		log.Errorf("render %s err: %v", name, err)
+	}
+}
+
+// redirect возвращает на страницу с сообщением для пользователя.
+func redirect(c *gin.Context, path, notice string) {
+	if notice != "" {
+		path += "?notice=" + url.QueryEscape(notice)
+	}
+	c.Redirect(http.StatusSeeOther, path)
+}
+
+// --- вход ---
+
+func (w *webUI) loginPage(c *gin.Context) {
+	w.render(c, http.StatusOK, "login", webPage{
+		Title: "Вход",
+		Error: c.Query("error"),
+		Data:  gin.H{"BotUsername": w.services.TelegramService.BotUsername()},
+	})
+}
+
+// telegramAuth принимает редирект Telegram Login Widget и проверяет подпись на сервере.
+func (w *webUI) telegramAuth(c *gin.Context) {
+	fields := map[string]string{}
+	for key, values := range c.Request.URL.Query() {
+		fields[key] = values[0]
+	}
+	telegramID, err := service.VerifyTelegramLogin(fields, time.Now())
+	if err != nil {
+		c.Redirect(http.StatusSeeOther, "/admin/login?error="+url.QueryEscape("Не удалось проверить вход через Telegram. Попробуйте ещё раз."))
+		return
+	}
+
+	user, err := w.services.UserService.GetUserByTelegramID(c.Request.Context(), telegramID)
+	if err != nil || !canUsePanel(user) {
+		log.Printf("Web login denied for %d", telegramID)
+		c.Redirect(http.StatusSeeOther, "/admin/login?error="+url.QueryEscape("У вас нет доступа к панели."))
+		return
+	}
+
+	session := service.SignWebSession(telegramID, time.Now().Add(service.WebSessionTTL))
+	secure := strings.HasPrefix(config.GlobalСonfig.ServerConfig.PublicURL, "https://")
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(webSessionCookie, session, int(service.WebSessionTTL.Seconds()), "/admin", "", secure, true)
+	log.Printf("Web login: %d", telegramID)
+	c.Redirect(http.StatusSeeOther, "/admin")
+}
+
+func (w *webUI) logout(c *gin.Context) {
+	c.SetCookie(webSessionCookie, "", -1, "/admin", "", false, true)
+	c.Redirect(http.StatusSeeOther, "/admin/login")
+}
+
+// requireSession пускает в панель по подписанной cookie. Роль и блокировка проверяются по БД на каждом запросе.
+func (w *webUI) requireSession(c *gin.Context) {
+	session, err := c.Cookie(webSessionCookie)
+	if err != nil {
+		c.Redirect(http.StatusSeeOther, "/admin/login")
+		c.Abort()
+		return
+	}
+	telegramID, err := service.ParseWebSession(session, time.Now())
+	if err != nil {
+		c.Redirect(http.StatusSeeOther, "/admin/login")
+		c.Abort()
+		return
+	}
+	user, err := w.services.UserService.GetUserByTelegramID(c.Request.Context(), telegramID)
+	if err != nil || !canUsePanel(user) {
+		c.Redirect(http.StatusSeeOther, "/admin/login?error="+url.QueryEscape("У вас нет доступа к панели."))
+		c.Abort()
+		return
+	}
+	c.Set("web_user", user)
+	c.Set("web_session", session)
+	c.Next()
+}
+
+func (w *webUI) requirePermission(permission service.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !service.HasPermission(webUser(c), permission) {
+			w.render(c, http.StatusForbidden, "error", webPage{Title: "Нет доступа", Error: "У вас нет прав для этого раздела."})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+func (w *webUI) requireCSRF(c *gin.Context) {
+	if !service.ValidWebCSRFToken(c.GetString("web_session"), c.PostForm("csrf")) {
+		w.render(c, http.StatusForbidden, "error", webPage{Title: "Ошибка", Error: "Форма устарела. Обновите страницу и повторите."})
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+func canUsePanel(user models.User) bool {
+	if user.Blocked || user.Status != models.UserStatusActive {
+		return false
+	}
+	for _, permission := range webPermissions {
+		if service.HasPermission(user, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+func webUser(c *gin.Context) models.User {
+	user, _ := c.Get("web_user")
+	u, _ := user.(models.User)
+	return u
+}
+
+// home открывает первый доступный пользователю раздел.
+func (w *webUI) home(c *gin.Context) {
+	user := webUser(c)
+	switch {
+	case service.HasPermission(user, service.PermissionManageUsers):
+		c.Redirect(http.StatusSeeOther, "/admin/users")
+	case service.HasPermission(user, service.PermissionViewBirthdays):
+		c.Redirect(http.StatusSeeOther, "/admin/birthdays")
+	case service.HasPermission(user, service.PermissionBroadcast):
+		c.Redirect(http.StatusSeeOther, "/admin/broadcasts/new")
+	default:
+		c.Redirect(http.StatusSeeOther, "/admin/audit")
+	}
+}
+
+// --- пользователи ---
+
+func (w *webUI) usersPage(c *gin.Context) {
+	pageNumber := webPageNumber(c)
+	filter := models.UserFilter{
+		Query:  strings.TrimSpace(c.Query("q")),
+		Status: c.Query("status"),
+		Limit:  webPageSize,
+		Offset: (pageNumber - 1) * webPageSize,
+	}
+	users, total, err := w.services.UserService.SearchUsers(c.Request.Context(), filter)
+	if err != nil {
+		w.render(c, http.StatusInternalServerError, "error", webPage{Title: "Ошибка", Error: "Ошибка при получении списка пользователей."})
+		return
+	}
+	w.render(c, http.StatusOK, "users", webPage{
+		Title: "Пользователи",
+		Data: gin.H{
+			"Users":  users,
+			"Total":  total,
+			"Query":  filter.Query,
+			"Status": filter.Status,
+			"Pager":  newWebPager(c, pageNumber, total),
+		},
+	})
+}
+
+func (w *webUI) setRole(c *gin.Context) {
+	telegramID, err := strconv.ParseInt(c.Param("telegram_id"), 10, 64)
+	if err != nil {
+		redirect(c, "/admin/users", "Неверный пользователь.")
+		return
+	}
+	role := c.PostForm("role")
+	user, err := w.services.UserService.ChangeUserRole(c.Request.Context(), telegramID, role, webUser(c).TelegramID, map[string]interface{}{
+		"source": "web",
+	})
+	if err != nil {
+		redirect(c, "/admin/users", webUserError(err))
+		return
+	}
+	redirect(c, "/admin/users", fmt.Sprintf("%s теперь %s.", webUserName(user), service.RoleTitle(role)))
+}
+
+func (w *webUI) setBlocked(blocked bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		telegramID, err := strconv.ParseInt(c.Param("telegram_id"), 10, 64)
+		if err != nil {
+			redirect(c, "/admin/users", "Неверный пользователь.")
+			return
+		}
+
+		var user models.User
+		actorID, payload := webUser(c).TelegramID, map[string]interface{}{"source": "web"}
+		notice := "%s разблокирован."
+		if blocked {
+			user, err = w.services.UserService.BlockUser(c.Request.Context(), telegramID, actorID, payload)
+			notice = "%s заблокирован."
+		} else {
+			user, err = w.services.UserService.UnblockUser(c.Request.Context(), telegramID, actorID, payload)
+		}
+		if err != nil {
+			redirect(c, "/admin/users", webUserError(err))
+			return
+		}
+		redirect(c, "/admin/users", fmt.Sprintf(notice, webUserName(user)))
+	}
+}
+
+func webUserError(err error) string {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		return "Пользователь не найден."
+	case errors.Is(err, service.ErrUnknownRole):
+		return "Неизвестная роль."
+	case errors.Is(err, service.ErrOwnerProtected):
+		return "Владельца бота нельзя заблокировать или сменить ему роль."
+	default:
+		log.Errorf("web user action err: %v", err)
+		return "Ошибка при сохранении."
+	}
+}
+
+// --- дни рождения ---
+
+type webCalendarDay struct {
+	Day       int
+	Today     bool
+	Birthdays []models.UpcomingOccasion
+}
+
+func (w *webUI) birthdaysPage(c *gin.Context) {
+	now := time.Now()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	if value := c.Query("month"); value != "" {
+		if parsed, err := time.ParseInLocation("2006-01", value, now.Location()); err == nil {
+			month = parsed
+		}
+	}
+	last := month.AddDate(0, 1, -1)
+
+	birthdays, err := w.services.OccasionService.GetUpcomingBirthdays(c.Request.Context(), month, last.Day()-1)
+	if err != nil {
+		w.render(c, http.StatusInternalServerError, "error", webPage{Title: "Ошибка", Error: "Ошибка при получении дней рождения."})
+		return
+	}
+	byDay := map[int][]models.UpcomingOccasion{}
+	for _, b := range birthdays {
+		byDay[b.NextDate.Day()] = append(byDay[b.NextDate.Day()], b)
+	}
+
+	// Сетка по неделям с понедельника; пустые клетки до первого числа и после последнего
+	var weeks [][]webCalendarDay
+	var week []webCalendarDay
+	for i := 0; i < (int(month.Weekday())+6)%7; i++ {
+		week = append(week, webCalendarDay{})
+	}
+	for day := 1; day <= last.Day(); day++ {
+		date := month.AddDate(0, 0, day-1)
+		week = append(week, webCalendarDay{
+			Day:       day,
+			Today:     date.Year() == now.Year() && date.YearDay() == now.YearDay(),
+			Birthdays: byDay[day],
+		})
+		if len(week) == 7 {
+			weeks = append(weeks, week)
+			week = nil
+		}
+	}
+	if len(week) > 0 {
+		for len(week) < 7 {
+			week = append(week, webCalendarDay{})
+		}
+		weeks = append(weeks, week)
+	}
+
+	w.render(c, http.StatusOK, "birthdays", webPage{
+		Title: "Дни рождения",
+		Data: gin.H{
+			"Month": fmt.Sprintf("%s %d", webMonthNames[month.Month()], month.Year()),
+			"Prev":  month.AddDate(0, -1, 0).Format("2006-01"),
+			"Next":  month.AddDate(0, 1, 0).Format("2006-01"),
+			"Weeks": weeks,
+			"Count": len(birthdays),
+		},
+	})
+}
+
+var webMonthNames = [...]string{"", "Январь", "Февраль", "Март", "Апрель", "Май", "Июнь",
+	"Июль", "Август", "Сентябрь", "Октябрь", "Ноябрь", "Декабрь"}
+
+// --- рассылки ---
+
+func (w *webUI) broadcastForm(c *gin.Context) {
+	w.render(c, http.StatusOK, "broadcast_form", webPage{Title: "Новая рассылка", Data: gin.H{"Teams": w.teams(c.Request.Context())}})
+}
+
+// createBroadcast ставит рассылку в ту же очередь, что /message и API, и показывает её состояние.
+func (w *webUI) createBroadcast(c *gin.Context) {
+	broadcast := models.Broadcast{
+		Text:       c.PostForm("text"),
+		IsTemplate: c.PostForm("template") == "on",
+		Audience:   c.PostForm("audience"),
+		CreatedBy:  webUser(c).TelegramID,
+	}
+	fail := func(message string) {
+		w.render(c, http.StatusBadRequest, "broadcast_form", webPage{Title: "Новая рассылка", Error: message, Data: gin.H{"Teams": w.teams(c.Request.Context())}})
+	}
+
+	switch broadcast.Audience {
+	case models.BroadcastAudienceTeams:
+		broadcast.Teams = splitList(c.PostForm("teams"))
+	case models.BroadcastAudienceUsers:
+		ids, unknown, err := w.resolveUsernames(c.Request.Context(), splitList(c.PostForm("users")))
+		if err != nil {
+			fail("Ошибка при получении списка пользователей.")
+			return
+		}
+		if len(unknown) > 0 {
+			fail("Не найдены пользователи: " + strings.Join(unknown, ", "))
+			return
+		}
+		broadcast.UserIDs = ids
+	}
+	if value := c.PostForm("scheduled_at"); value != "" {
+		scheduledAt, err := time.ParseInLocation("2006-01-02T15:04", value, time.Local)
+		if err != nil {
+			fail("Неверное время отправки.")
+			return
+		}
+		broadcast.ScheduledAt = scheduledAt
+	}
+
+	broadcast, err := w.services.BroadcastService.CreateBroadcast(c.Request.Context(), broadcast)
+	if errors.Is(err, service.ErrBroadcastInvalid) {
+		fail("Проверьте текст и получателей рассылки.")
+		return
+	}
+	if err != nil {
+		log.Errorf("web create broadcast err: %v", err)
+		fail("Ошибка при создании рассылки.")
+		return
+	}
+	w.services.AuditService.Record(c.Request.Context(), webUser(c).TelegramID, models.AuditActionBroadcast, 0, map[string]interface{}{
+		"broadcast_id": broadcast.ID, "audience": broadcast.Audience, "scheduled_at": broadcast.ScheduledAt, "source": "web",
+	})
+	if !broadcast.ScheduledAt.After(time.Now()) {
+		go w.services.TelegramService.SendDueBroadcasts(context.WithoutCancel(c.Request.Context()))
+	}
+	redirect(c, fmt.Sprintf("/admin/broadcasts/%d", broadcast.ID), "Рассылка поставлена в очередь.")
+}
+
+func (w *webUI) broadcastPage(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		w.render(c, http.StatusNotFound, "error", webPage{Title: "Не найдено", Error: "Рассылка не найдена."})
+		return
+	}
+	broadcast, stats, err := w.services.BroadcastService.GetBroadcast(c.Request.Context(), id)
+	if errors.Is(err, service.ErrBroadcastNotFound) {
+		w.render(c, http.StatusNotFound, "error", webPage{Title: "Не найдено", Error: "Рассылка не найдена."})
+		return
+	}
+	if err != nil {
+		w.render(c, http.StatusInternalServerError, "error", webPage{Title: "Ошибка", Error: "Ошибка при получении рассылки."})
+		return
+	}
+	w.render(c, http.StatusOK, "broadcast", webPage{
+		Title: fmt.Sprintf("Рассылка №%d", broadcast.ID),
+		Data:  gin.H{"Broadcast": broadcast, "Stats": stats},
+	})
+}
+
+// teams возвращает команды активных пользователей для подсказки в форме рассылки.
+func (w *webUI) teams(ctx context.Context) []string {
+	users, err := w.services.UserService.GetAllUsers(ctx)
+	if err != nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	var teams []string
+	for _, u := range users {
+		if team := strings.TrimSpace(u.Team); team != "" && !seen[team] {
+			seen[team] = true
+			teams = append(teams, team)
+		}
+	}
+	return teams
+}
+
+func (w *webUI) resolveUsernames(ctx context.Context, usernames []string) ([]int64, []string, error) {
+	users, err := w.services.UserService.GetAllUsers(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	byUsername := make(map[string]int64, len(users))
+	for _, u := range users {
+		byUsername[strings.ToLower(u.Username)] = u.TelegramID
+	}
+	var ids []int64
+	var unknown []string
+	for _, username := range usernames {
+		if id, ok := byUsername[strings.ToLower(strings.TrimPrefix(username, "@"))]; ok {
+			ids = append(ids, id)
+		} else {
+			unknown = append(unknown, username)
+		}
+	}
+	return ids, unknown, nil
+}
+
+// --- журнал ---
+
+type webAuditRow struct {
+	models.AuditEvent
+	Actor   string
+	Target  string
+	Details string
+}
+
+func (w *webUI) auditPage(c *gin.Context) {
+	pageNumber := webPageNumber(c)
+	filter := models.AuditFilter{
+		Action: c.Query("action"),
+		Limit:  webPageSize,
+		Offset: (pageNumber - 1) * webPageSize,
+	}
+	events, total, err := w.services.AuditService.GetAuditEvents(c.Request.Context(), filter)
+	if err != nil {
+		w.render(c, http.StatusInternalServerError, "error", webPage{Title: "Ошибка", Error: "Ошибка при получении журнала."})
+		return
+	}
+
+	names := w.userNames(c.Request.Context())
+	rows := make([]webAuditRow, 0, len(events))
+	for _, e := range events {
+		row := webAuditRow{AuditEvent: e, Actor: webAuditName(names, e.ActorTelegramID)}
+		if e.TargetTelegramID != nil {
+			row.Target = webAuditName(names, *e.TargetTelegramID)
+		}
+		if payload := string(e.Payload); payload != "{}" {
+			row.Details = payload
+		}
+		rows = append(rows, row)
+	}
+
+	w.render(c, http.StatusOK, "audit", webPage{
+		Title: "Журнал действий",
+		Data: gin.H{
+			"Rows":   rows,
+			"Total":  total,
+			"Action": filter.Action,
+			"Pager":  newWebPager(c, pageNumber, total),
+		},
+	})
+}
+
+func (w *webUI) userNames(ctx context.Context) map[int64]string {
+	names := map[int64]string{}
+	active, _ := w.services.UserService.GetAllUsers(ctx)
+	blocked, _ := w.services.UserService.GetBlockedUsers(ctx)
+	for _, u := range append(active, blocked...) {
+		names[u.TelegramID] = webUserName(u)
+	}
+	return names
+}
+
+func webAuditName(names map[int64]string, telegramID int64) string {
+	switch telegramID {
+	case 0:
+		return "удалённый пользователь"
+	case models.AuditActorAPI:
+		return "HTTP API"
+	}
+	if name, ok := names[telegramID]; ok {
+		return name
+	}
+	return fmt.Sprintf("id%d", telegramID)
+}
+
+// --- общее ---
+
+type webPager struct {
+	Page  int
+	Pages int
+	Prev  string
+	Next  string
+}
+
+func newWebPager(c *gin.Context, page, total int) webPager {
+	pages := (total + webPageSize - 1) / webPageSize
+	pager := webPager{Page: page, Pages: pages}
+	link := func(p int) string {
+		query := c.Request.URL.Query()
+		query.Set("page", strconv.Itoa(p))
+		query.Del("notice")
+		return "?" + query.Encode()
+	}
+	if page > 1 {
+		pager.Prev = link(page - 1)
+	}
+	if page < pages {
+		pager.Next = link(page + 1)
+	}
+	return pager
+}
+
+func webPageNumber(c *gin.Context) int {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page < 1 {
+		return 1
+	}
+	return page
+}
+
+// webUserName показывает @ник и имя, как в списках бота.
+func webUserName(u models.User) string {
+	name := service.FormatUserName(u)
+	switch {
+	case u.Username != "" && name != "":
+		return "@" + u.Username + " — " + name
+	case u.Username != "":
+		return "@" + u.Username
+	case name != "":
+		return name
+	}
+	return fmt.Sprintf("id%d", u.TelegramID)
+}
+
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '\n' || r == ' ' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
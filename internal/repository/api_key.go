@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"gift-bot/pkg/models"
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+type APIKeyRepositoryImpl struct {
+	dbProvider DBProvider
+}
+
+func NewAPIKeyRepository(dbProvider DBProvider) *APIKeyRepositoryImpl {
+	return &APIKeyRepositoryImpl{
+		dbProvider: dbProvider,
+	}
+}
+
+func (a APIKeyRepositoryImpl) CreateAPIKey(ctx context.Context, key models.APIKey, keyHash string) (int64, error) {
+	query := `INSERT INTO api_keys (name, key_prefix, key_hash, scopes, created_by, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6)
+              RETURNING id`
+	var id int64
+	err := querier(ctx, a.dbProvider).QueryRowContext(ctx, query, key.Name, key.KeyPrefix, keyHash, pq.Array(key.Scopes), key.CreatedBy, key.CreatedAt).Scan(&id)
+	if err != nil {
+		log.Errorf("create api key err: %v", err)
+		return 0, err
+	}
+	return id, nil
+}
+
+// GetAPIKeyByHash возвращает неотозванный ключ по хэшу.
+func (a APIKeyRepositoryImpl) GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error) {
+	query := `SELECT id, name, key_prefix, scopes, created_by, created_at, last_used_at, revoked_at
+              FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`
+	var key models.APIKey
+	err := querier(ctx, a.dbProvider).QueryRowContext(ctx, query, keyHash).Scan(&key.ID, &key.Name, &key.KeyPrefix, pq.Array(&key.Scopes), &key.CreatedBy, &key.CreatedAt, &key.LastUsedAt, &key.RevokedAt)
+	if err != nil {
+		return models.APIKey{}, err
+	}
+	return key, nil
+}
+
+func (a APIKeyRepositoryImpl) GetActiveAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	query := `SELECT id, name, key_prefix, scopes, created_by, created_at, last_used_at, revoked_at
+              FROM api_keys WHERE revoked_at IS NULL ORDER BY id`
+	rows, err := querier(ctx, a.dbProvider).QueryContext(ctx, query)
+	if err != nil {
+		log.Errorf("get api keys err: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		var key models.APIKey
+		if err := rows.Scan(&key.ID, &key.Name, &key.KeyPrefix, pq.Array(&key.Scopes), &key.CreatedBy, &key.CreatedAt, &key.LastUsedAt, &key.RevokedAt); err != nil {
+			log.Errorf("scan api key err: %v", err)
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает ключ. Возвращает false, если ключа нет или он уже отозван.
+func (a APIKeyRepositoryImpl) RevokeAPIKey(ctx context.Context, id int64) (bool, error) {
+	query := `UPDATE api_keys SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL;`
+	res, err := querier(ctx, a.dbProvider).ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		log.Errorf("revoke api key err: %v", err)
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Errorf("revoke api key rows err: %v", err)
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (a APIKeyRepositoryImpl) TouchAPIKey(ctx context.Context, id int64, usedAt time.Time) error {
+	query := `UPDATE api_keys SET last_used_at = $1 WHERE id = $2;`
+	_, err := querier(ctx, a.dbProvider).ExecContext(ctx, query, usedAt, id)
+	if err != nil {
+		log.Errorf("touch api key err: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"gift-bot/pkg/models"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+type AuditRepositoryImpl struct {
+	dbProvider DBProvider
+}
+
+func NewAuditRepository(dbProvider DBProvider) *AuditRepositoryImpl {
+	return &AuditRepositoryImpl{
+		dbProvider: dbProvider,
+	}
+}
+
+func (a AuditRepositoryImpl) SaveAuditEvent(ctx context.Context, event models.AuditEvent) error {
+	payload := event.Payload
+	if len(payload) == 0 {
+		payload = []byte("{}")
+	}
+	query := `INSERT INTO audit_events (actor_telegram_id, action, target_telegram_id, payload, created_at)
+              VALUES ($1, $2, $3, $4, $5)`
+	_, err := querier(ctx, a.dbProvider).ExecContext(ctx, query, event.ActorTelegramID, event.Action, event.TargetTelegramID, string(payload), time.Now())
+	if err != nil {
+		log.Errorf("save audit event err: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetAuditEvents возвращает страницу журнала (новые записи первыми) и общее число записей под фильтром.
+func (a AuditRepositoryImpl) GetAuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, int, error) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if filter.ActorTelegramID != 0 {
+		add("actor_telegram_id = $%d", filter.ActorTelegramID)
+	}
+	if filter.TargetTelegramID != 0 {
+		add("target_telegram_id = $%d", filter.TargetTelegramID)
+	}
+	if filter.TelegramID != 0 {
+		args = append(args, filter.TelegramID)
+		conditions = append(conditions, fmt.Sprintf("(actor_telegram_id = $%d OR target_telegram_id = $%d)", len(args), len(args)))
+	}
+	if !filter.Since.IsZero() {
+		add("created_at >= $%d", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		add("created_at < $%d", filter.Until)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := querier(ctx, a.dbProvider).GetContext(ctx, &total, "SELECT COUNT(*) FROM audit_events "+where, args...); err != nil {
+		log.Errorf("count audit events err: %v", err)
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+    SELECT id, actor_telegram_id, action, target_telegram_id, payload, created_at
+    FROM audit_events
+    %s
+    ORDER BY created_at DESC, id DESC
+    LIMIT $%d OFFSET $%d`, where, len(args)+1, len(args)+2)
+	// payload читается в []byte: SQLite отдаёт TEXT строкой, а в json.RawMessage строку не сканировать
+	var rows []struct {
+		models.AuditEvent
+		Payload []byte `db:"payload"`
+	}
+	if err := querier(ctx, a.dbProvider).SelectContext(ctx, &rows, query, append(args, filter.Limit, filter.Offset)...); err != nil {
+		log.Errorf("get audit events err: %v", err)
+		return nil, 0, err
+	}
+	events := make([]models.AuditEvent, len(rows))
+	for i, row := range rows {
+		events[i] = row.AuditEvent
+		events[i].Payload = row.Payload
+	}
+	return events, total, nil
+}
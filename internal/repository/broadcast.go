@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"gift-bot/pkg/models"
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+const broadcastColumns = `id, text, is_template, audience, teams, user_ids, excluded_ids, status, scheduled_at,
+    created_by, api_key_id, created_at, started_at, finished_at`
+
+type BroadcastRepositoryImpl struct {
+	dbProvider DBProvider
+}
+
+func NewBroadcastRepository(dbProvider DBProvider) *BroadcastRepositoryImpl {
+	return &BroadcastRepositoryImpl{
+		dbProvider: dbProvider,
+	}
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanBroadcast(row rowScanner) (models.Broadcast, error) {
+	var b models.Broadcast
+	err := row.Scan(&b.ID, &b.Text, &b.IsTemplate, &b.Audience, pq.Array(&b.Teams), pq.Array(&b.UserIDs), pq.Array(&b.ExcludedIDs),
+		&b.Status, &b.ScheduledAt, &b.CreatedBy, &b.APIKeyID, &b.CreatedAt, &b.StartedAt, &b.FinishedAt)
+	return b, err
+}
+
+func (r BroadcastRepositoryImpl) CreateBroadcast(ctx context.Context, b models.Broadcast) (int64, error) {
+	// pq передаёт nil-срез как NULL, а колонки массивов NOT NULL
+	if b.Teams == nil {
+		b.Teams = []string{}
+	}
+	if b.UserIDs == nil {
+		b.UserIDs = []int64{}
+	}
+	if b.ExcludedIDs == nil {
+		b.ExcludedIDs = []int64{}
+	}
+	query := `INSERT INTO broadcasts (text, is_template, audience, teams, user_ids, excluded_ids, status, scheduled_at, created_by, api_key_id, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+              RETURNING id`
+	var id int64
+	err := querier(ctx, r.dbProvider).QueryRowContext(ctx, query, b.Text, b.IsTemplate, b.Audience, pq.Array(b.Teams), pq.Array(b.UserIDs), pq.Array(b.ExcludedIDs),
+		b.Status, b.ScheduledAt, b.CreatedBy, b.APIKeyID, time.Now()).Scan(&id)
+	if err != nil {
+		log.Errorf("create broadcast err: %v", err)
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r BroadcastRepositoryImpl) GetBroadcast(ctx context.Context, id int64) (models.Broadcast, error) {
+	return scanBroadcast(querier(ctx, r.dbProvider).QueryRowContext(ctx, `SELECT `+broadcastColumns+` FROM broadcasts WHERE id = $1`, id))
+}
+
+// ClaimBroadcast переводит запланированную рассылку в отправку. Возвращает false, если её уже забрал другой обработчик.
+func (r BroadcastRepositoryImpl) ClaimBroadcast(ctx context.Context, id int64) (models.Broadcast, bool, error) {
+	claimed, err := r.claim(ctx, `status = 'scheduled' AND id = $2`, id)
+	if err != nil || len(claimed) == 0 {
+		return models.Broadcast{}, false, err
+	}
+	return claimed[0], true, nil
+}
+
+// ClaimDueBroadcasts забирает в отправку все рассылки, время которых наступило, а также рассылки в статусе sending,
+// отправка которых не подтверждалась с staleBefore: обработчик, забравший их, остановился на середине.
+func (r BroadcastRepositoryImpl) ClaimDueBroadcasts(ctx context.Context, now time.Time, staleBefore time.Time) ([]models.Broadcast, error) {
+	return r.claim(ctx, `(status = 'scheduled' AND scheduled_at <= $2) OR (status = 'sending' AND claimed_at < $3)`, now, staleBefore)
+}
+
+// claim переводит рассылки под условием в отправку. started_at сохраняет время первого захвата,
+// claimed_at — последнего; параметры условия нумеруются с $2.
+func (r BroadcastRepositoryImpl) claim(ctx context.Context, condition string, args ...interface{}) ([]models.Broadcast, error) {
+	query := `UPDATE broadcasts SET status = 'sending', started_at = COALESCE(started_at, $1), claimed_at = $1
+              WHERE ` + condition + `
+              RETURNING ` + broadcastColumns
+	rows, err := querier(ctx, r.dbProvider).QueryContext(ctx, query, append([]interface{}{time.Now()}, args...)...)
+	if err != nil {
+		log.Errorf("claim broadcasts err: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var broadcasts []models.Broadcast
+	for rows.Next() {
+		b, err := scanBroadcast(rows)
+		if err != nil {
+			log.Errorf("scan broadcast err: %v", err)
+			return nil, err
+		}
+		broadcasts = append(broadcasts, b)
+	}
+	return broadcasts, nil
+}
+
+// recipientsBatch — сколько получателей вставляется одним запросом: по два параметра на строку,
+// с запасом до лимитов параметров Postgres и SQLite.
+const recipientsBatch = 500
+
+// AddBroadcastRecipients фиксирует список получателей со статусом pending.
+func (r BroadcastRepositoryImpl) AddBroadcastRecipients(ctx context.Context, broadcastID int64, telegramIDs []int64) error {
+	return withTx(ctx, r.dbProvider, func(ctx context.Context) error {
+		for start := 0; start < len(telegramIDs); start += recipientsBatch {
+			batch := telegramIDs[start:min(start+recipientsBatch, len(telegramIDs))]
+			values := make([]string, len(batch))
+			args := make([]interface{}, 0, 2*len(batch))
+			for i, telegramID := range batch {
+				values[i] = fmt.Sprintf("($%d, $%d)", 2*i+1, 2*i+2)
+				args = append(args, broadcastID, telegramID)
+			}
+			query := `INSERT INTO broadcast_deliveries (broadcast_id, user_telegram_id)
+              VALUES ` + strings.Join(values, ", ") + `
+              ON CONFLICT DO NOTHING`
+			if _, err := querier(ctx, r.dbProvider).ExecContext(ctx, query, args...); err != nil {
+				log.Errorf("add broadcast recipients err: %v", err)
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ExtendBroadcastClaim подтверждает, что рассылка всё ещё отправляется, чтобы её не забрал другой обработчик.
+func (r BroadcastRepositoryImpl) ExtendBroadcastClaim(ctx context.Context, id int64) error {
+	query := `UPDATE broadcasts SET claimed_at = $1 WHERE id = $2 AND status = 'sending'`
+	_, err := querier(ctx, r.dbProvider).ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		log.Errorf("extend broadcast claim err: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetPendingBroadcastRecipients возвращает получателей, которым рассылка ещё не отправлялась.
+func (r BroadcastRepositoryImpl) GetPendingBroadcastRecipients(ctx context.Context, broadcastID int64) ([]int64, error) {
+	query := `SELECT user_telegram_id FROM broadcast_deliveries WHERE broadcast_id = $1 AND status = 'pending'`
+	var ids []int64
+	if err := querier(ctx, r.dbProvider).SelectContext(ctx, &ids, query, broadcastID); err != nil {
+		log.Errorf("get pending broadcast recipients err: %v", err)
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r BroadcastRepositoryImpl) SaveBroadcastDelivery(ctx context.Context, broadcastID int64, telegramID int64, status string, errText string) error {
+	query := `UPDATE broadcast_deliveries SET status = $1, error = $2, sent_at = $3
+              WHERE broadcast_id = $4 AND user_telegram_id = $5`
+	_, err := querier(ctx, r.dbProvider).ExecContext(ctx, query, status, errText, time.Now(), broadcastID, telegramID)
+	if err != nil {
+		log.Errorf("save broadcast delivery err: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (r BroadcastRepositoryImpl) FinishBroadcast(ctx context.Context, id int64) error {
+	query := `UPDATE broadcasts SET status = 'done', finished_at = $1 WHERE id = $2`
+	_, err := querier(ctx, r.dbProvider).ExecContext(ctx, query, time.Now(), id)
+	if err != nil {
+		log.Errorf("finish broadcast err: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (r BroadcastRepositoryImpl) GetBroadcastStats(ctx context.Context, id int64) (models.BroadcastStats, error) {
+	query := `SELECT
+                COUNT(*),
+                COUNT(*) FILTER (WHERE status = 'pending'),
+                COUNT(*) FILTER (WHERE status = 'sent'),
+                COUNT(*) FILTER (WHERE status = 'failed')
+              FROM broadcast_deliveries WHERE broadcast_id = $1`
+	var stats models.BroadcastStats
+	err := querier(ctx, r.dbProvider).QueryRowContext(ctx, query, id).Scan(&stats.Total, &stats.Pending, &stats.Sent, &stats.Failed)
+	if err != nil {
+		log.Errorf("get broadcast stats err: %v", err)
+		return models.BroadcastStats{}, err
+	}
+	return stats, nil
+}
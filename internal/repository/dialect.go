@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Запросы в репозиториях пишутся на общем подмножестве SQL Postgres и SQLite с плейсхолдерами $N.
+// Различия драйверов сглаживаются здесь, чтобы реализации репозиториев не зависели от хранилища.
+
+// driverSQLite — имя драйвера github.com/mattn/go-sqlite3.
+const driverSQLite = "sqlite3"
+
+var dollarPlaceholder = regexp.MustCompile(`\$(\d+)`)
+
+// withDialect оборачивает соединение или транзакцию SQLite так, чтобы запросы с $N работали без изменений.
+func withDialect(q Querier, driverName string) Querier {
+	if driverName == driverSQLite {
+		return sqliteQuerier{q: q}
+	}
+	return q
+}
+
+// sqliteQuerier переписывает $N в ?N. SQLite понимает и $N, но нумерует такие параметры по порядку
+// появления в запросе, а не по числу, и запрос вида "a = $2 AND b = $1" получил бы аргументы наоборот.
+type sqliteQuerier struct {
+	q Querier
+}
+
+func (s sqliteQuerier) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return s.q.ExecContext(ctx, rebindSQLite(query), args...)
+}
+
+func (s sqliteQuerier) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return s.q.QueryContext(ctx, rebindSQLite(query), args...)
+}
+
+func (s sqliteQuerier) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return s.q.QueryRowContext(ctx, rebindSQLite(query), args...)
+}
+
+func (s sqliteQuerier) GetContext(ctx context.Context, dest any, query string, args ...any) error {
+	return s.q.GetContext(ctx, dest, rebindSQLite(query), args...)
+}
+
+func (s sqliteQuerier) SelectContext(ctx context.Context, dest any, query string, args ...any) error {
+	return s.q.SelectContext(ctx, dest, rebindSQLite(query), args...)
+}
+
+func rebindSQLite(query string) string {
+	return dollarPlaceholder.ReplaceAllString(query, "?$1")
+}
+
+// inList возвращает "($start, $start+1, ...)" для n значений — замена ANY($1::text[]), которой нет в SQLite.
+// Для пустого списка возвращает "(NULL)": условие IN (NULL) не выполняется ни для одной строки.
+func inList(start, n int) string {
+	if n == 0 {
+		return "(NULL)"
+	}
+	placeholders := make([]string, n)
+	for i := range placeholders {
+		placeholders[i] = fmt.Sprintf("$%d", start+i)
+	}
+	return "(" + strings.Join(placeholders, ", ") + ")"
+}
+
+// listArgs превращает значения для inList в аргументы запроса.
+func listArgs[T any](values []T) []any {
+	args := make([]any, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"errors"
+)
+
+type HealthRepositoryImpl struct {
+	dbProvider DBProvider
+}
+
+func NewHealthRepository(dbProvider DBProvider) *HealthRepositoryImpl {
+	return &HealthRepositoryImpl{
+		dbProvider: dbProvider,
+	}
+}
+
+// Ping проверяет соединение с БД. postgres.Manager пингует с собственным таймаутом.
+func (h HealthRepositoryImpl) Ping(ctx context.Context) error {
+	if pinger, ok := h.dbProvider.(interface{ Ping(context.Context) error }); ok {
+		return pinger.Ping(ctx)
+	}
+	db := h.dbProvider.DB()
+	if db == nil {
+		return errors.New("db: not connected")
+	}
+	return db.PingContext(ctx)
+}
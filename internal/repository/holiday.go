@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"gift-bot/pkg/models"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+type HolidayRepositoryImpl struct {
+	dbProvider DBProvider
+}
+
+func NewHolidayRepository(dbProvider DBProvider) *HolidayRepositoryImpl {
+	return &HolidayRepositoryImpl{
+		dbProvider: dbProvider,
+	}
+}
+
+// UpsertHoliday создаёт праздник или обновляет его по коду. Переопределённое приветствие не затрагивается.
+func (h HolidayRepositoryImpl) UpsertHoliday(ctx context.Context, holiday models.Holiday) error {
+	query := `INSERT INTO holidays (code, name, month, day, year, greeting, created_at, updated_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+              ON CONFLICT (code) DO UPDATE
+              SET name = EXCLUDED.name, month = EXCLUDED.month, day = EXCLUDED.day, year = EXCLUDED.year,
+                  greeting = EXCLUDED.greeting, updated_at = EXCLUDED.updated_at;`
+	_, err := querier(ctx, h.dbProvider).ExecContext(ctx, query, holiday.Code, holiday.Name, holiday.Month, holiday.Day, holiday.Year, holiday.Greeting, time.Now())
+	if err != nil {
+		log.Errorf("upsert holiday err: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (h HolidayRepositoryImpl) GetHolidays(ctx context.Context) ([]models.Holiday, error) {
+	query := `
+    SELECT id, code, name, month, day, year, greeting, greeting_override, created_at, updated_at
+    FROM holidays
+    ORDER BY month, day`
+	var holidays []models.Holiday
+	if err := querier(ctx, h.dbProvider).SelectContext(ctx, &holidays, query); err != nil {
+		log.Errorf("get holidays err: %v", err)
+		return nil, err
+	}
+	return holidays, nil
+}
+
+func (h HolidayRepositoryImpl) GetHoliday(ctx context.Context, id int64) (models.Holiday, error) {
+	query := `
+    SELECT id, code, name, month, day, year, greeting, greeting_override, created_at, updated_at
+    FROM holidays
+    WHERE id = $1`
+	var holiday models.Holiday
+	if err := querier(ctx, h.dbProvider).GetContext(ctx, &holiday, query, id); err != nil {
+		log.Errorf("get holiday err: %v", err)
+		return models.Holiday{}, err
+	}
+	return holiday, nil
+}
+
+func (h HolidayRepositoryImpl) SetHolidayGreetingOverride(ctx context.Context, id int64, greeting string) error {
+	query := `UPDATE holidays SET greeting_override = $1, updated_at = $2 WHERE id = $3;`
+	_, err := querier(ctx, h.dbProvider).ExecContext(ctx, query, greeting, time.Now(), id)
+	if err != nil {
+		log.Errorf("set holiday greeting override err: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (h HolidayRepositoryImpl) HasHolidayDelivery(ctx context.Context, holidayID int64, telegramID int64, date time.Time) (bool, error) {
+	query := `SELECT EXISTS (
+		SELECT 1 FROM holiday_deliveries
+		WHERE holiday_id = $1 AND telegram_id = $2 AND greet_date = $3
+	);`
+	var exists bool
+	err := querier(ctx, h.dbProvider).QueryRowContext(ctx, query, holidayID, telegramID, date).Scan(&exists)
+	if err != nil {
+		log.Errorf("check holiday delivery err: %v", err)
+		return false, err
+	}
+	return exists, nil
+}
+
+func (h HolidayRepositoryImpl) SaveHolidayDelivery(ctx context.Context, holidayID int64, telegramID int64, date time.Time) error {
+	query := `INSERT INTO holiday_deliveries (holiday_id, telegram_id, greet_date)
+			  VALUES ($1, $2, $3)
+			  ON CONFLICT DO NOTHING;`
+	_, err := querier(ctx, h.dbProvider).ExecContext(ctx, query, holidayID, telegramID, date)
+	if err != nil {
+		log.Errorf("save holiday delivery err: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"gift-bot/pkg/models"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// ErrInviteUnavailable возвращается, если приглашение отозвано, истекло или исчерпано к моменту регистрации.
+var ErrInviteUnavailable = errors.New("invite code is no longer available")
+
+type InviteRepositoryImpl struct {
+	dbProvider DBProvider
+}
+
+func NewInviteRepository(dbProvider DBProvider) *InviteRepositoryImpl {
+	return &InviteRepositoryImpl{
+		dbProvider: dbProvider,
+	}
+}
+
+func (i InviteRepositoryImpl) CreateInviteCode(ctx context.Context, invite models.InviteCode) (int64, error) {
+	query := `INSERT INTO invite_codes (code, created_by, team, max_uses, expires_at, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6)
+              RETURNING id`
+	var id int64
+	err := querier(ctx, i.dbProvider).QueryRowContext(ctx, query, invite.Code, invite.CreatedBy, invite.Team, invite.MaxUses, invite.ExpiresAt, time.Now()).Scan(&id)
+	if err != nil {
+		log.Errorf("create invite code err: %v", err)
+		return 0, err
+	}
+	return id, nil
+}
+
+func (i InviteRepositoryImpl) GetInviteCodeByCode(ctx context.Context, code string) (models.InviteCode, error) {
+	query := `
+    SELECT id, code, created_by, team, max_uses, uses, expires_at, revoked, created_at
+    FROM invite_codes
+    WHERE code = $1`
+	var invite models.InviteCode
+	if err := querier(ctx, i.dbProvider).GetContext(ctx, &invite, query, code); err != nil {
+		return models.InviteCode{}, err
+	}
+	return invite, nil
+}
+
+// GetActiveInviteCodes возвращает неотозванные, неистёкшие и неисчерпанные приглашения.
+func (i InviteRepositoryImpl) GetActiveInviteCodes(ctx context.Context) ([]models.InviteCode, error) {
+	query := `
+    SELECT id, code, created_by, team, max_uses, uses, expires_at, revoked, created_at
+    FROM invite_codes
+    WHERE revoked = false AND expires_at > $1 AND uses < max_uses
+    ORDER BY id`
+	var invites []models.InviteCode
+	if err := querier(ctx, i.dbProvider).SelectContext(ctx, &invites, query, time.Now()); err != nil {
+		log.Errorf("get active invite codes err: %v", err)
+		return nil, err
+	}
+	return invites, nil
+}
+
+func (i InviteRepositoryImpl) RevokeInviteCode(ctx context.Context, id int64) error {
+	query := `UPDATE invite_codes SET revoked = true WHERE id = $1;`
+	_, err := querier(ctx, i.dbProvider).ExecContext(ctx, query, id)
+	if err != nil {
+		log.Errorf("revoke invite code err: %v", err)
+		return err
+	}
+	return nil
+}
+
+// RegisterUserWithInvite в одной транзакции списывает использование приглашения и создаёт пользователя.
+func (i InviteRepositoryImpl) RegisterUserWithInvite(ctx context.Context, user models.User, inviteID int64) error {
+	return withTx(ctx, i.dbProvider, func(ctx context.Context) error {
+		tx := querier(ctx, i.dbProvider)
+
+		now := time.Now()
+		redeem := `UPDATE invite_codes SET uses = uses + 1
+               WHERE id = $1 AND revoked = false AND expires_at > $2 AND uses < max_uses
+               RETURNING id`
+		var redeemedID int64
+		if err := tx.QueryRowContext(ctx, redeem, inviteID, now).Scan(&redeemedID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrInviteUnavailable
+			}
+			log.Errorf("redeem invite code err: %v", err)
+			return err
+		}
+
+		insert := `INSERT INTO users (telegram_id, username, first_name, last_name, role, team, birthdate, status, invite_code_id, created_at, updated_at)
+               VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+		_, err := tx.ExecContext(ctx, insert, user.TelegramID, user.Username, user.FirstName, user.LastName, user.Role, user.Team, user.Birthdate, userStatus(user), inviteID, now, now)
+		if err != nil {
+			log.Errorf("create invited user err: %v", err)
+			return err
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"gift-bot/pkg/models"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+type JobRunRepositoryImpl struct {
+	dbProvider DBProvider
+}
+
+func NewJobRunRepository(dbProvider DBProvider) *JobRunRepositoryImpl {
+	return &JobRunRepositoryImpl{
+		dbProvider: dbProvider,
+	}
+}
+
+func (r JobRunRepositoryImpl) StartJobRun(ctx context.Context, job, trigger string, scheduledFor, startedAt time.Time) (int64, error) {
+	query := `INSERT INTO job_runs (job, trigger, status, scheduled_for, started_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+	var id int64
+	if err := querier(ctx, r.dbProvider).QueryRowContext(ctx, query, job, trigger, models.JobRunStatusRunning, scheduledFor, startedAt).Scan(&id); err != nil {
+		log.Errorf("start job run err: %v", err)
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r JobRunRepositoryImpl) FinishJobRun(ctx context.Context, id int64, status, errText string, finishedAt time.Time) error {
+	query := `UPDATE job_runs SET status = $1, error = $2, finished_at = $3 WHERE id = $4`
+	if _, err := querier(ctx, r.dbProvider).ExecContext(ctx, query, status, errText, finishedAt, id); err != nil {
+		log.Errorf("finish job run err: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetLastJobRuns возвращает последний запуск каждой задачи.
+func (r JobRunRepositoryImpl) GetLastJobRuns(ctx context.Context) (map[string]models.JobRun, error) {
+	query := `SELECT id, job, trigger, status, error, scheduled_for, started_at, finished_at
+              FROM (
+                  SELECT *, ROW_NUMBER() OVER (PARTITION BY job ORDER BY started_at DESC) AS n FROM job_runs
+              ) runs
+              WHERE n = 1`
+	rows, err := querier(ctx, r.dbProvider).QueryContext(ctx, query)
+	if err != nil {
+		log.Errorf("get last job runs err: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := make(map[string]models.JobRun)
+	for rows.Next() {
+		var run models.JobRun
+		if err := rows.Scan(&run.ID, &run.Job, &run.Trigger, &run.Status, &run.Error, &run.ScheduledFor, &run.StartedAt, &run.FinishedAt); err != nil {
+			log.Errorf("scan job run err: %v", err)
+			return nil, err
+		}
+		runs[run.Job] = run
+	}
+	return runs, rows.Err()
+}
+
+// GetLastScheduledRun возвращает логическое время последнего успешного запуска по расписанию
+// (включая догоняющие). Упавшие и прерванные запуски со статусом running не учитываются, чтобы их догнать.
+// Нулевое время — задача ещё ни разу не выполнялась успешно.
+func (r JobRunRepositoryImpl) GetLastScheduledRun(ctx context.Context, job string) (time.Time, error) {
+	// ORDER BY ... LIMIT 1, а не MAX: у результата агрегата SQLite нет типа колонки, и время вернулось бы строкой
+	query := `SELECT scheduled_for FROM job_runs WHERE job = $1 AND trigger <> $2 AND status = $3
+              ORDER BY scheduled_for DESC LIMIT 1`
+	var last time.Time
+	err := querier(ctx, r.dbProvider).QueryRowContext(ctx, query, job, models.JobTriggerManual, models.JobRunStatusSuccess).Scan(&last)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		log.Errorf("get last scheduled run err: %v", err)
+		return time.Time{}, err
+	}
+	// Колонка TIMESTAMP хранит местное время без пояса, а pq возвращает его с поясом UTC
+	return time.Date(last.Year(), last.Month(), last.Day(), last.Hour(), last.Minute(), last.Second(), last.Nanosecond(), time.Local), nil
+}
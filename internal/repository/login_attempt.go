@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"gift-bot/pkg/models"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+type LoginAttemptRepositoryImpl struct {
+	dbProvider DBProvider
+}
+
+func NewLoginAttemptRepository(dbProvider DBProvider) *LoginAttemptRepositoryImpl {
+	return &LoginAttemptRepositoryImpl{
+		dbProvider: dbProvider,
+	}
+}
+
+// GetLoginAttempt возвращает состояние попыток входа; если записи нет — пустую структуру.
+func (l LoginAttemptRepositoryImpl) GetLoginAttempt(ctx context.Context, telegramID int64) (models.LoginAttempt, error) {
+	query := `
+    SELECT telegram_id, username, failed_attempts, lockouts, locked_until, last_attempt_at
+    FROM login_attempts
+    WHERE telegram_id = $1`
+	var attempt models.LoginAttempt
+	err := querier(ctx, l.dbProvider).GetContext(ctx, &attempt, query, telegramID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.LoginAttempt{TelegramID: telegramID}, nil
+	}
+	if err != nil {
+		log.Errorf("get login attempt err: %v", err)
+		return models.LoginAttempt{}, err
+	}
+	return attempt, nil
+}
+
+// IncrementFailedLogin атомарно увеличивает счётчик неудачных попыток и возвращает новое состояние.
+func (l LoginAttemptRepositoryImpl) IncrementFailedLogin(ctx context.Context, telegramID int64, username string) (models.LoginAttempt, error) {
+	query := `
+    INSERT INTO login_attempts (telegram_id, username, failed_attempts, last_attempt_at)
+    VALUES ($1, $2, 1, $3)
+    ON CONFLICT (telegram_id) DO UPDATE
+        SET failed_attempts = login_attempts.failed_attempts + 1,
+            username = EXCLUDED.username,
+            last_attempt_at = EXCLUDED.last_attempt_at
+    RETURNING telegram_id, username, failed_attempts, lockouts, locked_until, last_attempt_at`
+	var attempt models.LoginAttempt
+	if err := querier(ctx, l.dbProvider).GetContext(ctx, &attempt, query, telegramID, username, time.Now()); err != nil {
+		log.Errorf("increment failed login err: %v", err)
+		return models.LoginAttempt{}, err
+	}
+	return attempt, nil
+}
+
+// LockLogin блокирует вход до until и начинает новую серию попыток.
+func (l LoginAttemptRepositoryImpl) LockLogin(ctx context.Context, telegramID int64, until time.Time) error {
+	query := `UPDATE login_attempts SET failed_attempts = 0, lockouts = lockouts + 1, locked_until = $1 WHERE telegram_id = $2`
+	_, err := querier(ctx, l.dbProvider).ExecContext(ctx, query, until, telegramID)
+	if err != nil {
+		log.Errorf("lock login err: %v", err)
+		return err
+	}
+	return nil
+}
+
+// DeleteLoginAttempt сбрасывает счётчики и блокировку. Возвращает false, если записи не было.
+func (l LoginAttemptRepositoryImpl) DeleteLoginAttempt(ctx context.Context, telegramID int64) (bool, error) {
+	res, err := querier(ctx, l.dbProvider).ExecContext(ctx, `DELETE FROM login_attempts WHERE telegram_id = $1`, telegramID)
+	if err != nil {
+		log.Errorf("delete login attempt err: %v", err)
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Errorf("delete login attempt rows err: %v", err)
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+func (l LoginAttemptRepositoryImpl) GetLockedLogins(ctx context.Context, now time.Time) ([]models.LoginAttempt, error) {
+	query := `
+    SELECT telegram_id, username, failed_attempts, lockouts, locked_until, last_attempt_at
+    FROM login_attempts
+    WHERE locked_until > $1
+    ORDER BY locked_until`
+	var attempts []models.LoginAttempt
+	if err := querier(ctx, l.dbProvider).SelectContext(ctx, &attempts, query, now); err != nil {
+		log.Errorf("get locked logins err: %v", err)
+		return nil, err
+	}
+	return attempts, nil
+}
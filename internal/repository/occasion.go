@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"gift-bot/pkg/models"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+type OccasionRepositoryImpl struct {
+	dbProvider DBProvider
+}
+
+func NewOccasionRepository(dbProvider DBProvider) *OccasionRepositoryImpl {
+	return &OccasionRepositoryImpl{
+		dbProvider: dbProvider,
+	}
+}
+
+func (o OccasionRepositoryImpl) GetOccasionTypes(ctx context.Context) ([]models.OccasionType, error) {
+	query := `SELECT code, title, recurring, lead_days, reminder_template FROM occasion_types ORDER BY code`
+	var types []models.OccasionType
+	if err := querier(ctx, o.dbProvider).SelectContext(ctx, &types, query); err != nil {
+		log.Errorf("get occasion types err: %v", err)
+		return nil, err
+	}
+	return types, nil
+}
+
+func (o OccasionRepositoryImpl) UpdateOccasionType(ctx context.Context, occasionType models.OccasionType) error {
+	query := `UPDATE occasion_types SET lead_days = $1, reminder_template = $2 WHERE code = $3;`
+	_, err := querier(ctx, o.dbProvider).ExecContext(ctx, query, occasionType.LeadDays, occasionType.ReminderTemplate, occasionType.Code)
+	if err != nil {
+		log.Errorf("update occasion type err: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (o OccasionRepositoryImpl) CreateOccasion(ctx context.Context, occasion models.Occasion) (int64, error) {
+	query := `INSERT INTO occasions (user_telegram_id, type_code, title, occasion_date, created_by, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6)
+              RETURNING id`
+	var id int64
+	err := querier(ctx, o.dbProvider).QueryRowContext(ctx, query, occasion.UserTelegramID, occasion.TypeCode, occasion.Title,
+		occasion.Date, occasion.CreatedBy, time.Now()).Scan(&id)
+	if err != nil {
+		log.Errorf("create occasion err: %v", err)
+		return 0, err
+	}
+	return id, nil
+}
+
+func (o OccasionRepositoryImpl) DeleteOccasion(ctx context.Context, id int64) error {
+	query := `DELETE FROM occasions WHERE id = $1;`
+	_, err := querier(ctx, o.dbProvider).ExecContext(ctx, query, id)
+	if err != nil {
+		log.Errorf("delete occasion err: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (o OccasionRepositoryImpl) GetOccasions(ctx context.Context) ([]models.Occasion, error) {
+	query := `
+    SELECT id, user_telegram_id, type_code, title, occasion_date, created_by, created_at
+    FROM occasions
+    ORDER BY id`
+	var occasions []models.Occasion
+	if err := querier(ctx, o.dbProvider).SelectContext(ctx, &occasions, query); err != nil {
+		log.Errorf("get occasions err: %v", err)
+		return nil, err
+	}
+	return occasions, nil
+}
+
+func (o OccasionRepositoryImpl) GetUserOccasions(ctx context.Context, telegramID int64) ([]models.Occasion, error) {
+	query := `
+    SELECT id, user_telegram_id, type_code, title, occasion_date, created_by, created_at
+    FROM occasions
+    WHERE user_telegram_id = $1
+    ORDER BY id`
+	var occasions []models.Occasion
+	if err := querier(ctx, o.dbProvider).SelectContext(ctx, &occasions, query, telegramID); err != nil {
+		log.Errorf("get user occasions err: %v", err)
+		return nil, err
+	}
+	return occasions, nil
+}
+
+// SaveOccasionNotification отмечает уведомление отправленным и возвращает false, если отметка уже есть.
+// Вызывается до отправки внутри WithTx: одновременный второй вызов ждёт фиксации первого и получает false.
+func (o OccasionRepositoryImpl) SaveOccasionNotification(ctx context.Context, adminTelegramID int64, occasionID int64, date time.Time) (bool, error) {
+	query := `INSERT INTO occasion_notifications (admin_telegram_id, occasion_id, notify_date)
+			  VALUES ($1, $2, $3)
+			  ON CONFLICT DO NOTHING;`
+	res, err := querier(ctx, o.dbProvider).ExecContext(ctx, query, adminTelegramID, occasionID, date)
+	if err != nil {
+		log.Errorf("save occasion notification err: %v", err)
+		return false, err
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		log.Errorf("save occasion notification err: %v", err)
+		return false, err
+	}
+	return inserted > 0, nil
+}
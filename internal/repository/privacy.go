@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"gift-bot/pkg/models"
+
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+)
+
+// deletedUserTelegramID подставляется вместо автора записей, которые остаются после удаления пользователя.
+const deletedUserTelegramID = 0
+
+type PrivacyRepositoryImpl struct {
+	dbProvider DBProvider
+}
+
+func NewPrivacyRepository(dbProvider DBProvider) *PrivacyRepositoryImpl {
+	return &PrivacyRepositoryImpl{dbProvider: dbProvider}
+}
+
+// GetUserDataExport собирает данные пользователя из всех таблиц, кроме профиля.
+func (p PrivacyRepositoryImpl) GetUserDataExport(ctx context.Context, telegramID int64) (models.UserDataExport, error) {
+	db := querier(ctx, p.dbProvider)
+	export := models.UserDataExport{
+		Occasions:             []models.Occasion{},
+		BirthdayNotifications: []models.BirthdayNotification{},
+		OccasionNotifications: []models.OccasionNotification{},
+		HolidayDeliveries:     []models.HolidayDelivery{},
+		SantaParticipations:   []models.SantaParticipation{},
+		SantaExclusions:       []models.SantaExclusion{},
+		InviteCodes:           []models.InviteCode{},
+	}
+
+	if err := db.GetContext(ctx, &export.HasCalendarToken, `SELECT calendar_token_hash IS NOT NULL FROM users WHERE telegram_id = $1`, telegramID); err != nil {
+		log.Errorf("export calendar token err: %v", err)
+		return models.UserDataExport{}, err
+	}
+
+	queries := []struct {
+		name  string
+		dest  interface{}
+		query string
+	}{
+		{"occasions", &export.Occasions, `SELECT id, user_telegram_id, type_code, title, occasion_date, created_by, created_at
+            FROM occasions WHERE user_telegram_id = $1 ORDER BY id`},
+		{"birthday notifications", &export.BirthdayNotifications, `SELECT admin_telegram_id, user_telegram_id, notify_date
+            FROM birthday_notifications WHERE user_telegram_id = $1 OR admin_telegram_id = $1 ORDER BY notify_date`},
+		{"occasion notifications", &export.OccasionNotifications, `SELECT n.admin_telegram_id, n.occasion_id, n.notify_date
+            FROM occasion_notifications n JOIN occasions o ON o.id = n.occasion_id
+            WHERE o.user_telegram_id = $1 OR n.admin_telegram_id = $1 ORDER BY n.notify_date`},
+		{"holiday deliveries", &export.HolidayDeliveries, `SELECT d.holiday_id, h.name AS holiday_name, d.greet_date
+            FROM holiday_deliveries d JOIN holidays h ON h.id = d.holiday_id
+            WHERE d.telegram_id = $1 ORDER BY d.greet_date`},
+		{"santa participations", &export.SantaParticipations, `SELECT e.id AS event_id, e.title, e.year, sp.joined_at, pr.recipient_telegram_id
+            FROM santa_participants sp
+            JOIN santa_events e ON e.id = sp.event_id
+            LEFT JOIN santa_pairs pr ON pr.event_id = sp.event_id AND pr.giver_telegram_id = sp.telegram_id
+            WHERE sp.telegram_id = $1 ORDER BY e.id`},
+		{"santa exclusions", &export.SantaExclusions, `SELECT event_id, first_telegram_id, second_telegram_id
+            FROM santa_exclusions WHERE first_telegram_id = $1 OR second_telegram_id = $1`},
+		{"invite codes", &export.InviteCodes, `SELECT id, code, created_by, team, max_uses, uses, expires_at, revoked, created_at
+            FROM invite_codes WHERE created_by = $1 ORDER BY id`},
+	}
+	for _, q := range queries {
+		if err := db.SelectContext(ctx, q.dest, q.query, telegramID); err != nil {
+			log.Errorf("export %s err: %v", q.name, err)
+			return models.UserDataExport{}, err
+		}
+	}
+	return export, nil
+}
+
+// PurgeUser в одной транзакции удаляет пользователя и все связанные с ним записи.
+// Записи, которые нужны другим (события Санты, приглашения, события коллег), обезличиваются.
+func (p PrivacyRepositoryImpl) PurgeUser(ctx context.Context, telegramID int64) error {
+	return withTx(ctx, p.dbProvider, func(ctx context.Context) error {
+		tx := querier(ctx, p.dbProvider)
+
+		statements := []string{
+			`DELETE FROM birthday_notifications WHERE user_telegram_id = $1 OR admin_telegram_id = $1`,
+			`DELETE FROM occasion_notifications WHERE admin_telegram_id = $1`,
+			`DELETE FROM occasions WHERE user_telegram_id = $1`,
+			`DELETE FROM holiday_deliveries WHERE telegram_id = $1`,
+			`DELETE FROM santa_pairs WHERE giver_telegram_id = $1 OR recipient_telegram_id = $1`,
+			`DELETE FROM santa_exclusions WHERE first_telegram_id = $1 OR second_telegram_id = $1`,
+			`DELETE FROM santa_participants WHERE telegram_id = $1`,
+			`DELETE FROM login_attempts WHERE telegram_id = $1`,
+			`DELETE FROM broadcast_deliveries WHERE user_telegram_id = $1`,
+			`DELETE FROM users WHERE telegram_id = $1`,
+		}
+		for _, statement := range statements {
+			if _, err := tx.ExecContext(ctx, statement, telegramID); err != nil {
+				log.Errorf("purge user err: %v", err)
+				return err
+			}
+		}
+		if err := removeFromBroadcasts(ctx, tx, telegramID); err != nil {
+			log.Errorf("purge user from broadcasts err: %v", err)
+			return err
+		}
+
+		anonymize := []string{
+			`UPDATE occasions SET created_by = $2 WHERE created_by = $1`,
+			`UPDATE santa_events SET created_by = $2 WHERE created_by = $1`,
+			`UPDATE invite_codes SET created_by = $2 WHERE created_by = $1`,
+			`UPDATE api_keys SET created_by = $2 WHERE created_by = $1`,
+			`UPDATE broadcasts SET created_by = $2 WHERE created_by = $1`,
+			`UPDATE users SET approved_by = $2 WHERE approved_by = $1`,
+			`UPDATE audit_events SET actor_telegram_id = $2 WHERE actor_telegram_id = $1`,
+			`UPDATE audit_events SET target_telegram_id = $2 WHERE target_telegram_id = $1`,
+		}
+		for _, statement := range anonymize {
+			if _, err := tx.ExecContext(ctx, statement, telegramID, deletedUserTelegramID); err != nil {
+				log.Errorf("anonymize user records err: %v", err)
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// removeFromBroadcasts убирает пользователя из списков получателей и исключений рассылок.
+// Списки разбираются здесь, а не в SQL: array_remove есть только в Postgres.
+func removeFromBroadcasts(ctx context.Context, tx Querier, telegramID int64) error {
+	type broadcastLists struct {
+		id          int64
+		userIDs     []int64
+		excludedIDs []int64
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT id, user_ids, excluded_ids FROM broadcasts`)
+	if err != nil {
+		return err
+	}
+	var changed []broadcastLists
+	for rows.Next() {
+		var b broadcastLists
+		if err := rows.Scan(&b.id, pq.Array(&b.userIDs), pq.Array(&b.excludedIDs)); err != nil {
+			rows.Close()
+			return err
+		}
+		userIDs, removedUser := withoutID(b.userIDs, telegramID)
+		excludedIDs, removedExcluded := withoutID(b.excludedIDs, telegramID)
+		if removedUser || removedExcluded {
+			changed = append(changed, broadcastLists{id: b.id, userIDs: userIDs, excludedIDs: excludedIDs})
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, b := range changed {
+		query := `UPDATE broadcasts SET user_ids = $1, excluded_ids = $2 WHERE id = $3`
+		if _, err := tx.ExecContext(ctx, query, pq.Array(b.userIDs), pq.Array(b.excludedIDs), b.id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withoutID возвращает ids без id (не nil: колонки массивов NOT NULL) и признак, что id там был.
+func withoutID(ids []int64, id int64) ([]int64, bool) {
+	filtered := make([]int64, 0, len(ids))
+	for _, v := range ids {
+		if v != id {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered, len(filtered) != len(ids)
+}
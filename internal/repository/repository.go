@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"context"
 	"gift-bot/pkg/models"
 	"github.com/jmoiron/sqlx"
 	"time"
@@ -8,6 +9,18 @@ import (
 
 type Repositories struct {
 	UserRepository
+	SantaRepository
+	OccasionRepository
+	HolidayRepository
+	InviteRepository
+	PrivacyRepository
+	LoginAttemptRepository
+	AuditRepository
+	APIKeyRepository
+	BroadcastRepository
+	HealthRepository
+	JobRunRepository
+	Transactor
 }
 
 type DBProvider interface {
@@ -16,19 +29,154 @@ type DBProvider interface {
 
 func NewRepositories(dbProvider DBProvider) *Repositories {
 	userRepository := NewUserRepository(dbProvider)
-	return &Repositories{UserRepository: userRepository}
+	santaRepository := NewSantaRepository(dbProvider)
+	occasionRepository := NewOccasionRepository(dbProvider)
+	holidayRepository := NewHolidayRepository(dbProvider)
+	inviteRepository := NewInviteRepository(dbProvider)
+	privacyRepository := NewPrivacyRepository(dbProvider)
+	loginAttemptRepository := NewLoginAttemptRepository(dbProvider)
+	auditRepository := NewAuditRepository(dbProvider)
+	apiKeyRepository := NewAPIKeyRepository(dbProvider)
+	broadcastRepository := NewBroadcastRepository(dbProvider)
+	healthRepository := NewHealthRepository(dbProvider)
+	jobRunRepository := NewJobRunRepository(dbProvider)
+	transactor := NewTransactor(dbProvider)
+	return &Repositories{
+		UserRepository:         userRepository,
+		SantaRepository:        santaRepository,
+		OccasionRepository:     occasionRepository,
+		HolidayRepository:      holidayRepository,
+		InviteRepository:       inviteRepository,
+		PrivacyRepository:      privacyRepository,
+		LoginAttemptRepository: loginAttemptRepository,
+		AuditRepository:        auditRepository,
+		APIKeyRepository:       apiKeyRepository,
+		BroadcastRepository:    broadcastRepository,
+		HealthRepository:       healthRepository,
+		JobRunRepository:       jobRunRepository,
+		Transactor:             transactor,
+	}
+}
+
+// Transactor объединяет вызовы репозиториев в одну транзакцию, см. TransactorImpl.WithTx.
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 type UserRepository interface {
-	CreateUser(user models.User) error
-	GetUser(user models.User) (models.User, error)
-	GetAllUsers() ([]models.User, error)
-	GetBlockedUsers() ([]models.User, error)
-	DeleteUsersByUsernames(usernames []string) error
-	UnblockUsersByUsernames(usernames []string) error
-	UpdateUser(user models.User) error
-	GetUsersWithBirthdayInDays() ([]models.User, error)
-	GetAllAdmins() ([]models.User, error)
-	HasBirthdayNotification(adminTelegramID int64, userTelegramID int64, date time.Time) (bool, error)
-	SaveBirthdayNotification(adminTelegramID int64, userTelegramID int64, date time.Time) error
+	CreateUser(ctx context.Context, user models.User) error
+	GetUser(ctx context.Context, user models.User) (models.User, error)
+	GetAllUsers(ctx context.Context) ([]models.User, error)
+	GetBlockedUsers(ctx context.Context) ([]models.User, error)
+	DeleteUsersByUsernames(ctx context.Context, usernames []string) error
+	UnblockUsersByUsernames(ctx context.Context, usernames []string) error
+	UpdateUser(ctx context.Context, user models.User) error
+	UpdateUserProfile(ctx context.Context, user models.User) error
+	PatchUser(ctx context.Context, telegramID int64, patch models.UserPatch) (bool, error)
+	SetUserTeam(ctx context.Context, telegramID int64, team string) error
+	SetCalendarTokenHash(ctx context.Context, telegramID int64, tokenHash string) error
+	HasCalendarToken(ctx context.Context, telegramID int64) (bool, error)
+	GetUserByCalendarTokenHash(ctx context.Context, tokenHash string) (models.User, error)
+	GetPendingUsers(ctx context.Context) ([]models.User, error)
+	ReviewRegistration(ctx context.Context, telegramID int64, status string, reviewerID int64) (bool, error)
+	GetAllAdmins(ctx context.Context) ([]models.User, error)
+	GetUsersByRoles(ctx context.Context, roles []string) ([]models.User, error)
+	SetUserRole(ctx context.Context, telegramID int64, role string) (bool, error)
+	ClaimOwner(ctx context.Context, telegramID int64) (bool, error)
+	SetUserBlocked(ctx context.Context, telegramID int64, blocked bool) (bool, error)
+	SearchUsers(ctx context.Context, filter models.UserFilter) ([]models.User, int, error)
+	SaveBirthdayNotification(ctx context.Context, adminTelegramID int64, userTelegramID int64, date time.Time) (bool, error)
+}
+
+type SantaRepository interface {
+	CreateSantaEvent(ctx context.Context, event models.SantaEvent) (int64, error)
+	GetActiveSantaEvent(ctx context.Context) (models.SantaEvent, error)
+	GetSantaEvent(ctx context.Context, eventID int64) (models.SantaEvent, error)
+	GetPreviousSantaEvent(ctx context.Context, eventID int64) (models.SantaEvent, error)
+	CloseSantaEvent(ctx context.Context, eventID int64) error
+	AddSantaParticipant(ctx context.Context, eventID int64, telegramID int64) error
+	RemoveSantaParticipant(ctx context.Context, eventID int64, telegramID int64) error
+	GetSantaParticipants(ctx context.Context, eventID int64) ([]models.User, error)
+	AddSantaExclusion(ctx context.Context, exclusion models.SantaExclusion) error
+	GetSantaExclusions(ctx context.Context, eventID int64) ([]models.SantaExclusion, error)
+	SaveSantaDraw(ctx context.Context, eventID int64, pairs []models.SantaPair, digest string) (bool, error)
+	GetSantaPairs(ctx context.Context, eventID int64) ([]models.SantaPair, error)
+	MarkSantaPairNotified(ctx context.Context, eventID int64, giverTelegramID int64) error
+}
+
+type OccasionRepository interface {
+	GetOccasionTypes(ctx context.Context) ([]models.OccasionType, error)
+	UpdateOccasionType(ctx context.Context, occasionType models.OccasionType) error
+	CreateOccasion(ctx context.Context, occasion models.Occasion) (int64, error)
+	DeleteOccasion(ctx context.Context, id int64) error
+	GetOccasions(ctx context.Context) ([]models.Occasion, error)
+	GetUserOccasions(ctx context.Context, telegramID int64) ([]models.Occasion, error)
+	SaveOccasionNotification(ctx context.Context, adminTelegramID int64, occasionID int64, date time.Time) (bool, error)
+}
+
+type HolidayRepository interface {
+	UpsertHoliday(ctx context.Context, holiday models.Holiday) error
+	GetHolidays(ctx context.Context) ([]models.Holiday, error)
+	GetHoliday(ctx context.Context, id int64) (models.Holiday, error)
+	SetHolidayGreetingOverride(ctx context.Context, id int64, greeting string) error
+	HasHolidayDelivery(ctx context.Context, holidayID int64, telegramID int64, date time.Time) (bool, error)
+	SaveHolidayDelivery(ctx context.Context, holidayID int64, telegramID int64, date time.Time) error
+}
+
+type InviteRepository interface {
+	CreateInviteCode(ctx context.Context, invite models.InviteCode) (int64, error)
+	GetInviteCodeByCode(ctx context.Context, code string) (models.InviteCode, error)
+	GetActiveInviteCodes(ctx context.Context) ([]models.InviteCode, error)
+	RevokeInviteCode(ctx context.Context, id int64) error
+	RegisterUserWithInvite(ctx context.Context, user models.User, inviteID int64) error
+}
+
+type LoginAttemptRepository interface {
+	GetLoginAttempt(ctx context.Context, telegramID int64) (models.LoginAttempt, error)
+	IncrementFailedLogin(ctx context.Context, telegramID int64, username string) (models.LoginAttempt, error)
+	LockLogin(ctx context.Context, telegramID int64, until time.Time) error
+	DeleteLoginAttempt(ctx context.Context, telegramID int64) (bool, error)
+	GetLockedLogins(ctx context.Context, now time.Time) ([]models.LoginAttempt, error)
+}
+
+type AuditRepository interface {
+	SaveAuditEvent(ctx context.Context, event models.AuditEvent) error
+	GetAuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, int, error)
+}
+
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key models.APIKey, keyHash string) (int64, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error)
+	GetActiveAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int64) (bool, error)
+	TouchAPIKey(ctx context.Context, id int64, usedAt time.Time) error
+}
+
+type BroadcastRepository interface {
+	CreateBroadcast(ctx context.Context, b models.Broadcast) (int64, error)
+	GetBroadcast(ctx context.Context, id int64) (models.Broadcast, error)
+	ClaimBroadcast(ctx context.Context, id int64) (models.Broadcast, bool, error)
+	ClaimDueBroadcasts(ctx context.Context, now time.Time, staleBefore time.Time) ([]models.Broadcast, error)
+	ExtendBroadcastClaim(ctx context.Context, id int64) error
+	AddBroadcastRecipients(ctx context.Context, broadcastID int64, telegramIDs []int64) error
+	GetPendingBroadcastRecipients(ctx context.Context, broadcastID int64) ([]int64, error)
+	SaveBroadcastDelivery(ctx context.Context, broadcastID int64, telegramID int64, status string, errText string) error
+	FinishBroadcast(ctx context.Context, id int64) error
+	GetBroadcastStats(ctx context.Context, id int64) (models.BroadcastStats, error)
+}
+
+type PrivacyRepository interface {
+	GetUserDataExport(ctx context.Context, telegramID int64) (models.UserDataExport, error)
+	PurgeUser(ctx context.Context, telegramID int64) error
+}
+
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+}
+
+type JobRunRepository interface {
+	StartJobRun(ctx context.Context, job, trigger string, scheduledFor, startedAt time.Time) (int64, error)
+	FinishJobRun(ctx context.Context, id int64, status, errText string, finishedAt time.Time) error
+	GetLastJobRuns(ctx context.Context) (map[string]models.JobRun, error)
+	GetLastScheduledRun(ctx context.Context, job string) (time.Time, error)
 }
@@ -0,0 +1,620 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gift-bot/pkg/config"
+	"gift-bot/pkg/migrations"
+	"gift-bot/pkg/models"
+	"gift-bot/pkg/sqlite"
+	"github.com/jmoiron/sqlx"
+)
+
+// postgresDSNEnv — DSN Postgres для прогона тестов на втором хранилище. Каждый тест получает свою схему,
+// которая удаляется после него, поэтому подойдёт любая БД, где пользователю можно создавать схемы.
+const postgresDSNEnv = "TEST_POSTGRES_DSN"
+
+type testDB struct {
+	db *sqlx.DB
+}
+
+func (t testDB) DB() *sqlx.DB {
+	return t.db
+}
+
+type backend struct {
+	name string
+	open func(t *testing.T) DBProvider
+}
+
+// backends — хранилища, на которых выполняются одни и те же случаи: SQLite в памяти всегда, Postgres — если задан DSN.
+func backends() []backend {
+	list := []backend{{config.StorageSQLite, openSQLite}}
+	if dsn := os.Getenv(postgresDSNEnv); dsn != "" {
+		list = append(list, backend{config.StoragePostgres, func(t *testing.T) DBProvider { return openPostgres(t, dsn) }})
+	}
+	return list
+}
+
+func openSQLite(t *testing.T) DBProvider {
+	t.Helper()
+	m, err := sqlite.Open(context.Background(), ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { _ = m.Close() })
+	migrateTestDB(t, m.DB(), config.StorageSQLite)
+	return m
+}
+
+func openPostgres(t *testing.T, dsn string) DBProvider {
+	t.Helper()
+	admin, err := sqlx.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		_, _ = admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		_ = admin.Close()
+	})
+
+	// lib/pq передаёт неизвестные параметры DSN серверу как параметры сеанса
+	sep := " "
+	if strings.Contains(dsn, "://") {
+		sep = "&"
+		if !strings.Contains(dsn, "?") {
+			sep = "?"
+		}
+	}
+	db, err := sqlx.Open("postgres", dsn+sep+"search_path="+schema)
+	if err != nil {
+		t.Fatalf("open postgres: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	migrateTestDB(t, db, config.StoragePostgres)
+	return testDB{db: db}
+}
+
+func migrateTestDB(t *testing.T, db *sqlx.DB, storage string) {
+	t.Helper()
+	m, err := migrations.New(context.Background(), db, storage)
+	if err != nil {
+		t.Fatalf("prepare migrations: %v", err)
+	}
+	defer m.Close()
+	if err := m.Up(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+}
+
+func TestRebindSQLite(t *testing.T) {
+	cases := []struct{ query, want string }{
+		{`SELECT 1`, `SELECT 1`},
+		{`a = $1 AND b = $2`, `a = ?1 AND b = ?2`},
+		{`a = $2 AND b = $1 OR c = $2`, `a = ?2 AND b = ?1 OR c = ?2`},
+		{`VALUES ($9, $10, $11)`, `VALUES (?9, ?10, ?11)`},
+	}
+	for _, tc := range cases {
+		if got := rebindSQLite(tc.query); got != tc.want {
+			t.Errorf("rebindSQLite(%q) = %q, want %q", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestInList(t *testing.T) {
+	cases := []struct {
+		start, n int
+		want     string
+	}{
+		{1, 0, "(NULL)"},
+		{1, 1, "($1)"},
+		{3, 3, "($3, $4, $5)"},
+	}
+	for _, tc := range cases {
+		if got := inList(tc.start, tc.n); got != tc.want {
+			t.Errorf("inList(%d, %d) = %q, want %q", tc.start, tc.n, got, tc.want)
+		}
+	}
+	if got := listArgs([]string{"a", "b"}); !reflect.DeepEqual(got, []any{"a", "b"}) {
+		t.Errorf("listArgs = %v", got)
+	}
+}
+
+// repositoryCases выполняются на каждом хранилище из backends, каждый на чистой БД.
+var repositoryCases = []struct {
+	name string
+	run  func(t *testing.T, ctx context.Context, r *Repositories)
+}{
+	{"users by roles", func(t *testing.T, ctx context.Context, r *Repositories) {
+		createUsers(t, ctx, r,
+			models.User{TelegramID: 1, Username: "owner", Role: models.RoleOwner},
+			models.User{TelegramID: 2, Username: "admin", Role: models.RoleAdmin},
+			models.User{TelegramID: 3, Username: "user", Role: models.RoleUser},
+			models.User{TelegramID: 4, Username: "pending", Role: models.RoleAdmin, Status: models.UserStatusPending},
+		)
+		users, err := r.GetUsersByRoles(ctx, []string{models.RoleAdmin, models.RoleOwner})
+		mustNoErr(t, err)
+		if got := telegramIDs(users); !sameIDs(got, 1, 2) {
+			t.Errorf("admins and owners = %v, want [1 2]", got)
+		}
+		users, err = r.GetUsersByRoles(ctx, nil)
+		mustNoErr(t, err)
+		if len(users) != 0 {
+			t.Errorf("users for no roles = %v", telegramIDs(users))
+		}
+	}},
+	{"block and unblock by usernames", func(t *testing.T, ctx context.Context, r *Repositories) {
+		createUsers(t, ctx, r,
+			models.User{TelegramID: 1, Username: "owner", Role: models.RoleOwner},
+			models.User{TelegramID: 2, Username: "bob", Role: models.RoleUser},
+			models.User{TelegramID: 3, Username: "eve", Role: models.RoleUser},
+		)
+		mustNoErr(t, r.DeleteUsersByUsernames(ctx, []string{"owner", "bob", "eve"}))
+		mustNoErr(t, r.DeleteUsersByUsernames(ctx, nil))
+		blocked, err := r.GetBlockedUsers(ctx)
+		mustNoErr(t, err)
+		if got := telegramIDs(blocked); !sameIDs(got, 2, 3) {
+			t.Errorf("blocked = %v, want [2 3]: the owner cannot be blocked", got)
+		}
+
+		mustNoErr(t, r.UnblockUsersByUsernames(ctx, []string{"eve"}))
+		blocked, err = r.GetBlockedUsers(ctx)
+		mustNoErr(t, err)
+		if got := telegramIDs(blocked); !sameIDs(got, 2) {
+			t.Errorf("blocked after unblock = %v, want [2]", got)
+		}
+
+		changed, err := r.SetUserBlocked(ctx, 1, true)
+		mustNoErr(t, err)
+		if changed {
+			t.Error("SetUserBlocked blocked the owner")
+		}
+		changed, err = r.SetUserBlocked(ctx, 2, false)
+		mustNoErr(t, err)
+		if !changed {
+			t.Error("SetUserBlocked did not unblock a user")
+		}
+	}},
+	{"search users", func(t *testing.T, ctx context.Context, r *Repositories) {
+		createUsers(t, ctx, r,
+			models.User{TelegramID: 1, Username: "Alice", Role: models.RoleUser, Team: "dev"},
+			models.User{TelegramID: 2, Username: "bob", FirstName: "ALICIA", Role: models.RoleAdmin, Team: "dev"},
+			models.User{TelegramID: 3, Username: "carol", Role: models.RoleUser, Team: "ops"},
+		)
+		users, total, err := r.SearchUsers(ctx, models.UserFilter{Query: "@ali", Limit: 1})
+		mustNoErr(t, err)
+		if total != 2 || len(users) != 1 {
+			t.Errorf("search @ali: total %d, page %v; want total 2, one on the page", total, telegramIDs(users))
+		}
+		users, total, err = r.SearchUsers(ctx, models.UserFilter{Query: "ali", Role: models.RoleAdmin, Team: "dev", Limit: 10})
+		mustNoErr(t, err)
+		if total != 1 || !sameIDs(telegramIDs(users), 2) {
+			t.Errorf("search ali admin dev = %v (total %d), want [2]", telegramIDs(users), total)
+		}
+	}},
+	{"role changes spare the owner", func(t *testing.T, ctx context.Context, r *Repositories) {
+		createUsers(t, ctx, r,
+			models.User{TelegramID: 1, Username: "owner", Role: models.RoleOwner},
+			models.User{TelegramID: 2, Username: "bob", Role: models.RoleUser},
+		)
+		for _, tc := range []struct {
+			telegramID int64
+			want       bool
+		}{{1, false}, {2, true}, {3, false}} {
+			changed, err := r.SetUserRole(ctx, tc.telegramID, models.RoleAdmin)
+			mustNoErr(t, err)
+			if changed != tc.want {
+				t.Errorf("SetUserRole(%d) = %v, want %v", tc.telegramID, changed, tc.want)
+			}
+		}
+	}},
+	{"patch user touches only given columns", func(t *testing.T, ctx context.Context, r *Repositories) {
+		createUsers(t, ctx, r, models.User{TelegramID: 2, Username: "bob", FirstName: "Bob", Role: models.RoleUser, Team: "dev", NotifyHolidays: true})
+		// Роль меняется между чтением и PATCH в другом запросе и не должна откатиться
+		_, err := r.SetUserRole(ctx, 2, models.RoleAdmin)
+		mustNoErr(t, err)
+
+		name, notify := "Роберт", false
+		patched, err := r.PatchUser(ctx, 2, models.UserPatch{DisplayName: &name, NotifyHolidays: &notify})
+		mustNoErr(t, err)
+		if !patched {
+			t.Fatal("existing user was not patched")
+		}
+		user, err := r.GetUser(ctx, models.User{TelegramID: 2})
+		mustNoErr(t, err)
+		if user.DisplayName != name || user.NotifyHolidays || user.Role != models.RoleAdmin || user.Team != "dev" || user.FirstName != "Bob" {
+			t.Errorf("patched user = %+v", user)
+		}
+
+		if patched, err := r.PatchUser(ctx, 99, models.UserPatch{DisplayName: &name}); err != nil || patched {
+			t.Errorf("PatchUser of a missing user = %v, %v", patched, err)
+		}
+	}},
+	{"owner claimed once", func(t *testing.T, ctx context.Context, r *Repositories) {
+		createUsers(t, ctx, r,
+			models.User{TelegramID: 1, Username: "alice", Role: models.RoleUser, Status: models.UserStatusPending},
+			models.User{TelegramID: 2, Username: "bob", Role: models.RoleAdmin},
+		)
+		for _, tc := range []struct {
+			telegramID int64
+			want       bool
+		}{{3, false}, {1, true}, {2, false}, {1, false}} {
+			claimed, err := r.ClaimOwner(ctx, tc.telegramID)
+			mustNoErr(t, err)
+			if claimed != tc.want {
+				t.Errorf("ClaimOwner(%d) = %v, want %v", tc.telegramID, claimed, tc.want)
+			}
+		}
+		owner, err := r.GetUser(ctx, models.User{TelegramID: 1})
+		mustNoErr(t, err)
+		if owner.Role != models.RoleOwner || owner.Status != models.UserStatusActive {
+			t.Errorf("owner = %s/%s, want %s/%s", owner.Role, owner.Status, models.RoleOwner, models.UserStatusActive)
+		}
+	}},
+	{"notification dedup", func(t *testing.T, ctx context.Context, r *Repositories) {
+		createUsers(t, ctx, r, models.User{TelegramID: 2, Username: "bob", Role: models.RoleUser})
+		occasionID, err := r.CreateOccasion(ctx, models.Occasion{UserTelegramID: 2, TypeCode: "custom", Title: "x", Date: date(1990, 5, 3), CreatedBy: 1})
+		mustNoErr(t, err)
+
+		today, tomorrow := date(2026, 10, 19), date(2026, 10, 20)
+		for _, step := range []struct {
+			name string
+			save func(time.Time) (bool, error)
+		}{
+			{"birthday", func(d time.Time) (bool, error) { return r.SaveBirthdayNotification(ctx, 1, 2, d) }},
+			{"occasion", func(d time.Time) (bool, error) { return r.SaveOccasionNotification(ctx, 1, occasionID, d) }},
+		} {
+			for i, want := range []bool{true, false} {
+				saved, err := step.save(today)
+				mustNoErr(t, err)
+				if saved != want {
+					t.Errorf("%s notification, save #%d = %v, want %v", step.name, i+1, saved, want)
+				}
+			}
+			saved, err := step.save(tomorrow)
+			mustNoErr(t, err)
+			if !saved {
+				t.Errorf("%s notification for another day was not saved", step.name)
+			}
+		}
+	}},
+	{"holiday upsert and delivery", func(t *testing.T, ctx context.Context, r *Repositories) {
+		holiday := models.Holiday{Code: "new_year", Name: "Новый год", Month: 1, Day: 1, Greeting: "С Новым годом!"}
+		mustNoErr(t, r.UpsertHoliday(ctx, holiday))
+		holiday.Name = "Новый год!"
+		mustNoErr(t, r.UpsertHoliday(ctx, holiday))
+		holidays, err := r.GetHolidays(ctx)
+		mustNoErr(t, err)
+		if len(holidays) != 1 || holidays[0].Name != "Новый год!" {
+			t.Fatalf("holidays after upsert = %+v", holidays)
+		}
+
+		day := date(2027, 1, 1)
+		mustNoErr(t, r.SaveHolidayDelivery(ctx, holidays[0].ID, 2, day))
+		mustNoErr(t, r.SaveHolidayDelivery(ctx, holidays[0].ID, 2, day))
+		delivered, err := r.HasHolidayDelivery(ctx, holidays[0].ID, 2, day)
+		mustNoErr(t, err)
+		if !delivered {
+			t.Error("holiday delivery was not saved")
+		}
+	}},
+	{"login attempts upsert", func(t *testing.T, ctx context.Context, r *Repositories) {
+		for i := 1; i <= 2; i++ {
+			attempt, err := r.IncrementFailedLogin(ctx, 2, "bob")
+			mustNoErr(t, err)
+			if attempt.FailedAttempts != i {
+				t.Errorf("failed attempts after %d increments = %d", i, attempt.FailedAttempts)
+			}
+		}
+		deleted, err := r.DeleteLoginAttempt(ctx, 2)
+		mustNoErr(t, err)
+		if !deleted {
+			t.Error("login attempt was not deleted")
+		}
+	}},
+	{"santa participants and draw", func(t *testing.T, ctx context.Context, r *Repositories) {
+		createUsers(t, ctx, r,
+			models.User{TelegramID: 2, Username: "bob", Role: models.RoleUser},
+			models.User{TelegramID: 3, Username: "eve", Role: models.RoleUser},
+		)
+		eventID, err := r.CreateSantaEvent(ctx, models.SantaEvent{Title: "NY", Year: 2026, Status: models.SantaStatusRegistration, CreatedBy: 1})
+		mustNoErr(t, err)
+		for _, id := range []int64{2, 3, 3} {
+			mustNoErr(t, r.AddSantaParticipant(ctx, eventID, id))
+		}
+		participants, err := r.GetSantaParticipants(ctx, eventID)
+		mustNoErr(t, err)
+		if !sameIDs(telegramIDs(participants), 2, 3) {
+			t.Errorf("participants = %v, want [2 3]", telegramIDs(participants))
+		}
+
+		pairs := []models.SantaPair{{GiverTelegramID: 2, RecipientTelegramID: 3}, {GiverTelegramID: 3, RecipientTelegramID: 2}}
+		drawn, err := r.SaveSantaDraw(ctx, eventID, pairs, "digest")
+		mustNoErr(t, err)
+		if !drawn {
+			t.Fatal("draw of an open event was not saved")
+		}
+		drawn, err = r.SaveSantaDraw(ctx, eventID, pairs[:1], "second")
+		mustNoErr(t, err)
+		if drawn {
+			t.Error("second draw of the same event was saved")
+		}
+		saved, err := r.GetSantaPairs(ctx, eventID)
+		mustNoErr(t, err)
+		if len(saved) != 2 {
+			t.Errorf("pairs = %+v", saved)
+		}
+		event, err := r.GetSantaEvent(ctx, eventID)
+		mustNoErr(t, err)
+		if event.Status != models.SantaStatusDrawn || event.DrawDigest != "digest" || event.DrawnAt == nil {
+			t.Errorf("event after draw = %+v", event)
+		}
+	}},
+	{"broadcast claimed once", func(t *testing.T, ctx context.Context, r *Repositories) {
+		id, err := r.CreateBroadcast(ctx, models.Broadcast{
+			Text: "hi", Audience: "teams", Teams: []string{"dev", "ops"}, UserIDs: []int64{2, 3}, ExcludedIDs: []int64{3},
+			Status: "scheduled", ScheduledAt: time.Now().Add(-time.Minute), CreatedBy: 1,
+		})
+		mustNoErr(t, err)
+		later, err := r.CreateBroadcast(ctx, models.Broadcast{Text: "later", Audience: "all", Status: "scheduled", ScheduledAt: time.Now().Add(time.Hour), CreatedBy: 1})
+		mustNoErr(t, err)
+
+		claimed, err := r.ClaimDueBroadcasts(ctx, time.Now(), time.Now().Add(-time.Hour))
+		mustNoErr(t, err)
+		if len(claimed) != 1 || claimed[0].ID != id {
+			t.Fatalf("claimed = %+v, want only broadcast %d", claimed, id)
+		}
+		b := claimed[0]
+		if b.Status != "sending" || b.StartedAt == nil || !reflect.DeepEqual(b.Teams, []string{"dev", "ops"}) ||
+			!reflect.DeepEqual(b.UserIDs, []int64{2, 3}) || !reflect.DeepEqual(b.ExcludedIDs, []int64{3}) {
+			t.Errorf("claimed broadcast = %+v", b)
+		}
+
+		claimed, err = r.ClaimDueBroadcasts(ctx, time.Now(), time.Now().Add(-time.Hour))
+		mustNoErr(t, err)
+		if len(claimed) != 0 {
+			t.Errorf("broadcast claimed twice: %+v", claimed)
+		}
+
+		// Отправка, не подтверждённая до staleBefore, забирается повторно с прежним started_at
+		mustNoErr(t, r.ExtendBroadcastClaim(ctx, id))
+		claimed, err = r.ClaimDueBroadcasts(ctx, time.Now(), time.Now().Add(time.Second))
+		mustNoErr(t, err)
+		if len(claimed) != 1 || claimed[0].ID != id || claimed[0].StartedAt == nil || !claimed[0].StartedAt.Equal(*b.StartedAt) {
+			t.Errorf("stale broadcast reclaimed = %+v, want broadcast %d started at %v", claimed, id, b.StartedAt)
+		}
+		if _, ok, err := r.ClaimBroadcast(ctx, id); err != nil || ok {
+			t.Errorf("ClaimBroadcast of a claimed broadcast = %v, %v", ok, err)
+		}
+		if _, ok, err := r.ClaimBroadcast(ctx, later); err != nil || !ok {
+			t.Errorf("ClaimBroadcast of a scheduled broadcast = %v, %v", ok, err)
+		}
+	}},
+	{"broadcast recipients in batches", func(t *testing.T, ctx context.Context, r *Repositories) {
+		id, err := r.CreateBroadcast(ctx, models.Broadcast{Text: "hi", Audience: "all", Status: "scheduled", ScheduledAt: time.Now(), CreatedBy: 1})
+		mustNoErr(t, err)
+		recipients := make([]int64, 2*recipientsBatch+1)
+		for i := range recipients {
+			recipients[i] = int64(i + 1)
+		}
+		mustNoErr(t, r.AddBroadcastRecipients(ctx, id, recipients[:10]))
+		mustNoErr(t, r.AddBroadcastRecipients(ctx, id, recipients))
+		mustNoErr(t, r.SaveBroadcastDelivery(ctx, id, 1, "sent", ""))
+		mustNoErr(t, r.SaveBroadcastDelivery(ctx, id, 2, "failed", "blocked by user"))
+
+		stats, err := r.GetBroadcastStats(ctx, id)
+		mustNoErr(t, err)
+		want := models.BroadcastStats{Total: len(recipients), Pending: len(recipients) - 2, Sent: 1, Failed: 1}
+		if stats != want {
+			t.Errorf("stats = %+v, want %+v", stats, want)
+		}
+		pending, err := r.GetPendingBroadcastRecipients(ctx, id)
+		mustNoErr(t, err)
+		if !sameIDs(pending, recipients[2:]...) {
+			t.Errorf("pending recipients: got %d ids, want %d without 1 and 2", len(pending), len(recipients)-2)
+		}
+	}},
+	{"transaction rollback and nesting", func(t *testing.T, ctx context.Context, r *Repositories) {
+		errAbort := errors.New("abort")
+		err := r.WithTx(ctx, func(ctx context.Context) error {
+			createUsers(t, ctx, r, models.User{TelegramID: 2, Username: "bob", Role: models.RoleUser})
+			return r.WithTx(ctx, func(ctx context.Context) error {
+				createUsers(t, ctx, r, models.User{TelegramID: 3, Username: "eve", Role: models.RoleUser})
+				return errAbort
+			})
+		})
+		if !errors.Is(err, errAbort) {
+			t.Fatalf("WithTx err = %v, want %v", err, errAbort)
+		}
+		users, err := r.GetAllUsers(ctx)
+		mustNoErr(t, err)
+		if len(users) != 0 {
+			t.Errorf("users after rollback = %v", telegramIDs(users))
+		}
+	}},
+	{"job runs", func(t *testing.T, ctx context.Context, r *Repositories) {
+		last, err := r.GetLastScheduledRun(ctx, "greetings")
+		mustNoErr(t, err)
+		if !last.IsZero() {
+			t.Errorf("last run without history = %v", last)
+		}
+
+		at := func(hour int) time.Time { return time.Date(2026, 10, 19, hour, 0, 0, 0, time.Local) }
+		runs := []struct {
+			job, trigger, status string
+			scheduledFor         time.Time
+		}{
+			{"greetings", models.JobTriggerSchedule, models.JobRunStatusSuccess, at(9)},
+			{"greetings", models.JobTriggerCatchUp, models.JobRunStatusFailure, at(10)},
+			{"greetings", models.JobTriggerManual, models.JobRunStatusSuccess, at(11)},
+			{"greetings", models.JobTriggerSchedule, models.JobRunStatusRunning, at(12)},
+			{"sync", models.JobTriggerSchedule, models.JobRunStatusSuccess, at(4)},
+		}
+		for _, run := range runs {
+			id, err := r.StartJobRun(ctx, run.job, run.trigger, run.scheduledFor, run.scheduledFor)
+			mustNoErr(t, err)
+			if run.status != models.JobRunStatusRunning {
+				mustNoErr(t, r.FinishJobRun(ctx, id, run.status, "", run.scheduledFor.Add(time.Minute)))
+			}
+		}
+
+		last, err = r.GetLastScheduledRun(ctx, "greetings")
+		mustNoErr(t, err)
+		if !last.Equal(at(9)) {
+			t.Errorf("last scheduled run = %v, want %v: manual, failed and running runs do not count", last, at(9))
+		}
+		latest, err := r.GetLastJobRuns(ctx)
+		mustNoErr(t, err)
+		if len(latest) != 2 || !latest["greetings"].ScheduledFor.Equal(at(12)) || latest["sync"].Status != models.JobRunStatusSuccess {
+			t.Errorf("last job runs = %+v", latest)
+		}
+	}},
+	{"audit payload and filter", func(t *testing.T, ctx context.Context, r *Repositories) {
+		target := int64(2)
+		mustNoErr(t, r.SaveAuditEvent(ctx, models.AuditEvent{ActorTelegramID: 1, Action: models.AuditActionBlock, TargetTelegramID: &target, Payload: json.RawMessage(`{"source":"web"}`)}))
+		mustNoErr(t, r.SaveAuditEvent(ctx, models.AuditEvent{ActorTelegramID: 1, Action: models.AuditActionUnblock, Payload: json.RawMessage(`{}`)}))
+
+		events, total, err := r.GetAuditEvents(ctx, models.AuditFilter{TelegramID: 2, Limit: 10})
+		mustNoErr(t, err)
+		if total != 1 || len(events) != 1 || events[0].Action != models.AuditActionBlock {
+			t.Fatalf("events for user 2 = %+v (total %d)", events, total)
+		}
+		var payload map[string]string
+		mustNoErr(t, json.Unmarshal(events[0].Payload, &payload))
+		if payload["source"] != "web" {
+			t.Errorf("payload = %s", events[0].Payload)
+		}
+	}},
+	{"purge user", func(t *testing.T, ctx context.Context, r *Repositories) {
+		createUsers(t, ctx, r,
+			models.User{TelegramID: 1, Username: "admin", Role: models.RoleAdmin},
+			models.User{TelegramID: 2, Username: "bob", Role: models.RoleUser},
+		)
+		broadcastID, err := r.CreateBroadcast(ctx, models.Broadcast{Text: "hi", Audience: "users", UserIDs: []int64{2, 3}, ExcludedIDs: []int64{2},
+			Status: "scheduled", ScheduledAt: time.Now(), CreatedBy: 2})
+		mustNoErr(t, err)
+		target := int64(2)
+		mustNoErr(t, r.SaveAuditEvent(ctx, models.AuditEvent{ActorTelegramID: 1, Action: models.AuditActionBlock, TargetTelegramID: &target,
+			Payload: json.RawMessage(`{"source":"web"}`)}))
+		_, err = r.SaveBirthdayNotification(ctx, 1, 2, date(2026, 10, 19))
+		mustNoErr(t, err)
+
+		mustNoErr(t, r.PurgeUser(ctx, 2))
+
+		users, err := r.GetAllUsers(ctx)
+		mustNoErr(t, err)
+		if !sameIDs(telegramIDs(users), 1) {
+			t.Errorf("users after purge = %v, want [1]", telegramIDs(users))
+		}
+		b, err := r.GetBroadcast(ctx, broadcastID)
+		mustNoErr(t, err)
+		if !reflect.DeepEqual(b.UserIDs, []int64{3}) || len(b.ExcludedIDs) != 0 || b.CreatedBy != deletedUserTelegramID {
+			t.Errorf("broadcast after purge = %+v", b)
+		}
+		events, _, err := r.GetAuditEvents(ctx, models.AuditFilter{TelegramID: 2, Limit: 10})
+		mustNoErr(t, err)
+		if len(events) != 0 {
+			t.Errorf("audit still references the purged user: %+v", events)
+		}
+		events, _, err = r.GetAuditEvents(ctx, models.AuditFilter{Action: models.AuditActionBlock, Limit: 10})
+		mustNoErr(t, err)
+		if len(events) != 1 || events[0].TargetTelegramID == nil || *events[0].TargetTelegramID != deletedUserTelegramID {
+			t.Errorf("audit after purge = %+v, want the event kept with an anonymized target", events)
+		}
+		saved, err := r.SaveBirthdayNotification(ctx, 1, 2, date(2026, 10, 19))
+		mustNoErr(t, err)
+		if !saved {
+			t.Error("birthday notifications of the purged user were kept")
+		}
+	}},
+	{"invite registration", func(t *testing.T, ctx context.Context, r *Repositories) {
+		inviteID, err := r.CreateInviteCode(ctx, models.InviteCode{Code: "abc", CreatedBy: 1, Team: "dev", MaxUses: 2, ExpiresAt: time.Now().Add(time.Hour)})
+		mustNoErr(t, err)
+		mustNoErr(t, r.RegisterUserWithInvite(ctx, models.User{TelegramID: 2, Username: "bob", Role: models.RoleUser, Team: "dev"}, inviteID))
+
+		invite, err := r.GetInviteCodeByCode(ctx, "abc")
+		mustNoErr(t, err)
+		if invite.Uses != 1 {
+			t.Errorf("invite uses = %d, want 1", invite.Uses)
+		}
+		user, err := r.GetUser(ctx, models.User{TelegramID: 2})
+		mustNoErr(t, err)
+		if user.Team != "dev" {
+			t.Errorf("registered user = %+v", user)
+		}
+	}},
+	{"ping", func(t *testing.T, ctx context.Context, r *Repositories) {
+		mustNoErr(t, r.Ping(ctx))
+	}},
+}
+
+func TestRepositories(t *testing.T) {
+	for _, b := range backends() {
+		t.Run(b.name, func(t *testing.T) {
+			for _, tc := range repositoryCases {
+				t.Run(tc.name, func(t *testing.T) {
+					ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+					defer cancel()
+					tc.run(t, ctx, NewRepositories(b.open(t)))
+				})
+			}
+		})
+	}
+}
+
+func createUsers(t *testing.T, ctx context.Context, r *Repositories, users ...models.User) {
+	t.Helper()
+	for _, user := range users {
+		if err := r.CreateUser(ctx, user); err != nil {
+			t.Fatalf("create user %d: %v", user.TelegramID, err)
+		}
+	}
+}
+
+func mustNoErr(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func telegramIDs(users []models.User) []int64 {
+	ids := make([]int64, len(users))
+	for i, user := range users {
+		ids[i] = user.TelegramID
+	}
+	return ids
+}
+
+// sameIDs сравнивает списки без учёта порядка.
+func sameIDs(got []int64, want ...int64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	seen := map[int64]int{}
+	for _, id := range got {
+		seen[id]++
+	}
+	for _, id := range want {
+		if seen[id]--; seen[id] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"gift-bot/pkg/models"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+type SantaRepositoryImpl struct {
+	dbProvider DBProvider
+}
+
+func NewSantaRepository(dbProvider DBProvider) *SantaRepositoryImpl {
+	return &SantaRepositoryImpl{
+		dbProvider: dbProvider,
+	}
+}
+
+func (s SantaRepositoryImpl) CreateSantaEvent(ctx context.Context, event models.SantaEvent) (int64, error) {
+	query := `INSERT INTO santa_events (title, year, status, exclude_same_team, exclude_previous_pairs, created_by, created_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7)
+              RETURNING id`
+	var id int64
+	err := querier(ctx, s.dbProvider).QueryRowContext(ctx, query, event.Title, event.Year, models.SantaStatusRegistration,
+		event.ExcludeSameTeam, event.ExcludePreviousPairs, event.CreatedBy, time.Now()).Scan(&id)
+	if err != nil {
+		log.Errorf("create santa event err: %v", err)
+		return 0, err
+	}
+	return id, nil
+}
+
+// GetActiveSantaEvent возвращает последнее незакрытое событие (регистрация или жеребьёвка проведена).
+func (s SantaRepositoryImpl) GetActiveSantaEvent(ctx context.Context) (models.SantaEvent, error) {
+	query := `
+    SELECT id, title, year, status, exclude_same_team, exclude_previous_pairs, created_by, draw_digest, created_at, drawn_at
+    FROM santa_events
+    WHERE status <> $1
+    ORDER BY id DESC
+    LIMIT 1`
+	var event models.SantaEvent
+	err := querier(ctx, s.dbProvider).GetContext(ctx, &event, query, models.SantaStatusClosed)
+	if err != nil {
+		return models.SantaEvent{}, err
+	}
+	return event, nil
+}
+
+func (s SantaRepositoryImpl) GetSantaEvent(ctx context.Context, eventID int64) (models.SantaEvent, error) {
+	query := `
+    SELECT id, title, year, status, exclude_same_team, exclude_previous_pairs, created_by, draw_digest, created_at, drawn_at
+    FROM santa_events
+    WHERE id = $1`
+	var event models.SantaEvent
+	err := querier(ctx, s.dbProvider).GetContext(ctx, &event, query, eventID)
+	if err != nil {
+		log.Errorf("get santa event err: %v", err)
+		return models.SantaEvent{}, err
+	}
+	return event, nil
+}
+
+// GetPreviousSantaEvent возвращает последнее событие с проведённой жеребьёвкой, созданное раньше указанного.
+func (s SantaRepositoryImpl) GetPreviousSantaEvent(ctx context.Context, eventID int64) (models.SantaEvent, error) {
+	query := `
+    SELECT id, title, year, status, exclude_same_team, exclude_previous_pairs, created_by, draw_digest, created_at, drawn_at
+    FROM santa_events
+    WHERE id < $1 AND drawn_at IS NOT NULL
+    ORDER BY id DESC
+    LIMIT 1`
+	var event models.SantaEvent
+	err := querier(ctx, s.dbProvider).GetContext(ctx, &event, query, eventID)
+	if err != nil {
+		return models.SantaEvent{}, err
+	}
+	return event, nil
+}
+
+func (s SantaRepositoryImpl) CloseSantaEvent(ctx context.Context, eventID int64) error {
+	query := `UPDATE santa_events SET status = $1 WHERE id = $2;`
+	_, err := querier(ctx, s.dbProvider).ExecContext(ctx, query, models.SantaStatusClosed, eventID)
+	if err != nil {
+		log.Errorf("close santa event err: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (s SantaRepositoryImpl) AddSantaParticipant(ctx context.Context, eventID int64, telegramID int64) error {
+	query := `INSERT INTO santa_participants (event_id, telegram_id, joined_at)
+              VALUES ($1, $2, $3)
+              ON CONFLICT DO NOTHING;`
+	_, err := querier(ctx, s.dbProvider).ExecContext(ctx, query, eventID, telegramID, time.Now())
+	if err != nil {
+		log.Errorf("add santa participant err: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (s SantaRepositoryImpl) RemoveSantaParticipant(ctx context.Context, eventID int64, telegramID int64) error {
+	query := `DELETE FROM santa_participants WHERE event_id = $1 AND telegram_id = $2;`
+	_, err := querier(ctx, s.dbProvider).ExecContext(ctx, query, eventID, telegramID)
+	if err != nil {
+		log.Errorf("remove santa participant err: %v", err)
+		return err
+	}
+	return nil
+}
+
+// GetSantaParticipants возвращает участников события, исключая заблокированных пользователей.
+func (s SantaRepositoryImpl) GetSantaParticipants(ctx context.Context, eventID int64) ([]models.User, error) {
+	query := `
+    SELECT u.id, u.telegram_id, u.username, u.first_name, u.last_name, u.display_name, u.role, u.team
+    FROM santa_participants p
+    JOIN users u ON u.telegram_id = p.telegram_id
+    WHERE p.event_id = $1 AND u.blocked = false
+    ORDER BY p.joined_at`
+	rows, err := querier(ctx, s.dbProvider).QueryContext(ctx, query, eventID)
+	if err != nil {
+		log.Errorf("get santa participants err: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		err := rows.Scan(&user.ID, &user.TelegramID, &user.Username, &user.FirstName, &user.LastName, &user.DisplayName, &user.Role, &user.Team)
+		if err != nil {
+			log.Errorf("scan santa participant err: %v", err)
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+func (s SantaRepositoryImpl) AddSantaExclusion(ctx context.Context, exclusion models.SantaExclusion) error {
+	query := `INSERT INTO santa_exclusions (event_id, first_telegram_id, second_telegram_id)
+              VALUES ($1, $2, $3)
+              ON CONFLICT DO NOTHING;`
+	_, err := querier(ctx, s.dbProvider).ExecContext(ctx, query, exclusion.EventID, exclusion.FirstTelegramID, exclusion.SecondTelegramID)
+	if err != nil {
+		log.Errorf("add santa exclusion err: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (s SantaRepositoryImpl) GetSantaExclusions(ctx context.Context, eventID int64) ([]models.SantaExclusion, error) {
+	query := `SELECT event_id, first_telegram_id, second_telegram_id FROM santa_exclusions WHERE event_id = $1`
+	var exclusions []models.SantaExclusion
+	if err := querier(ctx, s.dbProvider).SelectContext(ctx, &exclusions, query, eventID); err != nil {
+		log.Errorf("get santa exclusions err: %v", err)
+		return nil, err
+	}
+	return exclusions, nil
+}
+
+// SaveSantaDraw атомарно сохраняет результат жеребьёвки и переводит событие в статус drawn.
+// SaveSantaDraw сохраняет пары и переводит событие в статус drawn. Возвращает false, если событие уже
+// не в статусе регистрации: из двух параллельных жеребьёвок сохраняется только первая.
+func (s SantaRepositoryImpl) SaveSantaDraw(ctx context.Context, eventID int64, pairs []models.SantaPair, digest string) (bool, error) {
+	saved := false
+	err := withTx(ctx, s.dbProvider, func(ctx context.Context) error {
+		tx := querier(ctx, s.dbProvider)
+
+		// Статус меняется первым: вторая жеребьёвка ждёт блокировки строки и затем не находит её в статусе регистрации
+		query := `UPDATE santa_events SET status = $1, draw_digest = $2, drawn_at = $3 WHERE id = $4 AND status = $5;`
+		res, err := tx.ExecContext(ctx, query, models.SantaStatusDrawn, digest, time.Now(), eventID, models.SantaStatusRegistration)
+		if err != nil {
+			log.Errorf("update santa event after draw err: %v", err)
+			return err
+		}
+		affected, err := res.RowsAffected()
+		if err != nil {
+			log.Errorf("update santa event after draw rows err: %v", err)
+			return err
+		}
+		if affected == 0 {
+			return nil
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM santa_pairs WHERE event_id = $1;`, eventID); err != nil {
+			log.Errorf("clear santa pairs err: %v", err)
+			return err
+		}
+
+		for _, pair := range pairs {
+			query := `INSERT INTO santa_pairs (event_id, giver_telegram_id, recipient_telegram_id) VALUES ($1, $2, $3);`
+			if _, err := tx.ExecContext(ctx, query, eventID, pair.GiverTelegramID, pair.RecipientTelegramID); err != nil {
+				log.Errorf("save santa pair err: %v", err)
+				return err
+			}
+		}
+
+		saved = true
+		return nil
+	})
+	return saved, err
+}
+
+func (s SantaRepositoryImpl) GetSantaPairs(ctx context.Context, eventID int64) ([]models.SantaPair, error) {
+	query := `SELECT event_id, giver_telegram_id, recipient_telegram_id, notified_at FROM santa_pairs WHERE event_id = $1`
+	var pairs []models.SantaPair
+	if err := querier(ctx, s.dbProvider).SelectContext(ctx, &pairs, query, eventID); err != nil {
+		log.Errorf("get santa pairs err: %v", err)
+		return nil, err
+	}
+	return pairs, nil
+}
+
+func (s SantaRepositoryImpl) MarkSantaPairNotified(ctx context.Context, eventID int64, giverTelegramID int64) error {
+	query := `UPDATE santa_pairs SET notified_at = $1 WHERE event_id = $2 AND giver_telegram_id = $3;`
+	_, err := querier(ctx, s.dbProvider).ExecContext(ctx, query, time.Now(), eventID, giverTelegramID)
+	if err != nil {
+		log.Errorf("mark santa pair notified err: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+// Querier — общие методы *sqlx.DB и *sqlx.Tx, через которые репозитории выполняют запросы.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	GetContext(ctx context.Context, dest any, query string, args ...any) error
+	SelectContext(ctx context.Context, dest any, query string, args ...any) error
+}
+
+type txKey struct{}
+
+// querier возвращает транзакцию из ctx, если метод вызван внутри WithTx, иначе — пул соединений.
+func querier(ctx context.Context, dbProvider DBProvider) Querier {
+	if tx, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return withDialect(tx, tx.DriverName())
+	}
+	db := dbProvider.DB()
+	if db == nil {
+		return db
+	}
+	return withDialect(db, db.DriverName())
+}
+
+type TransactorImpl struct {
+	dbProvider DBProvider
+}
+
+func NewTransactor(dbProvider DBProvider) *TransactorImpl {
+	return &TransactorImpl{
+		dbProvider: dbProvider,
+	}
+}
+
+// WithTx выполняет fn в одной транзакции: методы репозиториев, вызванные с переданным в fn ctx,
+// работают в ней. Ошибка или паника в fn откатывают транзакцию. Вложенный WithTx присоединяется к внешней.
+func (t TransactorImpl) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return withTx(ctx, t.dbProvider, fn)
+}
+
+func withTx(ctx context.Context, dbProvider DBProvider, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txKey{}).(*sqlx.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := dbProvider.DB().BeginTxx(ctx, nil)
+	if err != nil {
+		log.Errorf("begin tx err: %v", err)
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err = fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		log.Errorf("commit tx err: %v", err)
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
+}
@@ -1,9 +1,12 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
 	"gift-bot/pkg/models"
-	"github.com/lib/pq"
 	log "github.com/sirupsen/logrus"
+	"strings"
 	"time"
 )
 
@@ -17,10 +20,10 @@ func NewUserRepository(dbProvider DBProvider) *UserRepositoryImpl {
 	}
 }
 
-func (u UserRepositoryImpl) CreateUser(user models.User) error {
-	query := `INSERT INTO users (telegram_id, username, first_name, last_name, role, birthdate, created_at, updated_at)
-              VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
-	_, err := u.dbProvider.DB().Exec(query, user.TelegramID, user.Username, user.FirstName, user.LastName, user.Role, user.Birthdate, time.Now(), time.Now())
+func (u UserRepositoryImpl) CreateUser(ctx context.Context, user models.User) error {
+	query := `INSERT INTO users (telegram_id, username, first_name, last_name, role, team, birthdate, hide_birthday, status, created_at, updated_at)
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	_, err := querier(ctx, u.dbProvider).ExecContext(ctx, query, user.TelegramID, user.Username, user.FirstName, user.LastName, user.Role, user.Team, user.Birthdate, user.HideBirthday, userStatus(user), time.Now(), time.Now())
 	if err != nil {
 		log.Errorf("create user err: %v", err)
 		return err
@@ -28,9 +31,9 @@ func (u UserRepositoryImpl) CreateUser(user models.User) error {
 	return nil
 }
 
-func (u UserRepositoryImpl) UpdateUser(user models.User) error {
-	query := `UPDATE users SET username=$1, first_name=$2, last_name=$3, role=$4, birthdate=$5, updated_at=$6 WHERE telegram_id=$7`
-	_, err := u.dbProvider.DB().Exec(query, user.Username, user.FirstName, user.LastName, user.Role, user.Birthdate, time.Now(), user.TelegramID)
+func (u UserRepositoryImpl) UpdateUser(ctx context.Context, user models.User) error {
+	query := `UPDATE users SET username=$1, first_name=$2, last_name=$3, role=$4, team=$5, birthdate=$6, updated_at=$7 WHERE telegram_id=$8`
+	_, err := querier(ctx, u.dbProvider).ExecContext(ctx, query, user.Username, user.FirstName, user.LastName, user.Role, user.Team, user.Birthdate, time.Now(), user.TelegramID)
 	if err != nil {
 		log.Errorf("update user err: %v", err)
 		return err
@@ -38,17 +41,76 @@ func (u UserRepositoryImpl) UpdateUser(user models.User) error {
 	return nil
 }
 
-func (u UserRepositoryImpl) GetUsersWithBirthdayInDays() ([]models.User, error) {
+// PatchUser меняет только переданные в patch колонки: остальные поля, в том числе изменённые
+// параллельно, не перезаписываются. Возвращает false, если пользователя нет.
+func (u UserRepositoryImpl) PatchUser(ctx context.Context, telegramID int64, patch models.UserPatch) (bool, error) {
+	var sets []string
+	var args []interface{}
+	set := func(column string, value interface{}) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+	if patch.FirstName != nil {
+		set("first_name", *patch.FirstName)
+	}
+	if patch.LastName != nil {
+		set("last_name", *patch.LastName)
+	}
+	if patch.DisplayName != nil {
+		set("display_name", *patch.DisplayName)
+	}
+	if patch.Team != nil {
+		set("team", *patch.Team)
+	}
+	if patch.Birthdate != nil {
+		set("birthdate", *patch.Birthdate)
+	}
+	if patch.HideBirthday != nil {
+		set("hide_birthday", *patch.HideBirthday)
+	}
+	if patch.NotifyHolidays != nil {
+		set("notify_holidays", *patch.NotifyHolidays)
+	}
+	if patch.NotifyReminders != nil {
+		set("notify_reminders", *patch.NotifyReminders)
+	}
+	set("updated_at", time.Now())
+	args = append(args, telegramID)
+
+	query := `UPDATE users SET ` + strings.Join(sets, ", ") + fmt.Sprintf(` WHERE telegram_id = $%d`, len(args))
+	res, err := querier(ctx, u.dbProvider).ExecContext(ctx, query, args...)
+	if err != nil {
+		log.Errorf("patch user err: %v", err)
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Errorf("patch user rows err: %v", err)
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// UpdateUserProfile сохраняет поля, которые пользователь меняет сам через /profile.
+func (u UserRepositoryImpl) UpdateUserProfile(ctx context.Context, user models.User) error {
+	query := `UPDATE users SET birthdate = $1, hide_birthday = $2, display_name = $3, notify_holidays = $4, notify_reminders = $5, updated_at = $6
+              WHERE telegram_id = $7`
+	_, err := querier(ctx, u.dbProvider).ExecContext(ctx, query, user.Birthdate, user.HideBirthday, user.DisplayName, user.NotifyHolidays, user.NotifyReminders, time.Now(), user.TelegramID)
+	if err != nil {
+		log.Errorf("update user profile err: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (u UserRepositoryImpl) GetAllAdmins(ctx context.Context) ([]models.User, error) {
 	query := `
-    SELECT id, telegram_id, username, first_name, last_name, role, birthdate, created_at, updated_at
+    SELECT id, telegram_id, username, first_name, last_name, role, team, birthdate, hide_birthday, display_name, notify_holidays, notify_reminders, created_at, updated_at
     FROM users
-    WHERE birthdate IS NOT NULL 
-    AND blocked = false
-    AND (EXTRACT(DOY FROM birthdate) - EXTRACT(DOY FROM NOW())) = 2`
-
-	rows, err := u.dbProvider.DB().Query(query)
+    WHERE role IN ('owner', 'admin') AND status = 'active'`
+	rows, err := querier(ctx, u.dbProvider).QueryContext(ctx, query)
 	if err != nil {
-		log.Errorf("get users with birthday in 3 days err: %v", err)
+		log.Errorf("get all admins err: %v", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -56,7 +118,7 @@ func (u UserRepositoryImpl) GetUsersWithBirthdayInDays() ([]models.User, error)
 	var users []models.User
 	for rows.Next() {
 		var user models.User
-		err := rows.Scan(&user.ID, &user.TelegramID, &user.Username, &user.FirstName, &user.LastName, &user.Role, &user.Birthdate, &user.CreatedAt, &user.UpdatedAt)
+		err := rows.Scan(&user.ID, &user.TelegramID, &user.Username, &user.FirstName, &user.LastName, &user.Role, &user.Team, &user.Birthdate, &user.HideBirthday, &user.DisplayName, &user.NotifyHolidays, &user.NotifyReminders, &user.CreatedAt, &user.UpdatedAt)
 		if err != nil {
 			log.Errorf("scan user err: %v", err)
 			return nil, err
@@ -66,14 +128,15 @@ func (u UserRepositoryImpl) GetUsersWithBirthdayInDays() ([]models.User, error)
 	return users, nil
 }
 
-func (u UserRepositoryImpl) GetAllAdmins() ([]models.User, error) {
+// GetUsersByRoles возвращает активных незаблокированных пользователей с одной из ролей.
+func (u UserRepositoryImpl) GetUsersByRoles(ctx context.Context, roles []string) ([]models.User, error) {
 	query := `
-    SELECT id, telegram_id, username, first_name, last_name, role, birthdate, created_at, updated_at
+    SELECT id, telegram_id, username, first_name, last_name, role, team, birthdate, hide_birthday, display_name, notify_holidays, notify_reminders, created_at, updated_at
     FROM users
-    WHERE role = 'admin'`
-	rows, err := u.dbProvider.DB().Query(query)
+    WHERE role IN ` + inList(1, len(roles)) + ` AND blocked = false AND status = 'active'`
+	rows, err := querier(ctx, u.dbProvider).QueryContext(ctx, query, listArgs(roles)...)
 	if err != nil {
-		log.Errorf("get all admins err: %v", err)
+		log.Errorf("get users by roles err: %v", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -81,7 +144,7 @@ func (u UserRepositoryImpl) GetAllAdmins() ([]models.User, error) {
 	var users []models.User
 	for rows.Next() {
 		var user models.User
-		err := rows.Scan(&user.ID, &user.TelegramID, &user.Username, &user.FirstName, &user.LastName, &user.Role, &user.Birthdate, &user.CreatedAt, &user.UpdatedAt)
+		err := rows.Scan(&user.ID, &user.TelegramID, &user.Username, &user.FirstName, &user.LastName, &user.Role, &user.Team, &user.Birthdate, &user.HideBirthday, &user.DisplayName, &user.NotifyHolidays, &user.NotifyReminders, &user.CreatedAt, &user.UpdatedAt)
 		if err != nil {
 			log.Errorf("scan user err: %v", err)
 			return nil, err
@@ -91,25 +154,149 @@ func (u UserRepositoryImpl) GetAllAdmins() ([]models.User, error) {
 	return users, nil
 }
 
-func (u UserRepositoryImpl) GetUser(user models.User) (models.User, error) {
-	query := `SELECT id, telegram_id, username, first_name, last_name, role, created_at, updated_at, blocked FROM users WHERE telegram_id=$1;`
-	row := u.dbProvider.DB().QueryRow(query, user.TelegramID)
+// SetUserRole меняет роль пользователя. Роль владельца не меняется: для него возвращается false.
+func (u UserRepositoryImpl) SetUserRole(ctx context.Context, telegramID int64, role string) (bool, error) {
+	query := `UPDATE users SET role = $1, updated_at = $2 WHERE telegram_id = $3 AND role <> 'owner';`
+	res, err := querier(ctx, u.dbProvider).ExecContext(ctx, query, role, time.Now(), telegramID)
+	if err != nil {
+		log.Errorf("set user role err: %v", err)
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Errorf("set user role rows err: %v", err)
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// ClaimOwner делает пользователя владельцем и активирует его, если владельца ещё нет.
+// Возвращает false, если владелец уже есть или пользователь не зарегистрирован.
+func (u UserRepositoryImpl) ClaimOwner(ctx context.Context, telegramID int64) (bool, error) {
+	query := `UPDATE users SET role = 'owner', status = 'active', blocked = false, updated_at = $1
+              WHERE telegram_id = $2 AND NOT EXISTS (SELECT 1 FROM users WHERE role = 'owner');`
+	res, err := querier(ctx, u.dbProvider).ExecContext(ctx, query, time.Now(), telegramID)
+	if err != nil {
+		log.Errorf("claim owner err: %v", err)
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Errorf("claim owner rows err: %v", err)
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// SetUserBlocked блокирует или разблокирует пользователя. Владельца заблокировать нельзя: для него возвращается false.
+func (u UserRepositoryImpl) SetUserBlocked(ctx context.Context, telegramID int64, blocked bool) (bool, error) {
+	query := `UPDATE users SET blocked = $1, updated_at = $2 WHERE telegram_id = $3 AND (role <> 'owner' OR $1 = false);`
+	res, err := querier(ctx, u.dbProvider).ExecContext(ctx, query, blocked, time.Now(), telegramID)
+	if err != nil {
+		log.Errorf("set user blocked err: %v", err)
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Errorf("set user blocked rows err: %v", err)
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// SearchUsers возвращает страницу пользователей (по порядку регистрации) и общее число под фильтром.
+func (u UserRepositoryImpl) SearchUsers(ctx context.Context, filter models.UserFilter) ([]models.User, int, error) {
+	var conditions []string
+	var args []interface{}
+	add := func(condition string, value interface{}) {
+		args = append(args, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.Query != "" {
+		// LOWER(...) LIKE вместо ILIKE, которого нет в SQLite. LOWER в SQLite меняет регистр только латиницы
+		args = append(args, "%"+strings.ToLower(strings.TrimPrefix(filter.Query, "@"))+"%")
+		n := len(args)
+		conditions = append(conditions, fmt.Sprintf("(LOWER(username) LIKE $%d OR LOWER(first_name) LIKE $%d OR LOWER(last_name) LIKE $%d OR LOWER(display_name) LIKE $%d)", n, n, n, n))
+	}
+	if filter.Role != "" {
+		add("role = $%d", filter.Role)
+	}
+	if filter.Team != "" {
+		add("team = $%d", filter.Team)
+	}
+	if filter.Status != "" {
+		add("status = $%d", filter.Status)
+	}
+	if filter.Blocked != nil {
+		add("blocked = $%d", *filter.Blocked)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int
+	if err := querier(ctx, u.dbProvider).GetContext(ctx, &total, "SELECT COUNT(*) FROM users "+where, args...); err != nil {
+		log.Errorf("count users err: %v", err)
+		return nil, 0, err
+	}
+
+	query := fmt.Sprintf(`
+    SELECT id, telegram_id, username, first_name, last_name, role, team, birthdate, hide_birthday, display_name, notify_holidays, notify_reminders, created_at, updated_at, blocked, status, approved_by
+    FROM users
+    %s
+    ORDER BY id
+    LIMIT $%d OFFSET $%d`, where, len(args)+1, len(args)+2)
+	rows, err := querier(ctx, u.dbProvider).QueryContext(ctx, query, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		log.Errorf("search users err: %v", err)
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		var birthdate sql.NullTime
+		err := rows.Scan(&user.ID, &user.TelegramID, &user.Username, &user.FirstName, &user.LastName, &user.Role, &user.Team, &birthdate, &user.HideBirthday, &user.DisplayName, &user.NotifyHolidays, &user.NotifyReminders, &user.CreatedAt, &user.UpdatedAt, &user.Blocked, &user.Status, &user.ApprovedBy)
+		if err != nil {
+			log.Errorf("scan user err: %v", err)
+			return nil, 0, err
+		}
+		if birthdate.Valid {
+			user.Birthdate = birthdate.Time
+		}
+		users = append(users, user)
+	}
+	return users, total, nil
+}
+
+func (u UserRepositoryImpl) GetUser(ctx context.Context, user models.User) (models.User, error) {
+	query := `SELECT id, telegram_id, username, first_name, last_name, role, team, birthdate, hide_birthday, display_name, notify_holidays, notify_reminders, created_at, updated_at, blocked, status, approved_by FROM users WHERE telegram_id=$1;`
+	row := querier(ctx, u.dbProvider).QueryRowContext(ctx, query, user.TelegramID)
 
 	var foundUser models.User
-	err := row.Scan(&foundUser.ID, &foundUser.TelegramID, &foundUser.Username, &foundUser.FirstName, &foundUser.LastName, &foundUser.Role, &foundUser.CreatedAt, &foundUser.UpdatedAt, &foundUser.Blocked)
+	var birthdate sql.NullTime
+	err := row.Scan(&foundUser.ID, &foundUser.TelegramID, &foundUser.Username, &foundUser.FirstName, &foundUser.LastName, &foundUser.Role, &foundUser.Team, &birthdate, &foundUser.HideBirthday, &foundUser.DisplayName, &foundUser.NotifyHolidays, &foundUser.NotifyReminders, &foundUser.CreatedAt, &foundUser.UpdatedAt, &foundUser.Blocked, &foundUser.Status, &foundUser.ApprovedBy)
 	if err != nil {
 		log.Errorf("get user err: %v", err)
 		return models.User{}, err
 	}
+	// У пользователей, зарегистрированных до появления даты рождения, её может не быть
+	if birthdate.Valid {
+		foundUser.Birthdate = birthdate.Time
+	}
 	return foundUser, nil
 }
 
-func (u UserRepositoryImpl) GetAllUsers() ([]models.User, error) {
+func (u UserRepositoryImpl) GetAllUsers(ctx context.Context) ([]models.User, error) {
 	query := `
-    SELECT id, telegram_id, username, first_name, last_name, role, birthdate, created_at, updated_at, blocked
+    SELECT id, telegram_id, username, first_name, last_name, role, team, birthdate, hide_birthday, display_name, notify_holidays, notify_reminders, created_at, updated_at, blocked
     FROM users
-    WHERE blocked = false`
-	rows, err := u.dbProvider.DB().Query(query)
+    WHERE blocked = false AND status = 'active'`
+	rows, err := querier(ctx, u.dbProvider).QueryContext(ctx, query)
 	if err != nil {
 		log.Errorf("get all users err: %v", err)
 		return nil, err
@@ -119,7 +306,7 @@ func (u UserRepositoryImpl) GetAllUsers() ([]models.User, error) {
 	var users []models.User
 	for rows.Next() {
 		var user models.User
-		err := rows.Scan(&user.ID, &user.TelegramID, &user.Username, &user.FirstName, &user.LastName, &user.Role, &user.Birthdate, &user.CreatedAt, &user.UpdatedAt, &user.Blocked)
+		err := rows.Scan(&user.ID, &user.TelegramID, &user.Username, &user.FirstName, &user.LastName, &user.Role, &user.Team, &user.Birthdate, &user.HideBirthday, &user.DisplayName, &user.NotifyHolidays, &user.NotifyReminders, &user.CreatedAt, &user.UpdatedAt, &user.Blocked)
 		if err != nil {
 			log.Errorf("scan user err: %v", err)
 			return nil, err
@@ -129,12 +316,12 @@ func (u UserRepositoryImpl) GetAllUsers() ([]models.User, error) {
 	return users, nil
 }
 
-func (u UserRepositoryImpl) GetBlockedUsers() ([]models.User, error) {
+func (u UserRepositoryImpl) GetBlockedUsers(ctx context.Context) ([]models.User, error) {
 	query := `
-    SELECT id, telegram_id, username, first_name, last_name, role, birthdate, created_at, updated_at, blocked
+    SELECT id, telegram_id, username, first_name, last_name, role, team, birthdate, hide_birthday, display_name, notify_holidays, notify_reminders, created_at, updated_at, blocked
     FROM users
     WHERE blocked = true`
-	rows, err := u.dbProvider.DB().Query(query)
+	rows, err := querier(ctx, u.dbProvider).QueryContext(ctx, query)
 	if err != nil {
 		log.Errorf("get blocked users err: %v", err)
 		return nil, err
@@ -144,7 +331,7 @@ func (u UserRepositoryImpl) GetBlockedUsers() ([]models.User, error) {
 	var users []models.User
 	for rows.Next() {
 		var user models.User
-		err := rows.Scan(&user.ID, &user.TelegramID, &user.Username, &user.FirstName, &user.LastName, &user.Role, &user.Birthdate, &user.CreatedAt, &user.UpdatedAt, &user.Blocked)
+		err := rows.Scan(&user.ID, &user.TelegramID, &user.Username, &user.FirstName, &user.LastName, &user.Role, &user.Team, &user.Birthdate, &user.HideBirthday, &user.DisplayName, &user.NotifyHolidays, &user.NotifyReminders, &user.CreatedAt, &user.UpdatedAt, &user.Blocked)
 		if err != nil {
 			log.Errorf("scan user err: %v", err)
 			return nil, err
@@ -154,9 +341,10 @@ func (u UserRepositoryImpl) GetBlockedUsers() ([]models.User, error) {
 	return users, nil
 }
 
-func (u UserRepositoryImpl) DeleteUsersByUsernames(usernames []string) error {
-	query := `UPDATE users SET blocked = true WHERE username = ANY($1::text[]);`
-	_, err := u.dbProvider.DB().Exec(query, pq.Array(usernames))
+func (u UserRepositoryImpl) DeleteUsersByUsernames(ctx context.Context, usernames []string) error {
+	// Владельца бота заблокировать нельзя
+	query := `UPDATE users SET blocked = true WHERE username IN ` + inList(1, len(usernames)) + ` AND role <> 'owner';`
+	_, err := querier(ctx, u.dbProvider).ExecContext(ctx, query, listArgs(usernames)...)
 	if err != nil {
 		log.Errorf("block users by usernames err: %v", err)
 		return err
@@ -172,9 +360,9 @@ func (u UserRepositoryImpl) DeleteUsersByUsernames(usernames []string) error {
 	//return nil
 }
 
-func (u UserRepositoryImpl) UnblockUsersByUsernames(usernames []string) error {
-	query := `UPDATE users SET blocked = false WHERE username = ANY($1::text[]);`
-	_, err := u.dbProvider.DB().Exec(query, pq.Array(usernames))
+func (u UserRepositoryImpl) UnblockUsersByUsernames(ctx context.Context, usernames []string) error {
+	query := `UPDATE users SET blocked = false WHERE username IN ` + inList(1, len(usernames)) + `;`
+	_, err := querier(ctx, u.dbProvider).ExecContext(ctx, query, listArgs(usernames)...)
 	if err != nil {
 		log.Errorf("unblock users by usernames err: %v", err)
 		return err
@@ -182,28 +370,116 @@ func (u UserRepositoryImpl) UnblockUsersByUsernames(usernames []string) error {
 	return nil
 }
 
-func (u UserRepositoryImpl) HasBirthdayNotification(adminTelegramID int64, userTelegramID int64, date time.Time) (bool, error) {
-	query := `SELECT EXISTS (
-		SELECT 1 FROM birthday_notifications
-		WHERE admin_telegram_id = $1 AND user_telegram_id = $2 AND notify_date = $3
-	);`
-	var exists bool
-	err := u.dbProvider.DB().QueryRow(query, adminTelegramID, userTelegramID, date).Scan(&exists)
+// SaveBirthdayNotification отмечает уведомление отправленным и возвращает false, если отметка уже есть.
+// Вызывается до отправки внутри WithTx: одновременный второй вызов ждёт фиксации первого и получает false.
+func (u UserRepositoryImpl) SaveBirthdayNotification(ctx context.Context, adminTelegramID int64, userTelegramID int64, date time.Time) (bool, error) {
+	query := `INSERT INTO birthday_notifications (admin_telegram_id, user_telegram_id, notify_date)
+			  VALUES ($1, $2, $3)
+			  ON CONFLICT DO NOTHING;`
+	res, err := querier(ctx, u.dbProvider).ExecContext(ctx, query, adminTelegramID, userTelegramID, date)
 	if err != nil {
-		log.Errorf("check birthday notification err: %v", err)
+		log.Errorf("save birthday notification err: %v", err)
 		return false, err
 	}
-	return exists, nil
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		log.Errorf("save birthday notification err: %v", err)
+		return false, err
+	}
+	return inserted > 0, nil
 }
 
-func (u UserRepositoryImpl) SaveBirthdayNotification(adminTelegramID int64, userTelegramID int64, date time.Time) error {
-	query := `INSERT INTO birthday_notifications (admin_telegram_id, user_telegram_id, notify_date)
-			  VALUES ($1, $2, $3)
-			  ON CONFLICT DO NOTHING;`
-	_, err := u.dbProvider.DB().Exec(query, adminTelegramID, userTelegramID, date)
+func (u UserRepositoryImpl) SetUserTeam(ctx context.Context, telegramID int64, team string) error {
+	query := `UPDATE users SET team = $1, updated_at = $2 WHERE telegram_id = $3;`
+	_, err := querier(ctx, u.dbProvider).ExecContext(ctx, query, team, time.Now(), telegramID)
 	if err != nil {
-		log.Errorf("save birthday notification err: %v", err)
+		log.Errorf("set user team err: %v", err)
 		return err
 	}
 	return nil
 }
+
+func (u UserRepositoryImpl) SetCalendarTokenHash(ctx context.Context, telegramID int64, tokenHash string) error {
+	query := `UPDATE users SET calendar_token_hash = $1, updated_at = $2 WHERE telegram_id = $3;`
+	_, err := querier(ctx, u.dbProvider).ExecContext(ctx, query, tokenHash, time.Now(), telegramID)
+	if err != nil {
+		log.Errorf("set calendar token err: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (u UserRepositoryImpl) HasCalendarToken(ctx context.Context, telegramID int64) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM users WHERE telegram_id = $1 AND calendar_token_hash IS NOT NULL);`
+	var exists bool
+	if err := querier(ctx, u.dbProvider).QueryRowContext(ctx, query, telegramID).Scan(&exists); err != nil {
+		log.Errorf("check calendar token err: %v", err)
+		return false, err
+	}
+	return exists, nil
+}
+
+// GetUserByCalendarTokenHash возвращает незаблокированного пользователя, которому принадлежит токен календаря.
+func (u UserRepositoryImpl) GetUserByCalendarTokenHash(ctx context.Context, tokenHash string) (models.User, error) {
+	query := `SELECT id, telegram_id, username, first_name, last_name, display_name, role, team FROM users
+              WHERE calendar_token_hash = $1 AND blocked = false AND status = 'active';`
+	var user models.User
+	err := querier(ctx, u.dbProvider).QueryRowContext(ctx, query, tokenHash).Scan(&user.ID, &user.TelegramID, &user.Username, &user.FirstName, &user.LastName, &user.DisplayName, &user.Role, &user.Team)
+	if err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// GetPendingUsers возвращает заявки на регистрацию, ожидающие решения администратора.
+func (u UserRepositoryImpl) GetPendingUsers(ctx context.Context) ([]models.User, error) {
+	query := `
+    SELECT id, telegram_id, username, first_name, last_name, role, team, birthdate, hide_birthday, display_name, notify_holidays, notify_reminders, created_at, updated_at, blocked, status
+    FROM users
+    WHERE status = 'pending'
+    ORDER BY created_at`
+	rows, err := querier(ctx, u.dbProvider).QueryContext(ctx, query)
+	if err != nil {
+		log.Errorf("get pending users err: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		err := rows.Scan(&user.ID, &user.TelegramID, &user.Username, &user.FirstName, &user.LastName, &user.Role, &user.Team, &user.Birthdate, &user.HideBirthday, &user.DisplayName, &user.NotifyHolidays, &user.NotifyReminders, &user.CreatedAt, &user.UpdatedAt, &user.Blocked, &user.Status)
+		if err != nil {
+			log.Errorf("scan user err: %v", err)
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// ReviewRegistration переводит заявку из pending в указанный статус и запоминает, кто принял решение.
+// Возвращает false, если заявка уже была рассмотрена другим администратором.
+func (u UserRepositoryImpl) ReviewRegistration(ctx context.Context, telegramID int64, status string, reviewerID int64) (bool, error) {
+	query := `UPDATE users SET status = $1, approved_by = $2, approved_at = $3, updated_at = $3
+              WHERE telegram_id = $4 AND status = 'pending';`
+	res, err := querier(ctx, u.dbProvider).ExecContext(ctx, query, status, reviewerID, time.Now(), telegramID)
+	if err != nil {
+		log.Errorf("review registration err: %v", err)
+		return false, err
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		log.Errorf("review registration rows err: %v", err)
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// userStatus возвращает статус для новой записи: без явного статуса пользователь сразу активен.
+func userStatus(user models.User) string {
+	if user.Status == "" {
+		return models.UserStatusActive
+	}
+	return user.Status
+}
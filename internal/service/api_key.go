@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"gift-bot/internal/repository"
+	"gift-bot/pkg/models"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrAPIKeyInvalid = errors.New("api key: invalid or revoked")
+	ErrUnknownScope  = errors.New("api key: unknown scope")
+)
+
+const (
+	apiKeyPrefix = "gb_"
+	// apiKeyTouchInterval ограничивает запись last_used_at: не чаще раза в минуту на ключ.
+	apiKeyTouchInterval = time.Minute
+)
+
+// APIScopes — все области доступа в порядке вывода в подсказках.
+var APIScopes = []string{models.APIScopeReadUsers, models.APIScopeWriteUsers, models.APIScopeBroadcast, models.APIScopeAdmin}
+
+type APIKeyServiceImpl struct {
+	repo repository.APIKeyRepository
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepository) *APIKeyServiceImpl {
+	return &APIKeyServiceImpl{repo: repo}
+}
+
+// CreateAPIKey выпускает ключ и возвращает его вместе с открытым значением. Открытое значение больше нигде не сохраняется.
+func (a APIKeyServiceImpl) CreateAPIKey(ctx context.Context, name string, scopes []string, createdBy int64) (models.APIKey, string, error) {
+	for _, scope := range scopes {
+		if !isAPIScope(scope) {
+			return models.APIKey{}, "", fmt.Errorf("%w: %s", ErrUnknownScope, scope)
+		}
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return models.APIKey{}, "", err
+	}
+	plain := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	key := models.APIKey{
+		Name:      strings.TrimSpace(name),
+		KeyPrefix: plain[:len(apiKeyPrefix)+6],
+		Scopes:    scopes,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now(),
+	}
+	id, err := a.repo.CreateAPIKey(ctx, key, hashToken(plain))
+	if err != nil {
+		return models.APIKey{}, "", err
+	}
+	key.ID = id
+	return key, plain, nil
+}
+
+// AuthenticateAPIKey находит действующий ключ по открытому значению и отмечает время использования.
+func (a APIKeyServiceImpl) AuthenticateAPIKey(ctx context.Context, plain string) (models.APIKey, error) {
+	if !strings.HasPrefix(plain, apiKeyPrefix) {
+		return models.APIKey{}, ErrAPIKeyInvalid
+	}
+	key, err := a.repo.GetAPIKeyByHash(ctx, hashToken(plain))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.APIKey{}, ErrAPIKeyInvalid
+	}
+	if err != nil {
+		return models.APIKey{}, err
+	}
+
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval {
+		if err := a.repo.TouchAPIKey(ctx, key.ID, now); err != nil {
+			log.Errorf("api key %d last use not saved: %v", key.ID, err)
+		}
+	}
+	return key, nil
+}
+
+func (a APIKeyServiceImpl) GetActiveAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	return a.repo.GetActiveAPIKeys(ctx)
+}
+
+func (a APIKeyServiceImpl) RevokeAPIKey(ctx context.Context, id int64) (bool, error) {
+	return a.repo.RevokeAPIKey(ctx, id)
+}
+
+// APIKeyHasScope проверяет область доступа ключа. Ключ с областью admin проходит любую проверку.
+func APIKeyHasScope(key models.APIKey, scope string) bool {
+	for _, s := range key.Scopes {
+		if s == scope || s == models.APIScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseAPIScopes разбирает список областей через запятую, например «read:users,write:users».
+func ParseAPIScopes(s string) ([]string, error) {
+	var scopes []string
+	seen := map[string]bool{}
+	for _, scope := range strings.Split(s, ",") {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if scope == "" || seen[scope] {
+			continue
+		}
+		if !isAPIScope(scope) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownScope, scope)
+		}
+		seen[scope] = true
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 {
+		return nil, ErrUnknownScope
+	}
+	return scopes, nil
+}
+
+func isAPIScope(scope string) bool {
+	for _, s := range APIScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
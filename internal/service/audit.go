@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"gift-bot/internal/repository"
+	"gift-bot/pkg/models"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type AuditServiceImpl struct {
+	repo repository.AuditRepository
+}
+
+func NewAuditService(repo repository.AuditRepository) *AuditServiceImpl {
+	return &AuditServiceImpl{repo: repo}
+}
+
+// Record пишет событие в журнал. Ошибка записи только логируется: действие администратора уже выполнено.
+// targetTelegramID = 0 означает, что у действия нет конкретного пользователя-объекта.
+func (a AuditServiceImpl) Record(ctx context.Context, actorTelegramID int64, action string, targetTelegramID int64, payload map[string]interface{}) {
+	if err := a.Save(ctx, actorTelegramID, action, targetTelegramID, payload); err != nil {
+		log.Errorf("audit %s by %d not recorded: %v", action, actorTelegramID, err)
+	}
+}
+
+// Save пишет событие в журнал и возвращает ошибку записи. Вызванный внутри WithTx,
+// пишет в ту же транзакцию, что и само действие: без записи в журнале действие откатывается.
+func (a AuditServiceImpl) Save(ctx context.Context, actorTelegramID int64, action string, targetTelegramID int64, payload map[string]interface{}) error {
+	event := models.AuditEvent{ActorTelegramID: actorTelegramID, Action: action}
+	if targetTelegramID != 0 {
+		event.TargetTelegramID = &targetTelegramID
+	}
+	if payload != nil {
+		raw, err := json.Marshal(payload)
+		if err != nil {
+			log.Errorf("marshal audit payload err: %v", err)
+		} else {
+			event.Payload = raw
+		}
+	}
+	return a.repo.SaveAuditEvent(ctx, event)
+}
+
+// GetAuditEvents возвращает страницу журнала. Размер страницы ограничивается maxPageSize.
+func (a AuditServiceImpl) GetAuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, int, error) {
+	filter.Limit, filter.Offset = PageBounds(filter.Limit, filter.Offset)
+	return a.repo.GetAuditEvents(ctx, filter)
+}
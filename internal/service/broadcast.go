@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gift-bot/internal/repository"
+	"gift-bot/pkg/models"
+	"strings"
+	"time"
+)
+
+var (
+	ErrBroadcastNotFound = errors.New("broadcast: not found")
+	ErrBroadcastInvalid  = errors.New("broadcast: invalid")
+)
+
+const (
+	// broadcastClaimTTL — через сколько без подтверждения рассылку в статусе sending забирает другой обработчик.
+	broadcastClaimTTL = 10 * time.Minute
+	// broadcastClaimRefresh — как часто отправляющий обработчик подтверждает, что рассылка ещё идёт.
+	broadcastClaimRefresh = time.Minute
+)
+
+type BroadcastServiceImpl struct {
+	repo     repository.BroadcastRepository
+	userRepo repository.UserRepository
+}
+
+func NewBroadcastService(repo repository.BroadcastRepository, userRepo repository.UserRepository) *BroadcastServiceImpl {
+	return &BroadcastServiceImpl{repo: repo, userRepo: userRepo}
+}
+
+// CreateBroadcast сохраняет рассылку. Без времени отправки она считается запланированной на текущий момент.
+func (b BroadcastServiceImpl) CreateBroadcast(ctx context.Context, broadcast models.Broadcast) (models.Broadcast, error) {
+	broadcast.Text = strings.TrimSpace(broadcast.Text)
+	if broadcast.Text == "" {
+		return models.Broadcast{}, fmt.Errorf("%w: text is empty", ErrBroadcastInvalid)
+	}
+	switch broadcast.Audience {
+	case models.BroadcastAudienceAll:
+	case models.BroadcastAudienceTeams:
+		if len(broadcast.Teams) == 0 {
+			return models.Broadcast{}, fmt.Errorf("%w: teams are empty", ErrBroadcastInvalid)
+		}
+	case models.BroadcastAudienceUsers:
+		if len(broadcast.UserIDs) == 0 {
+			return models.Broadcast{}, fmt.Errorf("%w: user ids are empty", ErrBroadcastInvalid)
+		}
+	default:
+		return models.Broadcast{}, fmt.Errorf("%w: unknown audience", ErrBroadcastInvalid)
+	}
+
+	if broadcast.ScheduledAt.IsZero() {
+		broadcast.ScheduledAt = time.Now()
+	}
+	broadcast.Status = models.BroadcastStatusScheduled
+
+	id, err := b.repo.CreateBroadcast(ctx, broadcast)
+	if err != nil {
+		return models.Broadcast{}, err
+	}
+	return b.repo.GetBroadcast(ctx, id)
+}
+
+// GetBroadcast возвращает рассылку вместе со сводкой доставки.
+func (b BroadcastServiceImpl) GetBroadcast(ctx context.Context, id int64) (models.Broadcast, models.BroadcastStats, error) {
+	broadcast, err := b.repo.GetBroadcast(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Broadcast{}, models.BroadcastStats{}, ErrBroadcastNotFound
+	}
+	if err != nil {
+		return models.Broadcast{}, models.BroadcastStats{}, err
+	}
+	stats, err := b.repo.GetBroadcastStats(ctx, id)
+	if err != nil {
+		return models.Broadcast{}, models.BroadcastStats{}, err
+	}
+	return broadcast, stats, nil
+}
+
+func (b BroadcastServiceImpl) ClaimBroadcast(ctx context.Context, id int64) (models.Broadcast, bool, error) {
+	return b.repo.ClaimBroadcast(ctx, id)
+}
+
+// ClaimDueBroadcasts забирает рассылки, время которых наступило, и брошенные на середине отправки.
+func (b BroadcastServiceImpl) ClaimDueBroadcasts(ctx context.Context, now time.Time) ([]models.Broadcast, error) {
+	return b.repo.ClaimDueBroadcasts(ctx, now, now.Add(-broadcastClaimTTL))
+}
+
+func (b BroadcastServiceImpl) ExtendClaim(ctx context.Context, id int64) error {
+	return b.repo.ExtendBroadcastClaim(ctx, id)
+}
+
+// PrepareRecipients определяет получателей по аудитории среди активных незаблокированных пользователей
+// и фиксирует их в журнале доставки. Возвращает только тех, кому рассылка ещё не отправлялась,
+// поэтому повторно забранная рассылка продолжается, а не начинается заново.
+func (b BroadcastServiceImpl) PrepareRecipients(ctx context.Context, broadcast models.Broadcast) ([]models.User, error) {
+	users, err := b.userRepo.GetAllUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := make(map[int64]bool, len(broadcast.ExcludedIDs))
+	for _, id := range broadcast.ExcludedIDs {
+		excluded[id] = true
+	}
+	teams := make(map[string]bool, len(broadcast.Teams))
+	for _, team := range broadcast.Teams {
+		teams[strings.ToLower(strings.TrimSpace(team))] = true
+	}
+	explicit := make(map[int64]bool, len(broadcast.UserIDs))
+	for _, id := range broadcast.UserIDs {
+		explicit[id] = true
+	}
+
+	var recipients []models.User
+	var ids []int64
+	for _, user := range users {
+		if excluded[user.TelegramID] {
+			continue
+		}
+		switch broadcast.Audience {
+		case models.BroadcastAudienceTeams:
+			if !teams[strings.ToLower(strings.TrimSpace(user.Team))] {
+				continue
+			}
+		case models.BroadcastAudienceUsers:
+			if !explicit[user.TelegramID] {
+				continue
+			}
+		}
+		recipients = append(recipients, user)
+		ids = append(ids, user.TelegramID)
+	}
+
+	if err := b.repo.AddBroadcastRecipients(ctx, broadcast.ID, ids); err != nil {
+		return nil, err
+	}
+	pendingIDs, err := b.repo.GetPendingBroadcastRecipients(ctx, broadcast.ID)
+	if err != nil {
+		return nil, err
+	}
+	pending := make(map[int64]bool, len(pendingIDs))
+	for _, id := range pendingIDs {
+		pending[id] = true
+	}
+	unsent := recipients[:0]
+	for _, user := range recipients {
+		if pending[user.TelegramID] {
+			unsent = append(unsent, user)
+		}
+	}
+	return unsent, nil
+}
+
+// RecordDelivery отмечает результат отправки одному получателю.
+func (b BroadcastServiceImpl) RecordDelivery(ctx context.Context, broadcastID int64, telegramID int64, sendErr error) error {
+	if sendErr != nil {
+		return b.repo.SaveBroadcastDelivery(ctx, broadcastID, telegramID, models.DeliveryStatusFailed, sendErr.Error())
+	}
+	return b.repo.SaveBroadcastDelivery(ctx, broadcastID, telegramID, models.DeliveryStatusSent, "")
+}
+
+func (b BroadcastServiceImpl) FinishBroadcast(ctx context.Context, id int64) error {
+	return b.repo.FinishBroadcast(ctx, id)
+}
+
+// RenderBroadcast возвращает текст для получателя. В шаблоне поддерживаются {name}, {user} и {team}.
+func RenderBroadcast(broadcast models.Broadcast, user models.User) string {
+	if !broadcast.IsTemplate {
+		return broadcast.Text
+	}
+	replacer := strings.NewReplacer(
+		"{name}", FormatUserName(user),
+		"{user}", formatUserMention(user),
+		"{team}", user.Team,
+	)
+	return replacer.Replace(broadcast.Text)
+}
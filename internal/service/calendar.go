@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gift-bot/internal/repository"
+	"gift-bot/pkg/config"
+	"gift-bot/pkg/ical"
+	"gift-bot/pkg/models"
+	"strings"
+	"time"
+)
+
+var ErrCalendarTokenNotFound = errors.New("calendar: token not found")
+
+// calendarFeedDays — горизонт для разовых событий; ежегодные события попадают в ленту всегда.
+const calendarFeedDays = 10 * 366
+
+type CalendarServiceImpl struct {
+	userRepo        repository.UserRepository
+	occasionService OccasionService
+}
+
+func NewCalendarService(userRepo repository.UserRepository, occasionService OccasionService) *CalendarServiceImpl {
+	return &CalendarServiceImpl{userRepo: userRepo, occasionService: occasionService}
+}
+
+// IssueCalendarToken создаёт новый токен ленты календаря. Предыдущий токен перестаёт действовать.
+// В БД хранится только хэш токена.
+func (c CalendarServiceImpl) IssueCalendarToken(ctx context.Context, telegramID int64) (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	if err := c.userRepo.SetCalendarTokenHash(ctx, telegramID, hashToken(token)); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (c CalendarServiceImpl) HasCalendarToken(ctx context.Context, telegramID int64) (bool, error) {
+	return c.userRepo.HasCalendarToken(ctx, telegramID)
+}
+
+// CalendarFeedURL возвращает публичную ссылку на ленту для подписки в календаре.
+func (c CalendarServiceImpl) CalendarFeedURL(token string) string {
+	return fmt.Sprintf("%s/calendar/%s.ics", strings.TrimRight(config.GlobalСonfig.ServerConfig.PublicURL, "/"), token)
+}
+
+// RenderCalendarFeed формирует iCalendar-ленту с днями рождения (кроме скрытых) и событиями всех активных пользователей.
+func (c CalendarServiceImpl) RenderCalendarFeed(ctx context.Context, token string) ([]byte, error) {
+	if _, err := c.userRepo.GetUserByCalendarTokenHash(ctx, hashToken(token)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCalendarTokenNotFound
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	upcoming, err := c.occasionService.GetUpcomingOccasions(ctx, now, calendarFeedDays)
+	if err != nil {
+		return nil, err
+	}
+
+	cal := ical.Calendar{
+		ProdID: "-//gift-bot//Birthdays//RU",
+		Name:   "Дни рождения и события коллег",
+	}
+	for _, u := range upcoming {
+		isBirthday := u.Type.Code == models.OccasionTypeBirthday
+		if isBirthday && u.User.HideBirthday {
+			continue
+		}
+
+		event := ical.Event{
+			Date:    u.NextDate,
+			Yearly:  u.Type.Recurring,
+			LeapDay: u.Occasion.Date.Month() == time.February && u.Occasion.Date.Day() == 29,
+		}
+		if isBirthday {
+			event.UID = fmt.Sprintf("birthday-%d@gift-bot", u.User.TelegramID)
+			event.Summary = "🎂 " + formatUserButtonText(u.User)
+			event.Description = u.Type.Title
+		} else {
+			event.UID = fmt.Sprintf("occasion-%d@gift-bot", u.Occasion.ID)
+			event.Summary = fmt.Sprintf("%s — %s", formatOccasionTitle(u.Type, u.Occasion), formatUserButtonText(u.User))
+		}
+		cal.Events = append(cal.Events, event)
+	}
+
+	return cal.Marshal(now), nil
+}
+
+// hashToken возвращает SHA-256 токена в hex: в БД хранятся только хэши токенов календаря и API-ключей.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
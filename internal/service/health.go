@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"gift-bot/internal/repository"
+	"gift-bot/pkg/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// telegramPollMaxAge — сколько может не быть успешного getUpdates. Long polling держит запрос до 60 секунд,
+// поэтому три минуты без ответа означают, что поллер умер или Telegram недоступен.
+const telegramPollMaxAge = 3 * time.Minute
+
+type HealthServiceImpl struct {
+	repo      repository.HealthRepository
+	startedAt time.Time
+
+	mu          sync.RWMutex
+	leader      bool
+	leaderSince time.Time
+	telegram    models.TelegramHealth
+	jobs        map[string]*jobHealth
+}
+
+type jobHealth struct {
+	interval    time.Duration
+	lastRun     time.Time
+	lastSuccess time.Time
+	lastError   string
+}
+
+func NewHealthService(repo repository.HealthRepository, leaderService LeaderService) *HealthServiceImpl {
+	h := &HealthServiceImpl{
+		repo:      repo,
+		startedAt: time.Now(),
+		jobs:      make(map[string]*jobHealth),
+	}
+	leaderService.OnChange(h.recordLeadership)
+	return h
+}
+
+// recordLeadership запоминает роль экземпляра. Сроки опроса Telegram и задач ведущего отсчитываются
+// не раньше момента избрания: до него экземпляр был резервным и ничего не выполнял.
+func (h *HealthServiceImpl) recordLeadership(leader bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leader = leader
+	h.leaderSince = time.Now()
+}
+
+// RegisterJob добавляет периодическую задачу в отчёт. Задача считается зависшей,
+// если с последнего завершённого запуска (или со старта бота) прошло больше двух интервалов.
+func (h *HealthServiceImpl) RegisterJob(name string, interval time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if job, ok := h.jobs[name]; ok {
+		job.interval = interval
+		return
+	}
+	h.jobs[name] = &jobHealth{interval: interval}
+}
+
+func (h *HealthServiceImpl) RecordJobRun(name string, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	job, ok := h.jobs[name]
+	if !ok {
+		job = &jobHealth{}
+		h.jobs[name] = job
+	}
+	job.lastRun = time.Now()
+	if err != nil {
+		job.lastError = err.Error()
+		return
+	}
+	job.lastSuccess = job.lastRun
+	job.lastError = ""
+}
+
+// RecordTelegramPoll отмечает завершение вызова getUpdates.
+func (h *HealthServiceImpl) RecordTelegramPoll(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err != nil {
+		h.telegram.LastError = err.Error()
+		return
+	}
+	now := time.Now()
+	h.telegram.LastPollAt = &now
+	h.telegram.LastError = ""
+}
+
+func (h *HealthServiceImpl) RecordTelegramUpdate() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := time.Now()
+	h.telegram.LastUpdateAt = &now
+}
+
+// Liveness проверяет сам процесс: жив ли поллер Telegram и не зависли ли периодические задачи.
+// БД сюда не входит — перезапуск бота не чинит упавший Postgres. Резервный экземпляр проверяется только по БД в /readyz.
+func (h *HealthServiceImpl) Liveness(ctx context.Context) models.HealthReport {
+	report := h.report(ctx)
+	if report.Telegram.Status == models.HealthStatusFail {
+		report.Status = models.HealthStatusFail
+	}
+	for _, job := range report.Jobs {
+		if job.Status == models.HealthStatusOverdue {
+			report.Status = models.HealthStatusFail
+		}
+	}
+	return report
+}
+
+// Readiness проверяет, может ли бот обслуживать запросы: отвечает ли БД и работает ли поллер Telegram.
+// Резервный экземпляр обслуживает HTTP API, поэтому для него поллер не проверяется.
+func (h *HealthServiceImpl) Readiness(ctx context.Context) models.HealthReport {
+	report := h.report(ctx)
+	if report.Database.Status != models.HealthStatusOK || report.Telegram.Status == models.HealthStatusFail {
+		report.Status = models.HealthStatusFail
+	}
+	return report
+}
+
+func (h *HealthServiceImpl) report(ctx context.Context) models.HealthReport {
+	report := models.HealthReport{
+		Status:    models.HealthStatusOK,
+		StartedAt: h.startedAt,
+		Database:  models.DatabaseHealth{Status: models.HealthStatusOK},
+		Jobs:      []models.JobHealth{},
+	}
+
+	start := time.Now()
+	if err := h.repo.Ping(ctx); err != nil {
+		report.Database.Status = models.HealthStatusFail
+		report.Database.Error = err.Error()
+	}
+	report.Database.LatencyMS = time.Since(start).Milliseconds()
+
+	now := time.Now()
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	report.Role = models.InstanceRoleStandby
+	if h.leader {
+		report.Role = models.InstanceRoleLeader
+	}
+	report.Telegram = h.telegram
+	report.Telegram.Status = models.HealthStatusOK
+	switch {
+	case !h.leader:
+		report.Telegram.Status = models.HealthStatusStandby
+	case now.Sub(latest(lastOrStart(h.telegram.LastPollAt, h.startedAt), h.leaderSince)) > telegramPollMaxAge:
+		report.Telegram.Status = models.HealthStatusFail
+	}
+
+	for name, job := range h.jobs {
+		item := models.JobHealth{
+			Name:      name,
+			Status:    models.HealthStatusOK,
+			Interval:  job.interval.String(),
+			LastError: job.lastError,
+		}
+		// Зависшей считается задача, которая давно не завершалась: ошибка отдельного запуска
+		// (например, недоставленное сообщение) видна в last_error, но перезапуск бота её не исправит
+		since := h.startedAt
+		if !job.lastRun.IsZero() {
+			lastRun := job.lastRun
+			item.LastRunAt = &lastRun
+			since = lastRun
+		}
+		if !job.lastSuccess.IsZero() {
+			lastSuccess := job.lastSuccess
+			item.LastSuccessAt = &lastSuccess
+		}
+		switch {
+		case !h.leader:
+			item.Status = models.HealthStatusStandby
+		case job.interval > 0 && now.Sub(latest(since, h.leaderSince)) > 2*job.interval:
+			item.Status = models.HealthStatusOverdue
+		}
+		report.Jobs = append(report.Jobs, item)
+	}
+	sort.Slice(report.Jobs, func(i, j int) bool { return report.Jobs[i].Name < report.Jobs[j].Name })
+	return report
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func lastOrStart(last *time.Time, start time.Time) time.Time {
+	if last == nil {
+		return start
+	}
+	return *last
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"gift-bot/internal/repository"
+	"gift-bot/pkg/models"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type HolidayServiceImpl struct {
+	repo repository.HolidayRepository
+}
+
+func NewHolidayService(repo repository.HolidayRepository) *HolidayServiceImpl {
+	return &HolidayServiceImpl{repo: repo}
+}
+
+type holidayFile struct {
+	Holidays []holidayFileEntry `yaml:"holidays"`
+}
+
+type holidayFileEntry struct {
+	Code     string `yaml:"code"`
+	Name     string `yaml:"name"`
+	Date     string `yaml:"date"`
+	Greeting string `yaml:"greeting"`
+}
+
+// LoadHolidaysFromFile загружает праздники из YAML (.yaml, .yml) или CSV (code,name,date,greeting) файла
+// и сохраняет их в БД. Возвращает количество загруженных праздников.
+func (h HolidayServiceImpl) LoadHolidaysFromFile(ctx context.Context, path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var entries []holidayFileEntry
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		entries, err = parseHolidaysYAML(f)
+	case ".csv":
+		entries, err = parseHolidaysCSV(f)
+	default:
+		err = fmt.Errorf("unsupported holidays file format: %s", path)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	holidays := make([]models.Holiday, 0, len(entries))
+	for i, entry := range entries {
+		holiday, err := entry.toHoliday()
+		if err != nil {
+			return 0, fmt.Errorf("holiday #%d: %w", i+1, err)
+		}
+		holidays = append(holidays, holiday)
+	}
+
+	for _, holiday := range holidays {
+		if err := h.repo.UpsertHoliday(ctx, holiday); err != nil {
+			return 0, err
+		}
+	}
+	return len(holidays), nil
+}
+
+func parseHolidaysYAML(r io.Reader) ([]holidayFileEntry, error) {
+	var file holidayFile
+	if err := yaml.NewDecoder(r).Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return file.Holidays, nil
+}
+
+func parseHolidaysCSV(r io.Reader) ([]holidayFileEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []holidayFileEntry
+	for i, record := range records {
+		if i == 0 && strings.EqualFold(strings.TrimSpace(record[0]), "code") {
+			continue
+		}
+		entries = append(entries, holidayFileEntry{Code: record[0], Name: record[1], Date: record[2], Greeting: record[3]})
+	}
+	return entries, nil
+}
+
+func (e holidayFileEntry) toHoliday() (models.Holiday, error) {
+	code := strings.TrimSpace(e.Code)
+	name := strings.TrimSpace(e.Name)
+	greeting := strings.TrimSpace(e.Greeting)
+	if code == "" || name == "" || greeting == "" {
+		return models.Holiday{}, errors.New("code, name and greeting are required")
+	}
+
+	date := strings.TrimSpace(e.Date)
+	holiday := models.Holiday{Code: code, Name: name, Greeting: greeting}
+	if parsed, err := time.Parse("02.01.2006", date); err == nil {
+		holiday.Day, holiday.Month, holiday.Year = parsed.Day(), int(parsed.Month()), parsed.Year()
+		return holiday, nil
+	}
+	// 2000 — високосный год, чтобы 29.02 считалось корректной датой.
+	parsed, err := time.Parse("02.01.2006", date+".2000")
+	if err != nil {
+		return models.Holiday{}, fmt.Errorf("invalid date %q, expected ДД.ММ or ДД.ММ.ГГГГ", date)
+	}
+	holiday.Day, holiday.Month = parsed.Day(), int(parsed.Month())
+	return holiday, nil
+}
+
+func (h HolidayServiceImpl) GetHolidays(ctx context.Context) ([]models.Holiday, error) {
+	return h.repo.GetHolidays(ctx)
+}
+
+func (h HolidayServiceImpl) GetHoliday(ctx context.Context, id int64) (models.Holiday, error) {
+	return h.repo.GetHoliday(ctx, id)
+}
+
+func (h HolidayServiceImpl) SetHolidayGreetingOverride(ctx context.Context, id int64, greeting string) error {
+	return h.repo.SetHolidayGreetingOverride(ctx, id, greeting)
+}
+
+func (h HolidayServiceImpl) HasHolidayDelivery(ctx context.Context, holidayID int64, telegramID int64, date time.Time) (bool, error) {
+	return h.repo.HasHolidayDelivery(ctx, holidayID, telegramID, date)
+}
+
+func (h HolidayServiceImpl) SaveHolidayDelivery(ctx context.Context, holidayID int64, telegramID int64, date time.Time) error {
+	return h.repo.SaveHolidayDelivery(ctx, holidayID, telegramID, date)
+}
+
+// GetUpcomingHolidays возвращает праздники в ближайшие days дней начиная с from (включительно), по порядку дат.
+func (h HolidayServiceImpl) GetUpcomingHolidays(ctx context.Context, from time.Time, days int) ([]models.UpcomingHoliday, error) {
+	holidays, err := h.repo.GetHolidays(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	today := truncateToDay(from)
+	var upcoming []models.UpcomingHoliday
+	for _, holiday := range holidays {
+		next, ok := nextOccurrence(holidayDate(holiday), holiday.Year == 0, today)
+		if !ok {
+			continue
+		}
+		daysLeft := daysBetween(today, next)
+		if daysLeft > days {
+			continue
+		}
+		upcoming = append(upcoming, models.UpcomingHoliday{Holiday: holiday, NextDate: next, DaysLeft: daysLeft})
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		return upcoming[i].NextDate.Before(upcoming[j].NextDate)
+	})
+	return upcoming, nil
+}
+
+func holidayDate(holiday models.Holiday) time.Time {
+	year := holiday.Year
+	if year == 0 {
+		year = 2000
+	}
+	return time.Date(year, time.Month(holiday.Month), holiday.Day, 0, 0, 0, 0, time.UTC)
+}
+
+// RenderHolidayGreeting возвращает текст поздравления (с учётом переопределения) для конкретного пользователя.
+func RenderHolidayGreeting(holiday models.Holiday, user models.User) string {
+	greeting := holiday.Greeting
+	if strings.TrimSpace(holiday.GreetingOverride) != "" {
+		greeting = holiday.GreetingOverride
+	}
+
+	name := strings.TrimSpace(user.DisplayName)
+	if name == "" {
+		name = strings.TrimSpace(user.FirstName)
+	}
+	if name == "" {
+		name = formatUserMention(user)
+	}
+	return strings.ReplaceAll(greeting, "{name}", name)
+}
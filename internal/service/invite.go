@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"gift-bot/internal/repository"
+	"gift-bot/pkg/models"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInviteNotFound  = errors.New("invite: code not found")
+	ErrInviteExpired   = errors.New("invite: code expired or revoked")
+	ErrInviteExhausted = errors.New("invite: code has no uses left")
+	// ErrInviteUnavailable — приглашение стало недействительным между проверкой и регистрацией.
+	ErrInviteUnavailable = repository.ErrInviteUnavailable
+)
+
+type InviteServiceImpl struct {
+	repo repository.InviteRepository
+}
+
+func NewInviteService(repo repository.InviteRepository) *InviteServiceImpl {
+	return &InviteServiceImpl{repo: repo}
+}
+
+// CreateInviteCode выпускает приглашение со сроком действия ttl и лимитом использований.
+func (i InviteServiceImpl) CreateInviteCode(ctx context.Context, createdBy int64, ttl time.Duration, maxUses int, team string) (models.InviteCode, error) {
+	code, err := newInviteCode()
+	if err != nil {
+		return models.InviteCode{}, err
+	}
+
+	invite := models.InviteCode{
+		Code:      code,
+		CreatedBy: createdBy,
+		Team:      strings.TrimSpace(team),
+		MaxUses:   maxUses,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	id, err := i.repo.CreateInviteCode(ctx, invite)
+	if err != nil {
+		return models.InviteCode{}, err
+	}
+	invite.ID = id
+	return invite, nil
+}
+
+// ValidateInviteCode проверяет, что приглашение существует и им ещё можно воспользоваться.
+func (i InviteServiceImpl) ValidateInviteCode(ctx context.Context, code string) (models.InviteCode, error) {
+	invite, err := i.repo.GetInviteCodeByCode(ctx, code)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.InviteCode{}, ErrInviteNotFound
+	}
+	if err != nil {
+		return models.InviteCode{}, err
+	}
+
+	if invite.Revoked || !time.Now().Before(invite.ExpiresAt) {
+		return models.InviteCode{}, ErrInviteExpired
+	}
+	if invite.Uses >= invite.MaxUses {
+		return models.InviteCode{}, ErrInviteExhausted
+	}
+	return invite, nil
+}
+
+func (i InviteServiceImpl) GetActiveInviteCodes(ctx context.Context) ([]models.InviteCode, error) {
+	return i.repo.GetActiveInviteCodes(ctx)
+}
+
+func (i InviteServiceImpl) RevokeInviteCode(ctx context.Context, id int64) error {
+	return i.repo.RevokeInviteCode(ctx, id)
+}
+
+func (i InviteServiceImpl) RegisterUserWithInvite(ctx context.Context, user models.User, inviteID int64) error {
+	return i.repo.RegisterUserWithInvite(ctx, user, inviteID)
+}
+
+// newInviteCode генерирует код из символов, допустимых в параметре deep link /start.
+func newInviteCode() (string, error) {
+	raw := make([]byte, 10)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(raw)), nil
+}
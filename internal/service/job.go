@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"gift-bot/internal/repository"
+	"gift-bot/pkg/config"
+	"gift-bot/pkg/models"
+	"gift-bot/pkg/scheduler"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type JobServiceImpl struct {
+	repo          repository.JobRunRepository
+	healthService HealthService
+	scheduler     *scheduler.Scheduler
+}
+
+// NewJobService создаёт планировщик в часовом поясе сервера. Каждый запуск пишется в job_runs и в /healthz.
+// По расписанию задачи выполняются только на ведущем экземпляре: до избрания планировщик стоит на паузе.
+func NewJobService(repo repository.JobRunRepository, healthService HealthService, leaderService LeaderService) *JobServiceImpl {
+	loc, err := time.LoadLocation(config.GlobalСonfig.ServerConfig.Timezone)
+	if err != nil {
+		log.Errorf("load timezone %s for scheduler err: %v", config.GlobalСonfig.ServerConfig.Timezone, err)
+		loc = time.Local
+	}
+	s := scheduler.New(repo, loc)
+	s.OnFinish(healthService.RecordJobRun)
+	s.Pause()
+	leaderService.OnChange(func(leader bool) {
+		if leader {
+			s.Resume()
+			return
+		}
+		s.Pause()
+	})
+	return &JobServiceImpl{repo: repo, healthService: healthService, scheduler: s}
+}
+
+// AddJob регистрирует задачу с cron-расписанием.
+func (j *JobServiceImpl) AddJob(name, spec string, job scheduler.Job, opts ...scheduler.Option) error {
+	if err := j.scheduler.Add(name, spec, job, opts...); err != nil {
+		return err
+	}
+	j.healthService.RegisterJob(name, j.scheduler.Interval(name))
+	return nil
+}
+
+func (j *JobServiceImpl) StartJobs(ctx context.Context) {
+	j.scheduler.Start(ctx)
+}
+
+func (j *JobServiceImpl) StopJobs() {
+	j.scheduler.Stop()
+}
+
+// ListJobs возвращает задачи с последним запуском из job_runs.
+func (j *JobServiceImpl) ListJobs(ctx context.Context) ([]models.JobInfo, error) {
+	runs, err := j.repo.GetLastJobRuns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	jobs := j.scheduler.Jobs()
+	for i := range jobs {
+		if run, ok := runs[jobs[i].Name]; ok {
+			jobs[i].LastRun = &run
+		}
+	}
+	return jobs, nil
+}
+
+// TriggerJob запускает задачу вне расписания; результат приходит в канал.
+func (j *JobServiceImpl) TriggerJob(name string) (<-chan error, error) {
+	return j.scheduler.Trigger(name)
+}
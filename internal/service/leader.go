@@ -0,0 +1,53 @@
+package service
+
+import (
+	"gift-bot/pkg/metrics"
+	"sync"
+	"sync/atomic"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// LeaderServiceImpl хранит роль экземпляра. Ведущий опрашивает Telegram и выполняет периодические задачи,
+// резервные экземпляры обслуживают только HTTP API. Роль выставляет postgres.Elector.
+type LeaderServiceImpl struct {
+	leader atomic.Bool
+
+	mu        sync.Mutex
+	listeners []func(leader bool)
+}
+
+func NewLeaderService() *LeaderServiceImpl {
+	return &LeaderServiceImpl{}
+}
+
+func (l *LeaderServiceImpl) IsLeader() bool {
+	return l.leader.Load()
+}
+
+// OnChange подписывает fn на смену роли. Подписчики вызываются по порядку из горутины, которая меняет роль.
+func (l *LeaderServiceImpl) OnChange(fn func(leader bool)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.listeners = append(l.listeners, fn)
+}
+
+func (l *LeaderServiceImpl) SetLeader(leader bool) {
+	if l.leader.Swap(leader) == leader {
+		return
+	}
+	if leader {
+		log.Print("This instance is now the leader: polling Telegram and running jobs")
+		metrics.Leader.Set(1)
+	} else {
+		log.Print("This instance is now a standby: serving HTTP API only")
+		metrics.Leader.Set(0)
+	}
+
+	l.mu.Lock()
+	listeners := append([]func(bool){}, l.listeners...)
+	l.mu.Unlock()
+	for _, fn := range listeners {
+		fn(leader)
+	}
+}
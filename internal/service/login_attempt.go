@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"gift-bot/internal/repository"
+	"gift-bot/pkg/models"
+	"time"
+)
+
+const (
+	// maxLoginAttempts — сколько раз подряд можно ошибиться в секретном слове до блокировки.
+	maxLoginAttempts = 3
+	loginLockoutBase = 15 * time.Minute
+	loginLockoutMax  = 7 * 24 * time.Hour
+)
+
+type LoginAttemptServiceImpl struct {
+	repo repository.LoginAttemptRepository
+}
+
+func NewLoginAttemptService(repo repository.LoginAttemptRepository) *LoginAttemptServiceImpl {
+	return &LoginAttemptServiceImpl{repo: repo}
+}
+
+// GetLoginLock возвращает время окончания блокировки, если вход для чата сейчас заблокирован.
+func (l LoginAttemptServiceImpl) GetLoginLock(ctx context.Context, telegramID int64) (time.Time, bool, error) {
+	attempt, err := l.repo.GetLoginAttempt(ctx, telegramID)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if attempt.LockedUntil == nil || !attempt.LockedUntil.After(time.Now()) {
+		return time.Time{}, false, nil
+	}
+	return *attempt.LockedUntil, true, nil
+}
+
+// RegisterFailedLogin учитывает неудачную попытку. Если попытки исчерпаны, вход блокируется,
+// и каждая следующая блокировка вдвое длиннее предыдущей. locked сообщает, что блокировка началась
+// этой попыткой: LockedUntil может остаться от прошлой, уже истёкшей блокировки.
+func (l LoginAttemptServiceImpl) RegisterFailedLogin(ctx context.Context, telegramID int64, username string) (attempt models.LoginAttempt, locked bool, err error) {
+	attempt, err = l.repo.IncrementFailedLogin(ctx, telegramID, username)
+	if err != nil {
+		return models.LoginAttempt{}, false, err
+	}
+	if attempt.FailedAttempts < maxLoginAttempts {
+		return attempt, false, nil
+	}
+
+	until := time.Now().Add(loginLockoutDuration(attempt.Lockouts + 1))
+	if err := l.repo.LockLogin(ctx, telegramID, until); err != nil {
+		return models.LoginAttempt{}, false, err
+	}
+	attempt.FailedAttempts = 0
+	attempt.Lockouts++
+	attempt.LockedUntil = &until
+	return attempt, true, nil
+}
+
+// ResetLoginAttempts вызывается после успешного ввода секретного слова.
+func (l LoginAttemptServiceImpl) ResetLoginAttempts(ctx context.Context, telegramID int64) error {
+	_, err := l.repo.DeleteLoginAttempt(ctx, telegramID)
+	return err
+}
+
+// UnlockLogin снимает блокировку и обнуляет историю попыток. Возвращает false, если снимать нечего.
+func (l LoginAttemptServiceImpl) UnlockLogin(ctx context.Context, telegramID int64) (bool, error) {
+	return l.repo.DeleteLoginAttempt(ctx, telegramID)
+}
+
+func (l LoginAttemptServiceImpl) GetLockedLogins(ctx context.Context) ([]models.LoginAttempt, error) {
+	return l.repo.GetLockedLogins(ctx, time.Now())
+}
+
+// loginLockoutDuration возвращает длительность n-й блокировки: 15 минут, 30 минут, 1 час… но не больше недели.
+func loginLockoutDuration(lockouts int) time.Duration {
+	duration := loginLockoutBase
+	for i := 1; i < lockouts; i++ {
+		duration *= 2
+		if duration >= loginLockoutMax {
+			return loginLockoutMax
+		}
+	}
+	return duration
+}
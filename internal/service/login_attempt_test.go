@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRegisterFailedLogin(t *testing.T) {
+	ctx := context.Background()
+	repos := newTestRepositories(t)
+	logins := NewLoginAttemptService(repos.LoginAttemptRepository)
+	const chatID = 2
+
+	fail := func(wantLocked bool, wantFailed int) {
+		t.Helper()
+		attempt, locked, err := logins.RegisterFailedLogin(ctx, chatID, "bob")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if locked != wantLocked || attempt.FailedAttempts != wantFailed {
+			t.Fatalf("RegisterFailedLogin = locked %v, %d failed; want locked %v, %d failed",
+				locked, attempt.FailedAttempts, wantLocked, wantFailed)
+		}
+	}
+
+	for i := 1; i < maxLoginAttempts; i++ {
+		fail(false, i)
+	}
+	fail(true, 0)
+	if until, locked, err := logins.GetLoginLock(ctx, chatID); err != nil || !locked || !until.After(time.Now()) {
+		t.Fatalf("GetLoginLock = %v, %v, %v; want active lock", until, locked, err)
+	}
+
+	// Блокировка истекла: следующая ошибка — первая попытка новой серии, а не новая блокировка
+	if err := repos.LockLogin(ctx, chatID, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if _, locked, err := logins.GetLoginLock(ctx, chatID); err != nil || locked {
+		t.Fatalf("GetLoginLock after expiry = %v, %v; want no lock", locked, err)
+	}
+	for i := 1; i < maxLoginAttempts; i++ {
+		fail(false, i)
+	}
+
+	// Третья ошибка новой серии снова блокирует, уже на удвоенный срок
+	attempt, locked, err := logins.RegisterFailedLogin(ctx, chatID, "bob")
+	if err != nil || !locked {
+		t.Fatalf("RegisterFailedLogin = locked %v, %v; want new lock", locked, err)
+	}
+	if want := time.Now().Add(loginLockoutDuration(3) - time.Minute); attempt.LockedUntil.Before(want) {
+		t.Errorf("locked until %v, want about %v from now", attempt.LockedUntil, loginLockoutDuration(3))
+	}
+}
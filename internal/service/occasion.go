@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gift-bot/internal/repository"
+	"gift-bot/pkg/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type OccasionServiceImpl struct {
+	repo     repository.OccasionRepository
+	userRepo repository.UserRepository
+	tx       repository.Transactor
+}
+
+func NewOccasionService(repo repository.OccasionRepository, userRepo repository.UserRepository, tx repository.Transactor) *OccasionServiceImpl {
+	return &OccasionServiceImpl{repo: repo, userRepo: userRepo, tx: tx}
+}
+
+func (o OccasionServiceImpl) GetOccasionTypes(ctx context.Context) ([]models.OccasionType, error) {
+	return o.repo.GetOccasionTypes(ctx)
+}
+
+func (o OccasionServiceImpl) UpdateOccasionType(ctx context.Context, occasionType models.OccasionType) error {
+	return o.repo.UpdateOccasionType(ctx, occasionType)
+}
+
+func (o OccasionServiceImpl) CreateOccasion(ctx context.Context, occasion models.Occasion) (int64, error) {
+	return o.repo.CreateOccasion(ctx, occasion)
+}
+
+func (o OccasionServiceImpl) DeleteOccasion(ctx context.Context, id int64) error {
+	return o.repo.DeleteOccasion(ctx, id)
+}
+
+func (o OccasionServiceImpl) GetUserOccasions(ctx context.Context, telegramID int64) ([]models.Occasion, error) {
+	return o.repo.GetUserOccasions(ctx, telegramID)
+}
+
+// DeliverOccasionNotification — то же, что UserServiceImpl.DeliverBirthdayNotification, для остальных событий.
+func (o OccasionServiceImpl) DeliverOccasionNotification(ctx context.Context, adminTelegramID int64, occasionID int64, date time.Time, send func() error) (bool, error) {
+	delivered := false
+	err := o.tx.WithTx(ctx, func(ctx context.Context) error {
+		claimed, err := o.repo.SaveOccasionNotification(ctx, adminTelegramID, occasionID, date)
+		if err != nil || !claimed {
+			return err
+		}
+		if err := send(); err != nil {
+			return err
+		}
+		delivered = true
+		return nil
+	})
+	return delivered, err
+}
+
+// GetUpcomingOccasions возвращает события активных пользователей, которые наступят в ближайшие days дней
+// начиная с from (включительно). Дни рождения берутся из users.birthdate.
+func (o OccasionServiceImpl) GetUpcomingOccasions(ctx context.Context, from time.Time, days int) ([]models.UpcomingOccasion, error) {
+	types, err := o.repo.GetOccasionTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	typesByCode := make(map[string]models.OccasionType, len(types))
+	for _, t := range types {
+		typesByCode[t.Code] = t
+	}
+
+	users, err := o.userRepo.GetAllUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	usersByID := make(map[int64]models.User, len(users))
+	for _, u := range users {
+		usersByID[u.TelegramID] = u
+	}
+
+	occasions, err := o.repo.GetOccasions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		if u.Birthdate.IsZero() {
+			continue
+		}
+		occasions = append(occasions, models.Occasion{
+			UserTelegramID: u.TelegramID,
+			TypeCode:       models.OccasionTypeBirthday,
+			Date:           u.Birthdate,
+		})
+	}
+
+	today := truncateToDay(from)
+	var upcoming []models.UpcomingOccasion
+	for _, occasion := range occasions {
+		user, ok := usersByID[occasion.UserTelegramID]
+		if !ok {
+			continue
+		}
+		occasionType, ok := typesByCode[occasion.TypeCode]
+		if !ok {
+			continue
+		}
+		next, ok := nextOccurrence(occasion.Date, occasionType.Recurring, today)
+		if !ok {
+			continue
+		}
+		daysLeft := daysBetween(today, next)
+		if daysLeft > days {
+			continue
+		}
+		upcoming = append(upcoming, models.UpcomingOccasion{
+			Occasion: occasion,
+			Type:     occasionType,
+			User:     user,
+			NextDate: next,
+			DaysLeft: daysLeft,
+			Years:    next.Year() - occasion.Date.Year(),
+		})
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		if !upcoming[i].NextDate.Equal(upcoming[j].NextDate) {
+			return upcoming[i].NextDate.Before(upcoming[j].NextDate)
+		}
+		return upcoming[i].User.Username < upcoming[j].User.Username
+	})
+	return upcoming, nil
+}
+
+// GetUpcomingBirthdays возвращает дни рождения пользователей, которые не скрыли их, в ближайшие days дней.
+func (o OccasionServiceImpl) GetUpcomingBirthdays(ctx context.Context, from time.Time, days int) ([]models.UpcomingOccasion, error) {
+	upcoming, err := o.GetUpcomingOccasions(ctx, from, days)
+	if err != nil {
+		return nil, err
+	}
+
+	var birthdays []models.UpcomingOccasion
+	for _, u := range upcoming {
+		if u.Type.Code == models.OccasionTypeBirthday && !u.User.HideBirthday {
+			birthdays = append(birthdays, u)
+		}
+	}
+	return birthdays, nil
+}
+
+// GetDueOccasionReminders возвращает события, до которых осталось ровно столько дней, сколько задано в типе события.
+func (o OccasionServiceImpl) GetDueOccasionReminders(ctx context.Context, today time.Time) ([]models.UpcomingOccasion, error) {
+	types, err := o.repo.GetOccasionTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	maxLead := 0
+	for _, t := range types {
+		if t.LeadDays > maxLead {
+			maxLead = t.LeadDays
+		}
+	}
+
+	upcoming, err := o.GetUpcomingOccasions(ctx, today, maxLead)
+	if err != nil {
+		return nil, err
+	}
+
+	var due []models.UpcomingOccasion
+	for _, u := range upcoming {
+		if u.DaysLeft == u.Type.LeadDays {
+			due = append(due, u)
+		}
+	}
+	return due, nil
+}
+
+// RenderOccasionReminder подставляет данные события в шаблон напоминания.
+// Поддерживаются плейсхолдеры {user}, {name}, {title}, {date}, {days}, {years}.
+func RenderOccasionReminder(upcoming models.UpcomingOccasion) string {
+	title := strings.TrimSpace(upcoming.Occasion.Title)
+	if title == "" {
+		title = upcoming.Type.Title
+	}
+
+	replacer := strings.NewReplacer(
+		"{user}", formatUserMention(upcoming.User),
+		"{name}", FormatUserName(upcoming.User),
+		"{title}", title,
+		"{date}", upcoming.NextDate.Format("02.01"),
+		"{days}", strconv.Itoa(upcoming.DaysLeft),
+		"{years}", strconv.Itoa(upcoming.Years),
+	)
+	return replacer.Replace(upcoming.Type.ReminderTemplate)
+}
+
+// formatUserMention возвращает @ник, а если ника нет — имя пользователя.
+func formatUserMention(user models.User) string {
+	if username := strings.TrimSpace(user.Username); username != "" {
+		return "@" + username
+	}
+	if name := FormatUserName(user); name != "" {
+		return name
+	}
+	return fmt.Sprintf("id%d", user.TelegramID)
+}
+
+// FormatUserName возвращает имя, заданное пользователем в профиле, или имя и фамилию из Telegram.
+// Так пользователя видят коллеги в боте, веб-интерфейсе и API.
+func FormatUserName(user models.User) string {
+	if name := strings.TrimSpace(user.DisplayName); name != "" {
+		return name
+	}
+	return strings.TrimSpace(user.FirstName + " " + user.LastName)
+}
+
+func truncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// daysBetween считает разницу в календарных днях, не завися от перехода на летнее время.
+func daysBetween(from, to time.Time) int {
+	a := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	b := time.Date(to.Year(), to.Month(), to.Day(), 0, 0, 0, 0, time.UTC)
+	return int(b.Sub(a).Hours() / 24)
+}
+
+// anniversaryIn возвращает дату события в указанном году. 29 февраля в невисокосный год переносится на 28-е.
+func anniversaryIn(date time.Time, year int, loc *time.Location) time.Time {
+	day := date.Day()
+	if date.Month() == time.February && day == 29 && !isLeapYear(year) {
+		day = 28
+	}
+	return time.Date(year, date.Month(), day, 0, 0, 0, 0, loc)
+}
+
+// nextOccurrence возвращает ближайшую дату события не раньше today. Для разовых событий в прошлом возвращает false.
+func nextOccurrence(date time.Time, recurring bool, today time.Time) (time.Time, bool) {
+	if !recurring {
+		d := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, today.Location())
+		return d, !d.Before(today)
+	}
+
+	next := anniversaryIn(date, today.Year(), today.Location())
+	if next.Before(today) {
+		next = anniversaryIn(date, today.Year()+1, today.Location())
+	}
+	return next, true
+}
+
+func isLeapYear(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
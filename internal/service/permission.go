@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+	"gift-bot/pkg/models"
+)
+
+// ErrOwnerProtected возвращается при попытке снять, заблокировать или удалить владельца бота.
+var ErrOwnerProtected = errors.New("owner role cannot be removed")
+
+type Permission string
+
+const (
+	PermissionBroadcast        Permission = "broadcast"
+	PermissionBlock            Permission = "block"
+	PermissionManageRoles      Permission = "manage_roles"
+	PermissionManageUsers      Permission = "manage_users"
+	PermissionManageInvites    Permission = "manage_invites"
+	PermissionManageEvents     Permission = "manage_events"
+	PermissionViewBirthdays    Permission = "view_birthdays"
+	PermissionReceiveReminders Permission = "receive_reminders"
+	PermissionViewAudit        Permission = "view_audit"
+	PermissionManageAPIKeys    Permission = "manage_api_keys"
+	PermissionManageJobs       Permission = "manage_jobs"
+)
+
+// rolePermissions — единственное место, где описано, что может каждая роль.
+var rolePermissions = map[string][]Permission{
+	models.RoleOwner: {
+		PermissionBroadcast, PermissionBlock, PermissionManageRoles, PermissionManageUsers,
+		PermissionManageInvites, PermissionManageEvents, PermissionViewBirthdays, PermissionReceiveReminders,
+		PermissionViewAudit, PermissionManageAPIKeys, PermissionManageJobs,
+	},
+	models.RoleAdmin: {
+		PermissionBroadcast, PermissionBlock, PermissionManageRoles, PermissionManageUsers,
+		PermissionManageInvites, PermissionManageEvents, PermissionViewBirthdays, PermissionReceiveReminders,
+		PermissionViewAudit, PermissionManageAPIKeys, PermissionManageJobs,
+	},
+	models.RoleOrganiser: {
+		PermissionManageEvents, PermissionViewBirthdays, PermissionReceiveReminders,
+	},
+	models.RoleUser: {},
+}
+
+// assignableRoles — роли, которые можно выдать через бота. Владельца назначить нельзя.
+var assignableRoles = []string{models.RoleAdmin, models.RoleOrganiser, models.RoleUser}
+
+var roleTitles = map[string]string{
+	models.RoleOwner:     "владелец",
+	models.RoleAdmin:     "администратор",
+	models.RoleOrganiser: "организатор",
+	models.RoleUser:      "пользователь",
+}
+
+func HasPermission(user models.User, permission Permission) bool {
+	for _, p := range rolePermissions[user.Role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RolesWithPermission возвращает роли, которым выдано право.
+func RolesWithPermission(permission Permission) []string {
+	var roles []string
+	for role := range rolePermissions {
+		if HasPermission(models.User{Role: role}, permission) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+func IsAssignableRole(role string) bool {
+	for _, r := range assignableRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func IsAdminRole(role string) bool {
+	return role == models.RoleOwner || role == models.RoleAdmin
+}
+
+// AssignableRoles возвращает роли, которые можно выдать, в порядке показа.
+func AssignableRoles() []string {
+	return append([]string(nil), assignableRoles...)
+}
+
+func RoleTitle(role string) string {
+	if title, ok := roleTitles[role]; ok {
+		return title
+	}
+	return role
+}
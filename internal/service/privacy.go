@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"gift-bot/internal/repository"
+	"gift-bot/pkg/models"
+	"time"
+)
+
+type PrivacyServiceImpl struct {
+	repo     repository.PrivacyRepository
+	userRepo repository.UserRepository
+}
+
+func NewPrivacyService(repo repository.PrivacyRepository, userRepo repository.UserRepository) *PrivacyServiceImpl {
+	return &PrivacyServiceImpl{repo: repo, userRepo: userRepo}
+}
+
+// ExportUserData возвращает JSON-документ со всеми данными пользователя.
+func (p PrivacyServiceImpl) ExportUserData(ctx context.Context, telegramID int64) ([]byte, error) {
+	user, err := p.userRepo.GetUser(ctx, models.User{TelegramID: telegramID})
+	if err != nil {
+		return nil, err
+	}
+
+	export, err := p.repo.GetUserDataExport(ctx, telegramID)
+	if err != nil {
+		return nil, err
+	}
+	export.ExportedAt = time.Now()
+	export.Profile = user
+
+	return json.MarshalIndent(export, "", "  ")
+}
+
+// DeleteUserData удаляет пользователя и связанные с ним записи без возможности восстановления.
+// Владельца бота удалить нельзя.
+func (p PrivacyServiceImpl) DeleteUserData(ctx context.Context, telegramID int64) error {
+	user, err := p.userRepo.GetUser(ctx, models.User{TelegramID: telegramID})
+	if err != nil {
+		return err
+	}
+	if user.Role == models.RoleOwner {
+		return ErrOwnerProtected
+	}
+	return p.repo.PurgeUser(ctx, telegramID)
+}
@@ -0,0 +1,370 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gift-bot/internal/repository"
+	"gift-bot/pkg/models"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrSantaNotEnoughParticipants = errors.New("santa: at least 2 participants are required")
+	ErrSantaNoPairing             = errors.New("santa: no pairing satisfies the constraints")
+	ErrSantaWrongStatus           = errors.New("santa: event is not open for this action")
+)
+
+type SantaServiceImpl struct {
+	repo repository.SantaRepository
+}
+
+func NewSantaService(repo repository.SantaRepository) *SantaServiceImpl {
+	return &SantaServiceImpl{repo: repo}
+}
+
+func (s SantaServiceImpl) OpenSantaEvent(ctx context.Context, event models.SantaEvent) (int64, error) {
+	return s.repo.CreateSantaEvent(ctx, event)
+}
+
+func (s SantaServiceImpl) GetActiveSantaEvent(ctx context.Context) (models.SantaEvent, error) {
+	return s.repo.GetActiveSantaEvent(ctx)
+}
+
+func (s SantaServiceImpl) CloseSantaEvent(ctx context.Context, eventID int64) error {
+	return s.repo.CloseSantaEvent(ctx, eventID)
+}
+
+func (s SantaServiceImpl) JoinSantaEvent(ctx context.Context, eventID int64, telegramID int64) error {
+	event, err := s.repo.GetSantaEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	if event.Status != models.SantaStatusRegistration {
+		return ErrSantaWrongStatus
+	}
+	return s.repo.AddSantaParticipant(ctx, eventID, telegramID)
+}
+
+func (s SantaServiceImpl) LeaveSantaEvent(ctx context.Context, eventID int64, telegramID int64) error {
+	event, err := s.repo.GetSantaEvent(ctx, eventID)
+	if err != nil {
+		return err
+	}
+	if event.Status != models.SantaStatusRegistration {
+		return ErrSantaWrongStatus
+	}
+	return s.repo.RemoveSantaParticipant(ctx, eventID, telegramID)
+}
+
+func (s SantaServiceImpl) GetSantaParticipants(ctx context.Context, eventID int64) ([]models.User, error) {
+	return s.repo.GetSantaParticipants(ctx, eventID)
+}
+
+func (s SantaServiceImpl) AddSantaExclusion(ctx context.Context, exclusion models.SantaExclusion) error {
+	return s.repo.AddSantaExclusion(ctx, exclusion)
+}
+
+func (s SantaServiceImpl) GetSantaPairs(ctx context.Context, eventID int64) ([]models.SantaPair, error) {
+	return s.repo.GetSantaPairs(ctx, eventID)
+}
+
+func (s SantaServiceImpl) MarkSantaPairNotified(ctx context.Context, eventID int64, giverTelegramID int64) error {
+	return s.repo.MarkSantaPairNotified(ctx, eventID, giverTelegramID)
+}
+
+// DrawSantaPairs проводит жеребьёвку для события в статусе регистрации и сохраняет пары.
+func (s SantaServiceImpl) DrawSantaPairs(ctx context.Context, eventID int64) ([]models.SantaPair, error) {
+	event, err := s.repo.GetSantaEvent(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	if event.Status != models.SantaStatusRegistration {
+		return nil, ErrSantaWrongStatus
+	}
+
+	participants, err := s.repo.GetSantaParticipants(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	forbidden, err := s.santaConstraints(ctx, event, participants)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(participants))
+	for _, p := range participants {
+		ids = append(ids, p.TelegramID)
+	}
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	assignment, err := drawDerangement(ids, forbidden, rng)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([]models.SantaPair, 0, len(assignment))
+	for giver, recipient := range assignment {
+		pairs = append(pairs, models.SantaPair{EventID: eventID, GiverTelegramID: giver, RecipientTelegramID: recipient})
+	}
+
+	saved, err := s.repo.SaveSantaDraw(ctx, eventID, pairs, santaDrawDigest(eventID, pairs))
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		// Параллельная жеребьёвка успела раньше: её пары уже сохранены
+		return nil, ErrSantaWrongStatus
+	}
+	return pairs, nil
+}
+
+// AuditSantaDraw проверяет сохранённую жеребьёвку и возвращает только агрегаты, не раскрывая пары.
+func (s SantaServiceImpl) AuditSantaDraw(ctx context.Context, eventID int64) (models.SantaAudit, error) {
+	event, err := s.repo.GetSantaEvent(ctx, eventID)
+	if err != nil {
+		return models.SantaAudit{}, err
+	}
+
+	participants, err := s.repo.GetSantaParticipants(ctx, eventID)
+	if err != nil {
+		return models.SantaAudit{}, err
+	}
+
+	pairs, err := s.repo.GetSantaPairs(ctx, eventID)
+	if err != nil {
+		return models.SantaAudit{}, err
+	}
+
+	audit := models.SantaAudit{
+		Event:        event,
+		Participants: len(participants),
+		Pairs:        len(pairs),
+		DigestValid:  event.DrawDigest != "" && event.DrawDigest == santaDrawDigest(eventID, pairs),
+	}
+
+	teams := make(map[int64]string, len(participants))
+	for _, p := range participants {
+		teams[p.TelegramID] = strings.TrimSpace(p.Team)
+	}
+
+	previous, err := s.previousSantaPairs(ctx, event)
+	if err != nil {
+		return models.SantaAudit{}, err
+	}
+
+	excluded, err := s.santaExclusions(ctx, eventID)
+	if err != nil {
+		return models.SantaAudit{}, err
+	}
+
+	givers := make(map[int64]bool, len(pairs))
+	recipients := make(map[int64]bool, len(pairs))
+	for _, pair := range pairs {
+		givers[pair.GiverTelegramID] = true
+		recipients[pair.RecipientTelegramID] = true
+		if pair.NotifiedAt != nil {
+			audit.Notified++
+		}
+		if pair.GiverTelegramID == pair.RecipientTelegramID {
+			audit.SelfPairs++
+		}
+		giverTeam, recipientTeam := teams[pair.GiverTelegramID], teams[pair.RecipientTelegramID]
+		if event.ExcludeSameTeam && giverTeam != "" && strings.EqualFold(giverTeam, recipientTeam) {
+			audit.TeamViolations++
+		}
+		if event.ExcludePreviousPairs && previous[pair.GiverTelegramID] == pair.RecipientTelegramID {
+			audit.PreviousPairViolations++
+		}
+		if excluded[santaPairKey(pair.GiverTelegramID, pair.RecipientTelegramID)] {
+			audit.ExclusionViolations++
+		}
+	}
+
+	for _, p := range participants {
+		if !givers[p.TelegramID] {
+			audit.GiversWithoutPair++
+		}
+		if !recipients[p.TelegramID] {
+			audit.RecipientsWithoutPair++
+		}
+	}
+
+	return audit, nil
+}
+
+func (s SantaServiceImpl) santaConstraints(ctx context.Context, event models.SantaEvent, participants []models.User) (func(giver, recipient int64) bool, error) {
+	teams := make(map[int64]string, len(participants))
+	for _, p := range participants {
+		teams[p.TelegramID] = strings.TrimSpace(p.Team)
+	}
+
+	previous := map[int64]int64{}
+	if event.ExcludePreviousPairs {
+		var err error
+		previous, err = s.previousSantaPairs(ctx, event)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	excluded, err := s.santaExclusions(ctx, event.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(giver, recipient int64) bool {
+		if event.ExcludeSameTeam && teams[giver] != "" && strings.EqualFold(teams[giver], teams[recipient]) {
+			return true
+		}
+		if previous[giver] == recipient {
+			return true
+		}
+		return excluded[santaPairKey(giver, recipient)]
+	}, nil
+}
+
+// previousSantaPairs возвращает пары giver -> recipient прошлой жеребьёвки.
+func (s SantaServiceImpl) previousSantaPairs(ctx context.Context, event models.SantaEvent) (map[int64]int64, error) {
+	previous := map[int64]int64{}
+	prevEvent, err := s.repo.GetPreviousSantaEvent(ctx, event.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return previous, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pairs, err := s.repo.GetSantaPairs(ctx, prevEvent.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range pairs {
+		previous[pair.GiverTelegramID] = pair.RecipientTelegramID
+	}
+	return previous, nil
+}
+
+// santaExclusions возвращает запреты в обе стороны: если A и B исключены, то ни A не дарит B, ни B не дарит A.
+func (s SantaServiceImpl) santaExclusions(ctx context.Context, eventID int64) (map[string]bool, error) {
+	exclusions, err := s.repo.GetSantaExclusions(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+	excluded := make(map[string]bool, len(exclusions)*2)
+	for _, e := range exclusions {
+		excluded[santaPairKey(e.FirstTelegramID, e.SecondTelegramID)] = true
+		excluded[santaPairKey(e.SecondTelegramID, e.FirstTelegramID)] = true
+	}
+	return excluded, nil
+}
+
+func santaPairKey(giver, recipient int64) string {
+	return fmt.Sprintf("%d:%d", giver, recipient)
+}
+
+// santaDrawDigest считает SHA-256 от отсортированного списка пар. Дайджест позволяет проверить,
+// что сохранённые пары не менялись после жеребьёвки, не показывая сами пары.
+func santaDrawDigest(eventID int64, pairs []models.SantaPair) string {
+	lines := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		lines = append(lines, santaPairKey(pair.GiverTelegramID, pair.RecipientTelegramID))
+	}
+	sort.Strings(lines)
+
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d\n%s", eventID, strings.Join(lines, "\n"))))
+	return hex.EncodeToString(sum[:])
+}
+
+// drawLimits ограничивает перебор жеребьёвки: число попыток и число шагов в каждой.
+type drawLimits struct {
+	attempts   int
+	stepBudget int
+}
+
+var defaultDrawLimits = drawLimits{attempts: 20, stepBudget: 200000}
+
+// drawDerangement строит случайную перестановку без неподвижных точек с учётом запретов.
+// Используется перебор с возвратом: сначала обрабатываются самые ограниченные дарители,
+// кандидаты перебираются в случайном порядке; при исчерпании бюджета шагов попытка повторяется.
+func drawDerangement(ids []int64, forbidden func(giver, recipient int64) bool, rng *rand.Rand) (map[int64]int64, error) {
+	assignment, _, err := defaultDrawLimits.derange(ids, forbidden, rng)
+	return assignment, err
+}
+
+// derange — drawDerangement с заданными ограничениями; дополнительно возвращает число сделанных попыток.
+func (l drawLimits) derange(ids []int64, forbidden func(giver, recipient int64) bool, rng *rand.Rand) (map[int64]int64, int, error) {
+	if len(ids) < 2 {
+		return nil, 0, ErrSantaNotEnoughParticipants
+	}
+
+	allowed := make(map[int64][]int64, len(ids))
+	for _, giver := range ids {
+		for _, recipient := range ids {
+			if giver != recipient && !forbidden(giver, recipient) {
+				allowed[giver] = append(allowed[giver], recipient)
+			}
+		}
+		if len(allowed[giver]) == 0 {
+			return nil, 0, ErrSantaNoPairing
+		}
+	}
+
+	attempt := 0
+	for attempt < l.attempts {
+		attempt++
+		givers := append([]int64(nil), ids...)
+		rng.Shuffle(len(givers), func(i, j int) { givers[i], givers[j] = givers[j], givers[i] })
+		sort.SliceStable(givers, func(i, j int) bool {
+			return len(allowed[givers[i]]) < len(allowed[givers[j]])
+		})
+
+		assignment := make(map[int64]int64, len(ids))
+		used := make(map[int64]bool, len(ids))
+		steps := 0
+
+		var solve func(i int) bool
+		solve = func(i int) bool {
+			if i == len(givers) {
+				return true
+			}
+			steps++
+			if steps > l.stepBudget {
+				return false
+			}
+			giver := givers[i]
+			candidates := allowed[giver]
+			for _, idx := range rng.Perm(len(candidates)) {
+				recipient := candidates[idx]
+				if used[recipient] {
+					continue
+				}
+				used[recipient] = true
+				assignment[giver] = recipient
+				if solve(i + 1) {
+					return true
+				}
+				used[recipient] = false
+				delete(assignment, giver)
+			}
+			return false
+		}
+
+		if solve(0) {
+			return assignment, attempt, nil
+		}
+		if steps <= l.stepBudget {
+			// Перебор завершился полностью без решения — повторять бессмысленно.
+			break
+		}
+	}
+
+	return nil, attempt, ErrSantaNoPairing
+}
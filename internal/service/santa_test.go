@@ -0,0 +1,143 @@
+package service
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func noExclusions(giver, recipient int64) bool { return false }
+
+func ids(n int) []int64 {
+	out := make([]int64, n)
+	for i := range out {
+		out[i] = int64(i + 1)
+	}
+	return out
+}
+
+// checkDerangement проверяет, что назначение — перестановка без неподвижных точек и запретных пар.
+func checkDerangement(t *testing.T, participants []int64, forbidden func(giver, recipient int64) bool, assignment map[int64]int64) {
+	t.Helper()
+	if len(assignment) != len(participants) {
+		t.Fatalf("assignment has %d givers, want %d", len(assignment), len(participants))
+	}
+	received := make(map[int64]bool, len(participants))
+	for _, giver := range participants {
+		recipient, ok := assignment[giver]
+		if !ok {
+			t.Fatalf("giver %d has no recipient", giver)
+		}
+		if recipient == giver {
+			t.Fatalf("giver %d gives to themselves", giver)
+		}
+		if forbidden(giver, recipient) {
+			t.Fatalf("forbidden pair %d -> %d", giver, recipient)
+		}
+		if received[recipient] {
+			t.Fatalf("recipient %d is drawn twice", recipient)
+		}
+		received[recipient] = true
+	}
+}
+
+func TestDrawDerangement(t *testing.T) {
+	// Команды: 1-4 в одной, 5-8 в другой, 9-10 в третьей
+	team := func(id int64) int64 {
+		switch {
+		case id <= 4:
+			return 1
+		case id <= 8:
+			return 2
+		default:
+			return 3
+		}
+	}
+	cycle := func(n int) func(giver, recipient int64) bool {
+		return func(giver, recipient int64) bool { return recipient != giver%int64(n)+1 }
+	}
+
+	cases := []struct {
+		name      string
+		ids       []int64
+		forbidden func(giver, recipient int64) bool
+		wantErr   error
+	}{
+		{"no participants", nil, noExclusions, ErrSantaNotEnoughParticipants},
+		{"single participant", ids(1), noExclusions, ErrSantaNotEnoughParticipants},
+		{"two participants swap", ids(2), noExclusions, nil},
+		{"two participants excluded", ids(2), func(giver, recipient int64) bool { return giver == 1 }, ErrSantaNoPairing},
+		{"no gifts inside team", ids(10), func(giver, recipient int64) bool { return team(giver) == team(recipient) }, nil},
+		{"giver without options", ids(4), func(giver, recipient int64) bool { return giver == 3 }, ErrSantaNoPairing},
+		// У каждого есть варианты, но 1 и 2 могут дарить только 3
+		{"unsatisfiable constraints", ids(3), func(giver, recipient int64) bool { return giver != 3 && recipient != 3 }, ErrSantaNoPairing},
+		// Единственное решение — цикл 1 -> 2 -> ... -> 12 -> 1
+		{"fully constrained", ids(12), cycle(12), nil},
+		{"large group", ids(300), func(giver, recipient int64) bool { return giver%10 == recipient%10 }, nil},
+	}
+	for _, tc := range cases {
+		for seed := int64(1); seed <= 20; seed++ {
+			assignment, err := drawDerangement(tc.ids, tc.forbidden, rand.New(rand.NewSource(seed)))
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("%s (seed %d): err = %v, want %v", tc.name, seed, err, tc.wantErr)
+			}
+			if tc.wantErr == nil {
+				checkDerangement(t, tc.ids, tc.forbidden, assignment)
+			}
+		}
+	}
+}
+
+func TestDrawDerangementTwoParticipants(t *testing.T) {
+	assignment, err := drawDerangement(ids(2), noExclusions, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assignment[1] != 2 || assignment[2] != 1 {
+		t.Fatalf("assignment = %v, want 1 -> 2, 2 -> 1", assignment)
+	}
+}
+
+func TestDrawDerangementFullSearchIsNotRetried(t *testing.T) {
+	forbidden := func(giver, recipient int64) bool { return giver != 3 && recipient != 3 }
+	_, attempts, err := defaultDrawLimits.derange(ids(3), forbidden, rand.New(rand.NewSource(1)))
+	if !errors.Is(err, ErrSantaNoPairing) {
+		t.Fatalf("err = %v, want %v", err, ErrSantaNoPairing)
+	}
+	if attempts != 1 {
+		t.Fatalf("attempts = %d, want 1: exhaustive search must not be repeated", attempts)
+	}
+}
+
+func TestDrawDerangementStepBudget(t *testing.T) {
+	// Решение на 5 участников требует минимум 5 шагов
+	limits := drawLimits{attempts: 20, stepBudget: 4}
+	_, attempts, err := limits.derange(ids(5), noExclusions, rand.New(rand.NewSource(1)))
+	if !errors.Is(err, ErrSantaNoPairing) {
+		t.Fatalf("err = %v, want %v", err, ErrSantaNoPairing)
+	}
+	if attempts != limits.attempts {
+		t.Fatalf("attempts = %d, want %d: every attempt exhausts the budget", attempts, limits.attempts)
+	}
+}
+
+func TestDrawDerangementRetriesAfterBudget(t *testing.T) {
+	// Бюджет ровно на проход без возвратов: тупик в последнем дарителе исчерпывает попытку,
+	// и хотя бы на одном зерне жеребьёвка удаётся только со второй или следующей попытки.
+	const n = 6
+	limits := drawLimits{attempts: defaultDrawLimits.attempts, stepBudget: n}
+	retried := false
+	for seed := int64(1); seed <= 100; seed++ {
+		assignment, attempts, err := limits.derange(ids(n), noExclusions, rand.New(rand.NewSource(seed)))
+		if err != nil {
+			t.Fatalf("seed %d: %v after %d attempts", seed, err, attempts)
+		}
+		checkDerangement(t, ids(n), noExclusions, assignment)
+		if attempts > 1 {
+			retried = true
+		}
+	}
+	if !retried {
+		t.Fatal("no seed needed a retry")
+	}
+}
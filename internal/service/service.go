@@ -1,41 +1,220 @@
 package service
 
 import (
+	"context"
 	"gift-bot/internal/repository"
 	"gift-bot/pkg/models"
+	"gift-bot/pkg/scheduler"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"time"
 )
 
 type Services struct {
 	UserService
+	SantaService
+	OccasionService
+	HolidayService
+	CalendarService
+	InviteService
+	PrivacyService
+	LoginAttemptService
+	AuditService
+	APIKeyService
+	BroadcastService
+	HealthService
+	LeaderService
+	JobService
 	TelegramService
 }
 
 func NewServices(repos *repository.Repositories) *Services {
-	userService := NewUserService(repos.UserRepository)
-	telegramService := NewTelegramService(userService)
+	auditService := NewAuditService(repos.AuditRepository)
+	userService := NewUserService(repos.UserRepository, repos.Transactor, auditService)
+	santaService := NewSantaService(repos.SantaRepository)
+	occasionService := NewOccasionService(repos.OccasionRepository, repos.UserRepository, repos.Transactor)
+	holidayService := NewHolidayService(repos.HolidayRepository)
+	calendarService := NewCalendarService(repos.UserRepository, occasionService)
+	inviteService := NewInviteService(repos.InviteRepository)
+	privacyService := NewPrivacyService(repos.PrivacyRepository, repos.UserRepository)
+	loginAttemptService := NewLoginAttemptService(repos.LoginAttemptRepository)
+	apiKeyService := NewAPIKeyService(repos.APIKeyRepository)
+	broadcastService := NewBroadcastService(repos.BroadcastRepository, repos.UserRepository)
+	leaderService := NewLeaderService()
+	healthService := NewHealthService(repos.HealthRepository, leaderService)
+	jobService := NewJobService(repos.JobRunRepository, healthService, leaderService)
+	telegramService := NewTelegramService(userService, santaService, occasionService, holidayService, calendarService,
+		inviteService, privacyService, loginAttemptService, auditService, apiKeyService, broadcastService, healthService,
+		leaderService, jobService)
 	return &Services{
-		UserService:     userService,
-		TelegramService: telegramService,
+		UserService:         userService,
+		SantaService:        santaService,
+		OccasionService:     occasionService,
+		HolidayService:      holidayService,
+		CalendarService:     calendarService,
+		InviteService:       inviteService,
+		PrivacyService:      privacyService,
+		LoginAttemptService: loginAttemptService,
+		AuditService:        auditService,
+		APIKeyService:       apiKeyService,
+		BroadcastService:    broadcastService,
+		HealthService:       healthService,
+		LeaderService:       leaderService,
+		JobService:          jobService,
+		TelegramService:     telegramService,
 	}
 }
 
 type UserService interface {
-	CreateUser(user models.User) error
-	GetUser(user models.User) (models.User, error)
-	GetAllUsers() ([]models.User, error)
-	GetBlockedUsers() ([]models.User, error)
-	DeleteUsersByUsernames(usernames []string) error
-	UnblockUsersByUsernames(usernames []string) error
-	UpdateUser(user models.User) error
-	GetUsersWithBirthdayInDays() ([]models.User, error)
-	GetAllAdmins() ([]models.User, error)
-	HasBirthdayNotification(adminTelegramID int64, userTelegramID int64, date time.Time) (bool, error)
-	SaveBirthdayNotification(adminTelegramID int64, userTelegramID int64, date time.Time) error
+	CreateUser(ctx context.Context, user models.User) error
+	GetUser(ctx context.Context, user models.User) (models.User, error)
+	GetAllUsers(ctx context.Context) ([]models.User, error)
+	GetBlockedUsers(ctx context.Context) ([]models.User, error)
+	BlockUsers(ctx context.Context, actorTelegramID int64, users []models.User) error
+	UnblockUsers(ctx context.Context, actorTelegramID int64, users []models.User) error
+	UpdateUser(ctx context.Context, user models.User) error
+	UpdateUserProfile(ctx context.Context, user models.User) error
+	SetUserTeam(ctx context.Context, telegramID int64, team string) error
+	GetPendingUsers(ctx context.Context) ([]models.User, error)
+	ReviewRegistration(ctx context.Context, telegramID int64, status string, reviewerID int64) (bool, error)
+	GetAllAdmins(ctx context.Context) ([]models.User, error)
+	GetUsersByRoles(ctx context.Context, roles []string) ([]models.User, error)
+	SetUserRole(ctx context.Context, telegramID int64, role string) (bool, error)
+	SearchUsers(ctx context.Context, filter models.UserFilter) ([]models.User, int, error)
+	GetUserByTelegramID(ctx context.Context, telegramID int64) (models.User, error)
+	PatchUser(ctx context.Context, telegramID int64, patch models.UserPatch, actorTelegramID int64, payload map[string]interface{}) (models.User, error)
+	BlockUser(ctx context.Context, telegramID int64, actorTelegramID int64, payload map[string]interface{}) (models.User, error)
+	UnblockUser(ctx context.Context, telegramID int64, actorTelegramID int64, payload map[string]interface{}) (models.User, error)
+	ChangeUserRole(ctx context.Context, telegramID int64, role string, actorTelegramID int64, payload map[string]interface{}) (models.User, error)
+	BootstrapOwner(ctx context.Context, telegramID int64) (bool, error)
+	DeliverBirthdayNotification(ctx context.Context, adminTelegramID int64, userTelegramID int64, date time.Time, send func() error) (bool, error)
 }
+type SantaService interface {
+	OpenSantaEvent(ctx context.Context, event models.SantaEvent) (int64, error)
+	GetActiveSantaEvent(ctx context.Context) (models.SantaEvent, error)
+	CloseSantaEvent(ctx context.Context, eventID int64) error
+	JoinSantaEvent(ctx context.Context, eventID int64, telegramID int64) error
+	LeaveSantaEvent(ctx context.Context, eventID int64, telegramID int64) error
+	GetSantaParticipants(ctx context.Context, eventID int64) ([]models.User, error)
+	AddSantaExclusion(ctx context.Context, exclusion models.SantaExclusion) error
+	DrawSantaPairs(ctx context.Context, eventID int64) ([]models.SantaPair, error)
+	GetSantaPairs(ctx context.Context, eventID int64) ([]models.SantaPair, error)
+	MarkSantaPairNotified(ctx context.Context, eventID int64, giverTelegramID int64) error
+	AuditSantaDraw(ctx context.Context, eventID int64) (models.SantaAudit, error)
+}
+type OccasionService interface {
+	GetOccasionTypes(ctx context.Context) ([]models.OccasionType, error)
+	UpdateOccasionType(ctx context.Context, occasionType models.OccasionType) error
+	CreateOccasion(ctx context.Context, occasion models.Occasion) (int64, error)
+	DeleteOccasion(ctx context.Context, id int64) error
+	GetUserOccasions(ctx context.Context, telegramID int64) ([]models.Occasion, error)
+	GetUpcomingOccasions(ctx context.Context, from time.Time, days int) ([]models.UpcomingOccasion, error)
+	GetUpcomingBirthdays(ctx context.Context, from time.Time, days int) ([]models.UpcomingOccasion, error)
+	GetDueOccasionReminders(ctx context.Context, today time.Time) ([]models.UpcomingOccasion, error)
+	DeliverOccasionNotification(ctx context.Context, adminTelegramID int64, occasionID int64, date time.Time, send func() error) (bool, error)
+}
+type HolidayService interface {
+	LoadHolidaysFromFile(ctx context.Context, path string) (int, error)
+	GetHolidays(ctx context.Context) ([]models.Holiday, error)
+	GetHoliday(ctx context.Context, id int64) (models.Holiday, error)
+	GetUpcomingHolidays(ctx context.Context, from time.Time, days int) ([]models.UpcomingHoliday, error)
+	SetHolidayGreetingOverride(ctx context.Context, id int64, greeting string) error
+	HasHolidayDelivery(ctx context.Context, holidayID int64, telegramID int64, date time.Time) (bool, error)
+	SaveHolidayDelivery(ctx context.Context, holidayID int64, telegramID int64, date time.Time) error
+}
+type CalendarService interface {
+	IssueCalendarToken(ctx context.Context, telegramID int64) (string, error)
+	HasCalendarToken(ctx context.Context, telegramID int64) (bool, error)
+	CalendarFeedURL(token string) string
+	RenderCalendarFeed(ctx context.Context, token string) ([]byte, error)
+}
+type InviteService interface {
+	CreateInviteCode(ctx context.Context, createdBy int64, ttl time.Duration, maxUses int, team string) (models.InviteCode, error)
+	ValidateInviteCode(ctx context.Context, code string) (models.InviteCode, error)
+	GetActiveInviteCodes(ctx context.Context) ([]models.InviteCode, error)
+	RevokeInviteCode(ctx context.Context, id int64) error
+	RegisterUserWithInvite(ctx context.Context, user models.User, inviteID int64) error
+}
+type PrivacyService interface {
+	ExportUserData(ctx context.Context, telegramID int64) ([]byte, error)
+	DeleteUserData(ctx context.Context, telegramID int64) error
+}
+type LoginAttemptService interface {
+	GetLoginLock(ctx context.Context, telegramID int64) (time.Time, bool, error)
+	RegisterFailedLogin(ctx context.Context, telegramID int64, username string) (attempt models.LoginAttempt, locked bool, err error)
+	ResetLoginAttempts(ctx context.Context, telegramID int64) error
+	UnlockLogin(ctx context.Context, telegramID int64) (bool, error)
+	GetLockedLogins(ctx context.Context) ([]models.LoginAttempt, error)
+}
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, name string, scopes []string, createdBy int64) (models.APIKey, string, error)
+	AuthenticateAPIKey(ctx context.Context, plain string) (models.APIKey, error)
+	GetActiveAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id int64) (bool, error)
+}
+
+type BroadcastService interface {
+	CreateBroadcast(ctx context.Context, broadcast models.Broadcast) (models.Broadcast, error)
+	GetBroadcast(ctx context.Context, id int64) (models.Broadcast, models.BroadcastStats, error)
+	ClaimBroadcast(ctx context.Context, id int64) (models.Broadcast, bool, error)
+	ClaimDueBroadcasts(ctx context.Context, now time.Time) ([]models.Broadcast, error)
+	ExtendClaim(ctx context.Context, id int64) error
+	PrepareRecipients(ctx context.Context, broadcast models.Broadcast) ([]models.User, error)
+	RecordDelivery(ctx context.Context, broadcastID int64, telegramID int64, sendErr error) error
+	FinishBroadcast(ctx context.Context, id int64) error
+}
+
+type AuditService interface {
+	Record(ctx context.Context, actorTelegramID int64, action string, targetTelegramID int64, payload map[string]interface{})
+	Save(ctx context.Context, actorTelegramID int64, action string, targetTelegramID int64, payload map[string]interface{}) error
+	GetAuditEvents(ctx context.Context, filter models.AuditFilter) ([]models.AuditEvent, int, error)
+}
+type HealthService interface {
+	RegisterJob(name string, interval time.Duration)
+	RecordJobRun(name string, err error)
+	RecordTelegramPoll(err error)
+	RecordTelegramUpdate()
+	Liveness(ctx context.Context) models.HealthReport
+	Readiness(ctx context.Context) models.HealthReport
+}
+
+type LeaderService interface {
+	IsLeader() bool
+	OnChange(fn func(leader bool))
+	SetLeader(leader bool)
+}
+
+type JobService interface {
+	AddJob(name, spec string, job scheduler.Job, opts ...scheduler.Option) error
+	StartJobs(ctx context.Context)
+	StopJobs()
+	ListJobs(ctx context.Context) ([]models.JobInfo, error)
+	TriggerJob(name string) (<-chan error, error)
+}
+
 type TelegramService interface {
-	Start() *tgbotapi.BotAPI
-	NotifyUpcomingBirthdays()
-	SyncUserProfiles()
+	Start(ctx context.Context) *tgbotapi.BotAPI
+	NotifyUpcomingOccasions(ctx context.Context, at time.Time) error
+	SendHolidayGreetings(ctx context.Context, at time.Time)
+	SyncUserProfiles(ctx context.Context) error
+	SendDueBroadcasts(ctx context.Context)
+	BotUsername() string
+}
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// PageBounds подставляет размер страницы по умолчанию и не даёт запросить больше maxPageSize записей за раз.
+func PageBounds(limit, offset int) (int, int) {
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
 }
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"gift-bot/internal/repository"
+	"gift-bot/pkg/config"
+	"gift-bot/pkg/migrations"
+	"gift-bot/pkg/sqlite"
+)
+
+// newTestRepositories открывает SQLite в памяти со всеми миграциями.
+func newTestRepositories(t *testing.T) *repository.Repositories {
+	t.Helper()
+	ctx := context.Background()
+	db, err := sqlite.Open(ctx, ":memory:")
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	m, err := migrations.New(ctx, db.DB(), config.StorageSQLite)
+	if err != nil {
+		t.Fatalf("prepare migrations: %v", err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return repository.NewRepositories(db)
+}
@@ -1,10 +1,12 @@
 package service
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"gift-bot/pkg/config"
+	"gift-bot/pkg/metrics"
 	"gift-bot/pkg/models"
 	"math/rand"
 	"net"
@@ -12,6 +14,7 @@ import (
 	"net/url"
 	"strings"
 	"time"
+	"unicode"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	log "github.com/sirupsen/logrus"
@@ -19,17 +22,33 @@ import (
 )
 
 type Telegram struct {
-	Bot              *tgbotapi.BotAPI
-	userService      UserService
-	loginAttempts    map[int64]int
-	loginState       map[int64]bool
-	blockedUsers     map[int64]time.Time
-	messageState     map[int64]string             // Состояние: "waiting_message" или "waiting_ignored_users"
-	adminMessageData map[int64]*AdminMessageState // Состояние сообщения администратора
-	rateLimit        map[int64]*rateState
+	Bot                 *tgbotapi.BotAPI
+	userService         UserService
+	santaService        SantaService
+	occasionService     OccasionService
+	holidayService      HolidayService
+	calendarService     CalendarService
+	inviteService       InviteService
+	privacyService      PrivacyService
+	loginAttemptService LoginAttemptService
+	auditService        AuditService
+	apiKeyService       APIKeyService
+	broadcastService    BroadcastService
+	healthService       HealthService
+	leaderService       LeaderService
+	jobService          JobService
+	loginState          map[int64]bool
+	blockedUsers        map[int64]time.Time
+	messageState        map[int64]string             // Состояние: "waiting_message" или "waiting_ignored_users"
+	adminMessageData    map[int64]*AdminMessageState // Состояние сообщения администратора
+	rateLimit           map[int64]*rateState
 }
 
-func NewTelegramService(userService UserService) *Telegram {
+func NewTelegramService(userService UserService, santaService SantaService, occasionService OccasionService,
+	holidayService HolidayService, calendarService CalendarService, inviteService InviteService,
+	privacyService PrivacyService, loginAttemptService LoginAttemptService, auditService AuditService,
+	apiKeyService APIKeyService, broadcastService BroadcastService, healthService HealthService,
+	leaderService LeaderService, jobService JobService) *Telegram {
 	bot, err := newTelegramBot(
 		config.GlobalСonfig.Telegram.Token,
 		config.GlobalСonfig.Telegram.ProxyURL,
@@ -39,14 +58,26 @@ func NewTelegramService(userService UserService) *Telegram {
 	}
 
 	return &Telegram{
-		userService:      userService,
-		Bot:              bot,
-		loginAttempts:    make(map[int64]int),
-		loginState:       make(map[int64]bool),
-		blockedUsers:     make(map[int64]time.Time),
-		messageState:     make(map[int64]string),
-		adminMessageData: make(map[int64]*AdminMessageState),
-		rateLimit:        make(map[int64]*rateState),
+		userService:         userService,
+		santaService:        santaService,
+		occasionService:     occasionService,
+		holidayService:      holidayService,
+		calendarService:     calendarService,
+		inviteService:       inviteService,
+		privacyService:      privacyService,
+		Bot:                 bot,
+		loginAttemptService: loginAttemptService,
+		auditService:        auditService,
+		apiKeyService:       apiKeyService,
+		broadcastService:    broadcastService,
+		healthService:       healthService,
+		leaderService:       leaderService,
+		jobService:          jobService,
+		loginState:          make(map[int64]bool),
+		blockedUsers:        make(map[int64]time.Time),
+		messageState:        make(map[int64]string),
+		adminMessageData:    make(map[int64]*AdminMessageState),
+		rateLimit:           make(map[int64]*rateState),
 	}
 }
 
@@ -97,7 +128,7 @@ func newTelegramHTTPClient(proxyURL string) (*http.Client, error) {
 	}
 
 	if proxyURL == "" {
-		return &http.Client{Transport: transport}, nil
+		return &http.Client{Transport: sendMetricsTransport{next: transport}}, nil
 	}
 
 	parsedURL, err := url.Parse(proxyURL)
@@ -117,7 +148,7 @@ func newTelegramHTTPClient(proxyURL string) (*http.Client, error) {
 
 	transport.DialContext = contextDialer.DialContext
 
-	return &http.Client{Transport: transport}, nil
+	return &http.Client{Transport: sendMetricsTransport{next: transport}}, nil
 }
 
 func sanitizeProxyHost(proxyURL string) string {
@@ -134,10 +165,11 @@ func sanitizeProxyHost(proxyURL string) string {
 }
 
 type AdminMessageState struct {
-	Message     string
-	IgnoredList []string
-	User        models.User
-	CurrentPage int
+	Message      string
+	IgnoredList  []string
+	User         models.User
+	CurrentPage  int
+	InviteCodeID int64 // Приглашение, по которому идёт регистрация
 }
 
 type rateState struct {
@@ -150,66 +182,156 @@ var (
 	secretWord = &config.GlobalСonfig.Telegram.Secret
 )
 
-// Добавьте новое состояние для ожидания даты рождения
+// updateTimeout ограничивает обработку одного обновления Telegram.
+const updateTimeout = 30 * time.Second
+
 const waitingBirthdateState = "waiting_birthdate"
 const waitingBlockUsersState = "waiting_block_users_select"
 const waitingUnblockUsersState = "waiting_unblock_users_select"
 
-func (t *Telegram) Start() *tgbotapi.BotAPI {
+// BotUsername возвращает ник бота без @ — он нужен виджету входа веб-панели.
+func (t *Telegram) BotUsername() string {
+	return t.Bot.Self.UserName
+}
+
+// pollUpdates повторяет GetUpdatesChan из библиотеки, но отмечает каждый вызов getUpdates для /healthz.
+func (t *Telegram) pollUpdates(ctx context.Context, updateConfig tgbotapi.UpdateConfig) <-chan tgbotapi.Update {
+	ch := make(chan tgbotapi.Update, t.Bot.Buffer)
+	go func() {
+		for ctx.Err() == nil {
+			// Опрашивает только ведущий экземпляр: два getUpdates с одним токеном получают 409 Conflict.
+			// Обновления, которые резервный экземпляр не обработал, Telegram не считает подтверждёнными:
+			// их получит новый ведущий, а вернувшись, этот экземпляр начнёт с первого неподтверждённого
+			if !t.leaderService.IsLeader() {
+				updateConfig.Offset = 0
+				time.Sleep(time.Second)
+				continue
+			}
+			updates, err := t.Bot.GetUpdates(updateConfig)
+			t.healthService.RecordTelegramPoll(err)
+			if err != nil {
+				log.Errorf("get updates err: %v", err)
+				time.Sleep(3 * time.Second)
+				continue
+			}
+			// Роль могла смениться, пока шёл long polling
+			if !t.leaderService.IsLeader() {
+				continue
+			}
+			for _, update := range updates {
+				if update.UpdateID >= updateConfig.Offset {
+					updateConfig.Offset = update.UpdateID + 1
+					select {
+					case ch <- update:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+	return ch
+}
+
+// Start обрабатывает обновления до отмены ctx. Запросы к БД при обработке одного обновления
+// ограничены updateTimeout и прерываются при остановке бота.
+func (t *Telegram) Start(ctx context.Context) *tgbotapi.BotAPI {
 	bot := t.Bot
 
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
 
-	updates := bot.GetUpdatesChan(u)
+	updates := t.pollUpdates(ctx, u)
 
-	for update := range updates {
-		if update.Message == nil && update.CallbackQuery == nil {
-			//log.Println("1", update.Message.From.UserName, update.Message.Chat.ID)
-			continue
+	for {
+		var update tgbotapi.Update
+		select {
+		case <-ctx.Done():
+			return bot
+		case update = <-updates:
 		}
-
-		chatID, text := t.extractChatAndText(update)
-
-		allow, warn := t.allowRequest(chatID)
-		if !allow {
-			if warn {
-				msg := tgbotapi.NewMessage(chatID, "Слишком много запросов. Попробуйте позже.")
-				bot.Send(msg)
-			}
+		// Обновления из буфера после потери роли не подтверждены и достанутся новому ведущему
+		if !t.leaderService.IsLeader() {
 			continue
 		}
 
-		if t.handleStartCommand(bot, chatID, text) {
-			continue
-		}
+		t.healthService.RecordTelegramUpdate()
+		started := time.Now()
+		updateType := updateTypeLabel(update)
+		metrics.TelegramUpdates.WithLabelValues(updateType, commandLabel(update)).Inc()
+		updateCtx, cancel := context.WithTimeout(ctx, updateTimeout)
+		t.handleUpdate(updateCtx, bot, update)
+		cancel()
+		metrics.UpdateDuration.WithLabelValues(updateType).Observe(time.Since(started).Seconds())
+	}
+}
 
-		// Проверяем, заблокирован ли пользователь
-		existingUser, err := t.userService.GetUser(models.User{TelegramID: chatID})
-		if err != nil && err != sql.ErrNoRows {
-			log.Errorf("error getting existing user: %v", err)
-			continue
-		}
+func (t *Telegram) handleUpdate(ctx context.Context, bot *tgbotapi.BotAPI, update tgbotapi.Update) {
+	if update.Message == nil && update.CallbackQuery == nil {
+		//log.Println("1", update.Message.From.UserName, update.Message.Chat.ID)
+		return
+	}
+
+	chatID, text := t.extractChatAndText(update)
 
-		if existingUser.Blocked {
-			msg := tgbotapi.NewMessage(chatID, "Вы заблокированы.")
+	allow, warn := t.allowRequest(chatID)
+	if !allow {
+		metrics.RateLimited.Inc()
+		if warn {
+			msg := tgbotapi.NewMessage(chatID, "Слишком много запросов. Попробуйте позже.")
 			bot.Send(msg)
-			continue
 		}
+		return
+	}
 
-		// Обработка состояния администратора для отправки сообщений
-		if t.handleAdminMessageState(update, bot, chatID, text) {
-			continue
-		}
+	if t.handleStartCommand(ctx, update, bot, chatID, text) {
+		return
+	}
 
-		// Проверка состояния логина
-		if t.handleLoginState(update, bot, chatID, text) {
-			continue
-		}
+	// Проверяем, заблокирован ли пользователь
+	existingUser, err := t.userService.GetUser(ctx, models.User{TelegramID: chatID})
+	if err != nil && err != sql.ErrNoRows {
+		log.Errorf("error getting existing user: %v", err)
+		return
+	}
 
-		t.handleCommand(update, bot, chatID, text)
+	if existingUser.Blocked {
+		msg := tgbotapi.NewMessage(chatID, "Вы заблокированы.")
+		bot.Send(msg)
+		return
 	}
-	return bot
+
+	// Заявка ещё на рассмотрении или отклонена — остальные команды недоступны
+	switch existingUser.Status {
+	case models.UserStatusPending:
+		bot.Send(tgbotapi.NewMessage(chatID, "Ваша заявка на регистрацию ещё на рассмотрении у администратора."))
+		return
+	case models.UserStatusRejected:
+		bot.Send(tgbotapi.NewMessage(chatID, "Ваша заявка на регистрацию отклонена."))
+		return
+	}
+
+	// Обработка кнопок, не привязанных к состоянию диалога
+	if t.handleCallback(ctx, update, bot, chatID, text) {
+		return
+	}
+
+	// Ввод новых данных профиля после нажатия кнопки в /profile
+	if t.handleProfileState(ctx, update, bot, chatID, text) {
+		return
+	}
+
+	// Обработка состояния администратора для отправки сообщений
+	if t.handleAdminMessageState(ctx, update, bot, chatID, text) {
+		return
+	}
+
+	// Проверка состояния логина
+	if t.handleLoginState(ctx, update, bot, chatID, text) {
+		return
+	}
+
+	t.handleCommand(ctx, update, bot, chatID, text)
 }
 
 func (t *Telegram) allowRequest(chatID int64) (bool, bool) {
@@ -259,15 +381,22 @@ func (t *Telegram) extractChatAndText(update tgbotapi.Update) (int64, string) {
 	return chatID, text
 }
 
-func (t *Telegram) handleStartCommand(bot *tgbotapi.BotAPI, chatID int64, text string) bool {
-	if text != "/start" {
+func (t *Telegram) handleStartCommand(ctx context.Context, update tgbotapi.Update, bot *tgbotapi.BotAPI, chatID int64, text string) bool {
+	command, args := parseCommand(text)
+	if command != "/start" || update.Message == nil {
 		return false
 	}
 
+	// Deep link t.me/<bot>?start=<code> приходит как "/start <code>"
+	if len(args) > 0 {
+		t.handleInviteStart(ctx, update, bot, chatID, args[0])
+		return true
+	}
+
 	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Привет! Это простой телеграм бот для поздравляшек "+
 		"своих близких коллег. Тут есть пару команд, чтобы ты мог начать получать сообщения! Если что-то будет "+
 		"не так, то ты всегда можешь написать своему администратору для устранения проблем.\n\n"+
-		"Для начала введите команду /login"))
+		"%s", registrationHint()))
 	msg.ParseMode = "Markdown"
 	send, err := bot.Send(msg)
 	if err != nil {
@@ -276,7 +405,20 @@ func (t *Telegram) handleStartCommand(bot *tgbotapi.BotAPI, chatID int64, text s
 	return true
 }
 
-func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi.BotAPI, chatID int64, text string) bool {
+// registrationHint подсказывает, как зарегистрироваться, в зависимости от того, включено ли секретное слово.
+func registrationHint() string {
+	if legacySecretEnabled() {
+		return "Для начала введите команду /login или откройте ссылку-приглашение от администратора"
+	}
+	return "Для регистрации откройте ссылку-приглашение, которую выдаст администратор"
+}
+
+// legacySecretEnabled сообщает, разрешена ли регистрация по общему секретному слову (TELEGRAM_SECRET).
+func legacySecretEnabled() bool {
+	return strings.TrimSpace(*secretWord) != ""
+}
+
+func (t *Telegram) handleAdminMessageState(ctx context.Context, update tgbotapi.Update, bot *tgbotapi.BotAPI, chatID int64, text string) bool {
 	if _, exists := t.messageState[chatID]; !exists {
 		return false
 	}
@@ -298,7 +440,7 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 		}
 		t.messageState[chatID] = "waiting_ignored_users"
 
-		users, err := t.userService.GetAllUsers()
+		users, err := t.userService.GetAllUsers(ctx)
 		if err != nil {
 			log.Println(err)
 			msg := tgbotapi.NewMessage(chatID, "Ошибка при получении списка пользователей.")
@@ -327,7 +469,7 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 			if text == "send_message" {
 				t.clearInlineKeyboard(bot, update)
 				t.messageState[chatID] = ""
-				t.sendMessageToUsers(chatID)
+				t.sendMessageToUsers(ctx, chatID)
 				return true
 			}
 			if text == "cancel_action" {
@@ -352,7 +494,7 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 					data.CurrentPage--
 				}
 
-				users, err := t.userService.GetAllUsers()
+				users, err := t.userService.GetAllUsers(ctx)
 				if err != nil {
 					log.Println(err)
 					msg := tgbotapi.NewMessage(chatID, "Ошибка при обновлении списка пользователей.")
@@ -371,7 +513,7 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 			data.IgnoredList = append(data.IgnoredList, username)
 
 			// Обновляем клавиатуру, чтобы удалить выбранного пользователя
-			users, err := t.userService.GetAllUsers()
+			users, err := t.userService.GetAllUsers(ctx)
 			if err != nil {
 				log.Println(err)
 				msg := tgbotapi.NewMessage(chatID, "Ошибка при обновлении списка пользователей.")
@@ -388,7 +530,7 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 		// Если администратор завершил выбор
 		if text == "нет" {
 			t.messageState[chatID] = ""
-			t.sendMessageToUsers(chatID)
+			t.sendMessageToUsers(ctx, chatID)
 			return true
 		}
 
@@ -403,7 +545,7 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 
 		if update.CallbackQuery != nil {
 			if text == "noop" || text == "page:next" || text == "page:prev" {
-				return t.handleAdminSelectionPaging(update, bot, chatID, "waiting_promote_admin")
+				return t.handleAdminSelectionPaging(ctx, update, bot, chatID, "waiting_promote_admin")
 			}
 			if text == "cancel_action" {
 				t.clearInlineKeyboard(bot, update)
@@ -414,7 +556,7 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 				return true
 			}
 
-			users, err := t.userService.GetAllUsers()
+			users, err := t.userService.GetAllUsers(ctx)
 			if err != nil {
 				log.Println(err)
 				msg := tgbotapi.NewMessage(chatID, "Ошибка при получении списка пользователей.")
@@ -436,14 +578,13 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 				return true
 			}
 
-			if target.Role == "admin" {
+			if IsAdminRole(target.Role) {
 				msg := tgbotapi.NewMessage(chatID, "Пользователь уже админ.")
 				bot.Send(msg)
 				return true
 			}
 
-			target.Role = "admin"
-			if err := t.userService.UpdateUser(*target); err != nil {
+			if _, err := t.userService.SetUserRole(ctx, target.TelegramID, models.RoleAdmin); err != nil {
 				log.Println(err)
 				msg := tgbotapi.NewMessage(chatID, "Ошибка при назначении администратора.")
 				bot.Send(msg)
@@ -451,6 +592,7 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 			}
 
 			t.clearInlineKeyboard(bot, update)
+			t.auditService.Record(ctx, chatID, models.AuditActionAdminAdd, target.TelegramID, nil)
 
 			t.messageState[chatID] = ""
 			delete(t.adminMessageData, chatID)
@@ -470,7 +612,7 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 
 		if update.CallbackQuery != nil {
 			if text == "noop" || text == "page:next" || text == "page:prev" {
-				return t.handleAdminSelectionPaging(update, bot, chatID, "waiting_demote_admin")
+				return t.handleAdminSelectionPaging(ctx, update, bot, chatID, "waiting_demote_admin")
 			}
 			if text == "cancel_action" {
 				t.clearInlineKeyboard(bot, update)
@@ -481,7 +623,7 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 				return true
 			}
 
-			users, err := t.userService.GetAllUsers()
+			users, err := t.userService.GetAllUsers(ctx)
 			if err != nil {
 				log.Println(err)
 				msg := tgbotapi.NewMessage(chatID, "Ошибка при получении списка пользователей.")
@@ -491,7 +633,7 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 
 			var target *models.User
 			for i := range users {
-				if users[i].Username == text && users[i].Role == "admin" {
+				if users[i].Username == text && users[i].Role == models.RoleAdmin {
 					target = &users[i]
 					break
 				}
@@ -503,8 +645,8 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 				return true
 			}
 
-			target.Role = "user"
-			if err := t.userService.UpdateUser(*target); err != nil {
+			// Владельца нет среди кандидатов, а SetUserRole дополнительно не меняет его роль
+			if _, err := t.userService.SetUserRole(ctx, target.TelegramID, models.RoleUser); err != nil {
 				log.Println(err)
 				msg := tgbotapi.NewMessage(chatID, "Ошибка при снятии прав администратора.")
 				bot.Send(msg)
@@ -512,6 +654,7 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 			}
 
 			t.clearInlineKeyboard(bot, update)
+			t.auditService.Record(ctx, chatID, models.AuditActionAdminRemove, target.TelegramID, nil)
 
 			t.messageState[chatID] = ""
 			delete(t.adminMessageData, chatID)
@@ -522,16 +665,29 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 
 	case waitingBirthdateState:
 		log.Printf("Received birthdate from user: %s", text)
-		birthdate, err := time.Parse("02.01.2006", text)
+		birthdate, err := parseDate(text)
 		if err != nil {
 			msg := tgbotapi.NewMessage(chatID, "Неверный формат даты. Пожалуйста, введите дату в формате ДД.ММ.ГГГГ:")
 			bot.Send(msg)
 			return true
 		}
 
-		user := t.adminMessageData[chatID].User
+		data := t.adminMessageData[chatID]
+		user := data.User
 		user.Birthdate = birthdate
-		err = t.userService.CreateUser(user)
+		user.Status = newUserStatus()
+		if data.InviteCodeID != 0 {
+			err = t.inviteService.RegisterUserWithInvite(ctx, user, data.InviteCodeID)
+		} else {
+			err = t.userService.CreateUser(ctx, user)
+		}
+		if errors.Is(err, ErrInviteUnavailable) {
+			delete(t.adminMessageData, chatID)
+			t.messageState[chatID] = ""
+			msg := tgbotapi.NewMessage(chatID, "Приглашение больше не действует. Попросите у администратора новую ссылку.")
+			bot.Send(msg)
+			return true
+		}
 		if err != nil {
 			msg := tgbotapi.NewMessage(chatID, "Ошибка при создании пользователя.")
 			bot.Send(msg)
@@ -541,22 +697,26 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 		delete(t.adminMessageData, chatID)
 		t.messageState[chatID] = ""
 
-		msg := tgbotapi.NewMessage(chatID, "Вы успешно зарегистрировались.")
-		bot.Send(msg)
-		// Отправляем админам уведомление о регистрации в боте пользователя
-
-		admins, err := t.userService.GetAllAdmins()
-		if err != nil {
-			log.Println("Error getting all admins:", err)
-			return true
+		if isConfiguredOwner(user.TelegramID) {
+			promoted, err := t.userService.BootstrapOwner(ctx, user.TelegramID)
+			if err != nil {
+				log.Errorf("bootstrap owner %d err: %v", user.TelegramID, err)
+			}
+			if promoted {
+				bot.Send(tgbotapi.NewMessage(chatID, "Вы зарегистрировались как владелец бота."))
+				return true
+			}
 		}
 
-		for _, admin := range admins {
-			message := fmt.Sprintf("Пользователь @%s зарегистрировался в боте", user.Username)
-			msg := tgbotapi.NewMessage(admin.TelegramID, message)
-			//fmt.Printf("Notifying admin %s about upcoming birthday of %s", admin.Username, birthdayUser.Username)
-			t.Bot.Send(msg)
+		if user.Status == models.UserStatusPending {
+			msg := tgbotapi.NewMessage(chatID, "Заявка на регистрацию отправлена администраторам. Мы сообщим, когда её рассмотрят.")
+			bot.Send(msg)
+		} else {
+			msg := tgbotapi.NewMessage(chatID, "Вы успешно зарегистрировались.")
+			bot.Send(msg)
 		}
+		// Отправляем админам уведомление о регистрации в боте пользователя
+		t.notifyAdminsAboutRegistration(ctx, user)
 
 		return true
 	case waitingBlockUsersState:
@@ -570,7 +730,7 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 
 		if update.CallbackQuery != nil {
 			if text == "noop" || text == "page:next" || text == "page:prev" {
-				return t.handleAdminSelectionPaging(update, bot, chatID, waitingBlockUsersState)
+				return t.handleAdminSelectionPaging(ctx, update, bot, chatID, waitingBlockUsersState)
 			}
 			if text == "cancel_action" {
 				t.clearInlineKeyboard(bot, update)
@@ -588,7 +748,7 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 					return true
 				}
 
-				users, err := t.userService.GetAllUsers()
+				users, err := t.userService.GetAllUsers(ctx)
 				if err != nil {
 					log.Println(err)
 					msg := tgbotapi.NewMessage(chatID, "Ошибка при получении списка пользователей.")
@@ -601,7 +761,18 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 					byUsername[u.Username] = u
 				}
 
-				err = t.userService.DeleteUsersByUsernames(data.IgnoredList)
+				// Владелец не попадает в список, но выбор мог прийти из старой клавиатуры
+				var usernames []string
+				var selected []models.User
+				for _, username := range data.IgnoredList {
+					if byUsername[username].Role != models.RoleOwner {
+						usernames = append(usernames, username)
+						selected = append(selected, selectedUser(byUsername, username))
+					}
+				}
+				data.IgnoredList = usernames
+
+				err = t.userService.BlockUsers(ctx, chatID, selected)
 				if err != nil {
 					log.Println(err)
 					msg := tgbotapi.NewMessage(chatID, "Ошибка при блокировке пользователей.")
@@ -634,7 +805,7 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 			}
 			data.IgnoredList = append(data.IgnoredList, username)
 
-			users, err := t.userService.GetAllUsers()
+			users, err := t.userService.GetAllUsers(ctx)
 			if err != nil {
 				log.Println(err)
 				msg := tgbotapi.NewMessage(chatID, "Ошибка при обновлении списка пользователей.")
@@ -642,7 +813,7 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 				return true
 			}
 
-			keyboard := t.createUserSelectionKeyboard(users, data, true, "Заблокировать", "block_users", true)
+			keyboard := t.createUserSelectionKeyboard(withoutOwner(users), data, true, "Заблокировать", "block_users", true)
 			editMsg := tgbotapi.NewEditMessageReplyMarkup(chatID, update.CallbackQuery.Message.MessageID, keyboard)
 			bot.Send(editMsg)
 			return true
@@ -659,7 +830,7 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 
 		if update.CallbackQuery != nil {
 			if text == "noop" || text == "page:next" || text == "page:prev" {
-				return t.handleAdminSelectionPaging(update, bot, chatID, waitingUnblockUsersState)
+				return t.handleAdminSelectionPaging(ctx, update, bot, chatID, waitingUnblockUsersState)
 			}
 			if text == "cancel_action" {
 				t.clearInlineKeyboard(bot, update)
@@ -677,7 +848,7 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 					return true
 				}
 
-				users, err := t.userService.GetBlockedUsers()
+				users, err := t.userService.GetBlockedUsers(ctx)
 				if err != nil {
 					log.Println(err)
 					msg := tgbotapi.NewMessage(chatID, "Ошибка при получении списка пользователей.")
@@ -690,7 +861,12 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 					byUsername[u.Username] = u
 				}
 
-				err = t.userService.UnblockUsersByUsernames(data.IgnoredList)
+				var selected []models.User
+				for _, username := range data.IgnoredList {
+					selected = append(selected, selectedUser(byUsername, username))
+				}
+
+				err = t.userService.UnblockUsers(ctx, chatID, selected)
 				if err != nil {
 					log.Println(err)
 					msg := tgbotapi.NewMessage(chatID, "Ошибка при разблокировке пользователей.")
@@ -723,7 +899,7 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 			}
 			data.IgnoredList = append(data.IgnoredList, username)
 
-			users, err := t.userService.GetBlockedUsers()
+			users, err := t.userService.GetBlockedUsers(ctx)
 			if err != nil {
 				log.Println(err)
 				msg := tgbotapi.NewMessage(chatID, "Ошибка при обновлении списка пользователей.")
@@ -741,7 +917,7 @@ func (t *Telegram) handleAdminMessageState(update tgbotapi.Update, bot *tgbotapi
 	return false
 }
 
-func (t *Telegram) handleAdminSelectionPaging(update tgbotapi.Update, bot *tgbotapi.BotAPI, chatID int64, state string) bool {
+func (t *Telegram) handleAdminSelectionPaging(ctx context.Context, update tgbotapi.Update, bot *tgbotapi.BotAPI, chatID int64, state string) bool {
 	if update.CallbackQuery == nil {
 		return true
 	}
@@ -765,9 +941,9 @@ func (t *Telegram) handleAdminSelectionPaging(update tgbotapi.Update, bot *tgbot
 	var users []models.User
 	var err error
 	if state == waitingUnblockUsersState {
-		users, err = t.userService.GetBlockedUsers()
+		users, err = t.userService.GetBlockedUsers(ctx)
 	} else {
-		users, err = t.userService.GetAllUsers()
+		users, err = t.userService.GetAllUsers(ctx)
 	}
 	if err != nil {
 		log.Println(err)
@@ -780,18 +956,18 @@ func (t *Telegram) handleAdminSelectionPaging(update tgbotapi.Update, bot *tgbot
 	switch state {
 	case "waiting_promote_admin":
 		for _, user := range users {
-			if user.Role != "admin" {
+			if !IsAdminRole(user.Role) {
 				filtered = append(filtered, user)
 			}
 		}
 	case "waiting_demote_admin":
 		for _, user := range users {
-			if user.Role == "admin" {
+			if user.Role == models.RoleAdmin {
 				filtered = append(filtered, user)
 			}
 		}
 	case waitingBlockUsersState:
-		filtered = users
+		filtered = withoutOwner(users)
 	case waitingUnblockUsersState:
 		filtered = users
 	default:
@@ -813,51 +989,63 @@ func (t *Telegram) handleAdminSelectionPaging(update tgbotapi.Update, bot *tgbot
 	return true
 }
 
-func (t *Telegram) handleLoginState(update tgbotapi.Update, bot *tgbotapi.BotAPI, chatID int64, text string) bool {
+// startRegistration начинает регистрацию пользователя: дальше бот спрашивает дату рождения.
+func (t *Telegram) startRegistration(bot *tgbotapi.BotAPI, chat *tgbotapi.Chat, chatID int64) {
+	t.adminMessageData[chatID] = &AdminMessageState{
+		User: models.User{
+			TelegramID: chatID,
+			Username:   chat.UserName,
+			FirstName:  chat.FirstName,
+			LastName:   chat.LastName,
+			Role:       models.RoleUser,
+			CreatedAt:  time.Now(),
+			UpdatedAt:  time.Now(),
+		},
+	}
+	t.messageState[chatID] = waitingBirthdateState
+
+	msg := tgbotapi.NewMessage(chatID, "Введите вашу дату рождения в формате ДД.ММ.ГГГГ:")
+	bot.Send(msg)
+}
+
+func (t *Telegram) handleLoginState(ctx context.Context, update tgbotapi.Update, bot *tgbotapi.BotAPI, chatID int64, text string) bool {
 	if !t.loginState[chatID] {
 		return false
 	}
 
+	if t.loginLocked(ctx, bot, chatID) {
+		t.loginState[chatID] = false
+		return true
+	}
+
 	if text == *secretWord {
-		user := models.User{
-			TelegramID: chatID,
-			Username:   update.Message.Chat.UserName,
-			FirstName:  update.Message.Chat.FirstName,
-			LastName:   update.Message.Chat.LastName,
-			Role:       "user",
-			CreatedAt:  time.Now(),
-			UpdatedAt:  time.Now(),
+		t.loginState[chatID] = false
+		if err := t.loginAttemptService.ResetLoginAttempts(ctx, chatID); err != nil {
+			log.Println("Error resetting login attempts:", err)
 		}
+		t.startRegistration(bot, update.Message.Chat, chatID)
+		return true
+	}
 
-		t.adminMessageData[chatID] = &AdminMessageState{
-			User: user,
-		}
+	attempt, locked, err := t.loginAttemptService.RegisterFailedLogin(ctx, chatID, update.Message.Chat.UserName)
+	if err != nil {
+		msg := tgbotapi.NewMessage(chatID, "Ошибка при проверке секретного слова. Попробуйте позже.")
+		bot.Send(msg)
+		return true
+	}
 
+	if locked {
 		t.loginState[chatID] = false
-		t.loginAttempts[chatID] = 0
-		t.messageState[chatID] = waitingBirthdateState
-
-		msg := tgbotapi.NewMessage(chatID, "Введите вашу дату рождения в формате ДД.ММ.ГГГГ:")
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Вы исчерпали количество попыток ввода секретного слова. "+
+			"Вход заблокирован до %s.", attempt.LockedUntil.Format("15:04 02.01.2006")))
 		bot.Send(msg)
-	} else {
-		t.loginAttempts[chatID]++
-		if t.loginAttempts[chatID] >= 3 {
-			msg := tgbotapi.NewMessage(chatID, "Вы исчерпали количество попыток ввода секретного слова и заблокированы.")
-			bot.Send(msg)
-			t.loginState[chatID] = false
-			t.loginAttempts[chatID] = 0
-
-			// Обновляем состояние пользователя в базе данных
-			err := t.userService.DeleteUsersByUsernames([]string{update.Message.Chat.UserName})
-			if err != nil {
-				log.Println("Error blocking user:", err)
-			}
-		} else {
-			msg := tgbotapi.NewMessage(chatID, "Неправильное секретное слово, попробуйте снова.")
-			bot.Send(msg)
-		}
+		t.notifyAdminsAboutLockout(ctx, attempt)
+		return true
 	}
 
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Неправильное секретное слово, попробуйте снова. Осталось попыток: %d.",
+		maxLoginAttempts-attempt.FailedAttempts))
+	bot.Send(msg)
 	return true
 }
 
@@ -876,8 +1064,8 @@ func (t *Telegram) clearInlineKeyboard(bot *tgbotapi.BotAPI, update tgbotapi.Upd
 	}
 }
 
-func (t *Telegram) startBlockUsersFlow(chatID int64, bot *tgbotapi.BotAPI) {
-	users, err := t.userService.GetAllUsers()
+func (t *Telegram) startBlockUsersFlow(ctx context.Context, chatID int64, bot *tgbotapi.BotAPI) {
+	users, err := t.userService.GetAllUsers(ctx)
 	if err != nil {
 		log.Println(err)
 		msg := tgbotapi.NewMessage(chatID, "Ошибка при получении списка пользователей.")
@@ -885,6 +1073,7 @@ func (t *Telegram) startBlockUsersFlow(chatID int64, bot *tgbotapi.BotAPI) {
 		return
 	}
 
+	users = withoutOwner(users)
 	if len(users) == 0 {
 		msg := tgbotapi.NewMessage(chatID, "Нет пользователей для блокировки.")
 		bot.Send(msg)
@@ -900,8 +1089,8 @@ func (t *Telegram) startBlockUsersFlow(chatID int64, bot *tgbotapi.BotAPI) {
 	bot.Send(msg)
 }
 
-func (t *Telegram) startUnblockUsersFlow(chatID int64, bot *tgbotapi.BotAPI) {
-	users, err := t.userService.GetBlockedUsers()
+func (t *Telegram) startUnblockUsersFlow(ctx context.Context, chatID int64, bot *tgbotapi.BotAPI) {
+	users, err := t.userService.GetBlockedUsers(ctx)
 	if err != nil {
 		log.Println(err)
 		msg := tgbotapi.NewMessage(chatID, "Ошибка при получении списка пользователей.")
@@ -924,11 +1113,100 @@ func (t *Telegram) startUnblockUsersFlow(chatID int64, bot *tgbotapi.BotAPI) {
 	bot.Send(msg)
 }
 
-func (t *Telegram) handleCommand(update tgbotapi.Update, bot *tgbotapi.BotAPI, chatID int64, text string) {
+// handleCallback обрабатывает inline-кнопки с данными вида "<действие>:<аргумент>".
+func (t *Telegram) handleCallback(ctx context.Context, update tgbotapi.Update, bot *tgbotapi.BotAPI, chatID int64, text string) bool {
+	if update.CallbackQuery == nil {
+		return false
+	}
+
+	action, arg, ok := strings.Cut(text, ":")
+	if !ok {
+		return false
+	}
+	if !t.authorizeCallback(ctx, bot, chatID, action) {
+		return true
+	}
+
+	switch action {
+	case calendarCallback:
+		t.handleCalendarCallback(ctx, update, bot, chatID, arg)
+		return true
+	case birthdaysPageCallback:
+		t.handleBirthdaysPageCallback(ctx, update, bot, chatID, arg)
+		return true
+	case santaJoinCallback, santaLeaveCallback:
+		t.handleSantaCallback(ctx, update, bot, chatID, action, arg)
+		return true
+	case deleteMeCallback:
+		t.handleDeleteMeCallback(ctx, update, bot, chatID, arg)
+		return true
+	case offboardCallback:
+		t.handleOffboardCallback(ctx, update, bot, chatID, arg)
+		return true
+	case auditCallback:
+		t.handleAuditCallback(ctx, update, bot, chatID, arg)
+		return true
+	case profileCallback:
+		t.handleProfileCallback(ctx, update, bot, chatID, arg)
+		return true
+	case registrationApproveCallback, registrationRejectCallback:
+		t.handleRegistrationCallback(ctx, update, bot, chatID, action, arg)
+		return true
+	}
+	return false
+}
+
+// parseDate разбирает дату в формате ДД.ММ.ГГГГ, который используется во всех диалогах бота.
+func parseDate(text string) (time.Time, error) {
+	return time.Parse("02.01.2006", strings.TrimSpace(text))
+}
+
+// parseCommand разделяет текст сообщения на команду и аргументы.
+func parseCommand(text string) (string, []string) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return "", nil
+	}
+	return fields[0], fields[1:]
+}
+
+// commandRemainder возвращает текст после первых skip слов (включая команду) без изменения пробелов и переносов строк.
+func commandRemainder(text string, skip int) string {
+	rest := strings.TrimSpace(text)
+	for i := 0; i < skip; i++ {
+		idx := strings.IndexFunc(rest, unicode.IsSpace)
+		if idx < 0 {
+			return ""
+		}
+		rest = strings.TrimSpace(rest[idx:])
+	}
+	return rest
+}
+
+// findUserByUsername ищет активного пользователя по нику (с @ или без).
+func (t *Telegram) findUserByUsername(ctx context.Context, username string) (models.User, bool, error) {
+	username = strings.TrimPrefix(strings.TrimSpace(username), "@")
+	users, err := t.userService.GetAllUsers(ctx)
+	if err != nil {
+		return models.User{}, false, err
+	}
+	for _, u := range users {
+		if strings.EqualFold(u.Username, username) {
+			return u, true, nil
+		}
+	}
+	return models.User{}, false, nil
+}
+
+func (t *Telegram) handleCommand(ctx context.Context, update tgbotapi.Update, bot *tgbotapi.BotAPI, chatID int64, text string) {
 	if update.Message == nil {
 		return
 	}
-	switch text {
+	command, args := parseCommand(text)
+	if !t.authorizeCommand(ctx, bot, chatID, command) {
+		return
+	}
+	switch command {
 	case "/chat":
 		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Ваш уникальный номер чата: `%d`", chatID))
 		msg.ParseMode = "Markdown"
@@ -939,7 +1217,7 @@ func (t *Telegram) handleCommand(update tgbotapi.Update, bot *tgbotapi.BotAPI, c
 			TelegramID: chatID,
 		}
 
-		existingUser, err := t.userService.GetUser(user)
+		existingUser, err := t.userService.GetUser(ctx, user)
 		if err != nil && err != sql.ErrNoRows {
 			log.Errorf("error getting existing user: %v", err)
 			return
@@ -951,131 +1229,117 @@ func (t *Telegram) handleCommand(update tgbotapi.Update, bot *tgbotapi.BotAPI, c
 			return
 		}
 
-		msg := tgbotapi.NewMessage(chatID, "Напишите секретное слово, которое вам выдали, для регистрации в боте")
-		msg.ParseMode = "Markdown"
-		bot.Send(msg)
-
-		t.loginState[chatID] = true
-		t.loginAttempts[chatID] = 0
-
-	case "/message":
-		user, err := t.userService.GetUser(models.User{TelegramID: chatID})
-		if err != nil {
-			log.Println(err)
-			msg := tgbotapi.NewMessage(chatID, "Ошибка при получении данных пользователя.")
-			bot.Send(msg)
+		// Владелец новой установки регистрируется без секретного слова: приглашение выдать ещё некому
+		if t.awaitingOwner(ctx, chatID) {
+			t.startRegistration(bot, update.Message.Chat, chatID)
 			return
 		}
 
-		if user.Role == "admin" {
-			msg := tgbotapi.NewMessage(chatID, "Введите сообщение, которое хотите отправить всем пользователям:")
-			bot.Send(msg)
-			t.messageState[chatID] = "waiting_message"
-		} else {
-			msg := tgbotapi.NewMessage(chatID, "У вас нет прав для использования этой команды.")
+		if !legacySecretEnabled() {
+			msg := tgbotapi.NewMessage(chatID, "Регистрация по секретному слову отключена. "+
+				"Попросите у администратора ссылку-приглашение и откройте её.")
 			bot.Send(msg)
+			return
 		}
 
-	case "/block":
-		user, err := t.userService.GetUser(models.User{TelegramID: chatID})
-		if err != nil {
-			log.Println(err)
-			msg := tgbotapi.NewMessage(chatID, "Ошибка при получении данных пользователя.")
-			bot.Send(msg)
+		if t.loginLocked(ctx, bot, chatID) {
 			return
 		}
 
-		if user.Role == "admin" {
-			t.startBlockUsersFlow(chatID, bot)
-		} else {
-			msg := tgbotapi.NewMessage(chatID, "У вас нет прав для использования этой команды.")
-			bot.Send(msg)
-		}
+		msg := tgbotapi.NewMessage(chatID, "Напишите секретное слово, которое вам выдали, для регистрации в боте")
+		msg.ParseMode = "Markdown"
+		bot.Send(msg)
 
-	case "/unblock":
-		user, err := t.userService.GetUser(models.User{TelegramID: chatID})
-		if err != nil {
-			log.Println(err)
-			msg := tgbotapi.NewMessage(chatID, "Ошибка при получении данных пользователя.")
-			bot.Send(msg)
-			return
-		}
+		t.loginState[chatID] = true
 
-		if user.Role == "admin" {
-			t.startUnblockUsersFlow(chatID, bot)
-		} else {
-			msg := tgbotapi.NewMessage(chatID, "У вас нет прав для использования этой команды.")
-			bot.Send(msg)
-		}
+	case "/message":
+		msg := tgbotapi.NewMessage(chatID, "Введите сообщение, которое хотите отправить всем пользователям:")
+		bot.Send(msg)
+		t.messageState[chatID] = "waiting_message"
+
+	case "/block":
+		t.startBlockUsersFlow(ctx, chatID, bot)
+
+	case "/unblock":
+		t.startUnblockUsersFlow(ctx, chatID, bot)
 
 	case "/list":
-		user, err := t.userService.GetUser(models.User{TelegramID: chatID})
+		users, err := t.userService.GetAllUsers(ctx)
 		if err != nil {
 			log.Println(err)
-			msg := tgbotapi.NewMessage(chatID, "Ошибка при получении данных пользователя.")
+			msg := tgbotapi.NewMessage(chatID, "Ошибка при обновлении списка пользователей.")
 			bot.Send(msg)
 			return
 		}
-
-		if user.Role == "admin" {
-			users, err := t.userService.GetAllUsers()
-			if err != nil {
-				log.Println(err)
-				msg := tgbotapi.NewMessage(chatID, "Ошибка при обновлении списка пользователей.")
-				bot.Send(msg)
-				return
-			}
-			if len(users) == 0 {
-				msg := tgbotapi.NewMessage(chatID, "Нет зарегистрированных пользователей.")
-				t.Bot.Send(msg)
-			}
-
-			var userList string
-			i := 1
-			for _, user := range users {
-				userList += fmt.Sprintf("%v. @%s\n", i, user.Username)
-				i++
-			}
-
-			msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Список зарегистрированных пользователей:\n\n%s", userList))
+		if len(users) == 0 {
+			msg := tgbotapi.NewMessage(chatID, "Нет зарегистрированных пользователей.")
 			t.Bot.Send(msg)
-		} else {
-			msg := tgbotapi.NewMessage(chatID, "У вас нет прав для использования этой команды.")
-			bot.Send(msg)
 		}
 
+		var userList string
+		i := 1
+		for _, user := range users {
+			userList += fmt.Sprintf("%v. @%s\n", i, user.Username)
+			i++
+		}
+
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Список зарегистрированных пользователей:\n\n%s", userList))
+		t.Bot.Send(msg)
+
 	case "/help":
 		helpText := "Доступные команды:\n" +
 			"/start — приветствие\n" +
 			"/help — список команд\n" +
 			"/chat — показать ID чата\n" +
-			"/login — регистрация в боте\n\n" +
-			"Команды только для админов:\n" +
+			"/login — регистрация в боте\n" +
+			"/birthdays — ближайшие дни рождения коллег\n" +
+			"/profile — ваш профиль: дата рождения, имя и уведомления\n" +
+			"/export_my_data — выгрузить все ваши данные в JSON\n" +
+			"/delete_me — удалить аккаунт и все данные\n" +
+			"/calendar — ссылка на календарь дней рождения для подписки\n" +
+			"/santa — Тайный Санта: участие и ваш получатель\n\n" +
+			"Для организаторов и администраторов:\n" +
+			"/birthdays month — дни рождения в этом месяце\n" +
+			"/holidays — ближайшие праздники\n" +
+			"/holiday_greeting ID [текст] — изменить или сбросить поздравление\n" +
+			"/occasions [@ник] — ближайшие события или события пользователя\n" +
+			"/occasion_add @ник тип ДД.ММ.ГГГГ [название] — добавить событие\n" +
+			"/occasion_delete ID — удалить событие\n" +
+			"/occasion_types — типы событий и настройки напоминаний\n" +
+			"/occasion_type код дни [шаблон] — изменить напоминание для типа\n" +
+			"/santa_open [название] — открыть регистрацию на Тайного Санту\n" +
+			"/santa_exclude @ник1 @ник2 — запретить паре дарить друг другу\n" +
+			"/santa_draw — провести жеребьёвку\n" +
+			"/santa_audit — проверить жеребьёвку\n" +
+			"/santa_close — закрыть событие\n\n" +
+			"Только для администраторов:\n" +
 			"/message — рассылка сообщения пользователям\n" +
 			"/block — заблокировать пользователей\n" +
 			"/unblock — разблокировать пользователей\n" +
 			"/list — список зарегистрированных пользователей\n" +
 			"/admin_add — назначить администратора\n" +
-			"/admin_remove — снять права администратора"
+			"/admin_remove — снять права администратора\n" +
+			"/role @ник роль — назначить роль (admin, organiser, user)\n" +
+			"/invite [дни] [использований] [команда] — создать ссылку-приглашение\n" +
+			"/invites — действующие приглашения\n" +
+			"/invite_revoke ID — отозвать приглашение\n" +
+			"/export_user @ник — выгрузить данные пользователя\n" +
+			"/offboard @ник — удалить пользователя и его данные\n" +
+			"/lockouts — заблокированные попытки входа\n" +
+			"/login_unlock ID — снять блокировку входа\n" +
+			"/pending — заявки на регистрацию, ожидающие подтверждения\n" +
+			"/set_team @ник Команда — указать команду пользователя\n" +
+			"/audit [действие] [@ник] — журнал действий администраторов\n" +
+			"/api_keys — действующие API-ключи\n" +
+			"/api_key_create имя области — создать API-ключ\n" +
+			"/api_key_revoke ID — отозвать API-ключ\n" +
+			"/jobs — фоновые задачи и их последние запуски\n" +
+			"/job_run имя — запустить задачу вне расписания"
 		msg := tgbotapi.NewMessage(chatID, helpText)
 		bot.Send(msg)
 
 	case "/admin_add":
-		user, err := t.userService.GetUser(models.User{TelegramID: chatID})
-		if err != nil {
-			log.Println(err)
-			msg := tgbotapi.NewMessage(chatID, "Ошибка при получении данных пользователя.")
-			bot.Send(msg)
-			return
-		}
-
-		if user.Role != "admin" {
-			msg := tgbotapi.NewMessage(chatID, "У вас нет прав для использования этой команды.")
-			bot.Send(msg)
-			return
-		}
-
-		users, err := t.userService.GetAllUsers()
+		users, err := t.userService.GetAllUsers(ctx)
 		if err != nil {
 			log.Println(err)
 			msg := tgbotapi.NewMessage(chatID, "Ошибка при получении списка пользователей.")
@@ -1085,7 +1349,7 @@ func (t *Telegram) handleCommand(update tgbotapi.Update, bot *tgbotapi.BotAPI, c
 
 		var candidates []models.User
 		for _, u := range users {
-			if u.Role != "admin" {
+			if !IsAdminRole(u.Role) {
 				candidates = append(candidates, u)
 			}
 		}
@@ -1105,21 +1369,7 @@ func (t *Telegram) handleCommand(update tgbotapi.Update, bot *tgbotapi.BotAPI, c
 		bot.Send(msg)
 
 	case "/admin_remove":
-		user, err := t.userService.GetUser(models.User{TelegramID: chatID})
-		if err != nil {
-			log.Println(err)
-			msg := tgbotapi.NewMessage(chatID, "Ошибка при получении данных пользователя.")
-			bot.Send(msg)
-			return
-		}
-
-		if user.Role != "admin" {
-			msg := tgbotapi.NewMessage(chatID, "У вас нет прав для использования этой команды.")
-			bot.Send(msg)
-			return
-		}
-
-		users, err := t.userService.GetAllUsers()
+		users, err := t.userService.GetAllUsers(ctx)
 		if err != nil {
 			log.Println(err)
 			msg := tgbotapi.NewMessage(chatID, "Ошибка при получении списка пользователей.")
@@ -1129,7 +1379,7 @@ func (t *Telegram) handleCommand(update tgbotapi.Update, bot *tgbotapi.BotAPI, c
 
 		var admins []models.User
 		for _, u := range users {
-			if u.Role == "admin" {
+			if u.Role == models.RoleAdmin {
 				admins = append(admins, u)
 			}
 		}
@@ -1148,6 +1398,94 @@ func (t *Telegram) handleCommand(update tgbotapi.Update, bot *tgbotapi.BotAPI, c
 		msg.ReplyMarkup = keyboard
 		bot.Send(msg)
 
+	case "/audit":
+		t.handleAuditCommand(ctx, bot, chatID, args)
+	case "/api_keys":
+		t.handleAPIKeysCommand(ctx, bot, chatID)
+	case "/api_key_create":
+		t.handleAPIKeyCreateCommand(ctx, bot, chatID, args)
+	case "/api_key_revoke":
+		t.handleAPIKeyRevokeCommand(ctx, bot, chatID, args)
+	case "/jobs":
+		t.handleJobsCommand(ctx, bot, chatID)
+	case "/job_run":
+		t.handleJobRunCommand(ctx, bot, chatID, args)
+	case "/role":
+		t.handleRoleCommand(ctx, bot, chatID, args)
+
+	case "/birthdays":
+		t.handleBirthdaysCommand(ctx, bot, chatID, args)
+
+	case "/profile":
+		t.handleProfileCommand(ctx, bot, chatID)
+	case "/export_my_data":
+		t.handleExportMyDataCommand(ctx, bot, chatID)
+	case "/delete_me":
+		t.handleDeleteMeCommand(ctx, bot, chatID)
+	case "/export_user":
+		t.handleExportUserCommand(ctx, bot, chatID, args)
+	case "/offboard":
+		t.handleOffboardCommand(ctx, bot, chatID, args)
+	case "/calendar":
+		t.handleCalendarCommand(ctx, bot, chatID)
+
+	case "/occasions":
+		t.handleOccasionsCommand(ctx, bot, chatID, args)
+
+	case "/occasion_add":
+		t.handleOccasionAddCommand(ctx, bot, chatID, args)
+
+	case "/occasion_delete":
+		t.handleOccasionDeleteCommand(ctx, bot, chatID, args)
+
+	case "/occasion_types":
+		t.handleOccasionTypesCommand(ctx, bot, chatID)
+
+	case "/occasion_type":
+		t.handleOccasionTypeCommand(ctx, bot, chatID, args)
+
+	case "/holidays":
+		t.handleHolidaysCommand(ctx, bot, chatID)
+
+	case "/holiday_greeting":
+		t.handleHolidayGreetingCommand(ctx, bot, chatID, args, commandRemainder(text, 2))
+
+	case "/invite":
+		t.handleInviteCommand(ctx, bot, chatID, args)
+
+	case "/invites":
+		t.handleInvitesCommand(ctx, bot, chatID)
+
+	case "/lockouts":
+		t.handleLockoutsCommand(ctx, bot, chatID)
+	case "/login_unlock":
+		t.handleLoginUnlockCommand(ctx, bot, chatID, args)
+	case "/pending":
+		t.handlePendingCommand(ctx, bot, chatID)
+	case "/invite_revoke":
+		t.handleInviteRevokeCommand(ctx, bot, chatID, args)
+
+	case "/set_team":
+		t.handleSetTeamCommand(ctx, bot, chatID, args)
+
+	case "/santa":
+		t.handleSantaCommand(ctx, bot, chatID)
+
+	case "/santa_open":
+		t.handleSantaOpenCommand(ctx, bot, chatID, args)
+
+	case "/santa_exclude":
+		t.handleSantaExcludeCommand(ctx, bot, chatID, args)
+
+	case "/santa_draw":
+		t.handleSantaDrawCommand(ctx, bot, chatID)
+
+	case "/santa_audit":
+		t.handleSantaAuditCommand(ctx, bot, chatID)
+
+	case "/santa_close":
+		t.handleSantaCloseCommand(ctx, bot, chatID)
+
 	default:
 		if update.Message.Text == "/start" || update.Message.From.UserName == "/start" {
 			user := models.User{
@@ -1158,7 +1496,7 @@ func (t *Telegram) handleCommand(update tgbotapi.Update, bot *tgbotapi.BotAPI, c
 			msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Привет, %s! Это простой телеграм бот для поздравляшек "+
 				"своих близких коллег. Тут есть пару команд, чтобы ты мог начать получать сообщения! Если что-то будет "+
 				"не так, то ты всегда можешь написать своему администратору для устранения проблем.\n\n"+
-				"Для начала введите команду /login", user.Username))
+				"%s", user.Username, registrationHint()))
 			msg.ParseMode = "Markdown"
 			bot.Send(msg)
 			return
@@ -1170,15 +1508,16 @@ func (t *Telegram) handleCommand(update tgbotapi.Update, bot *tgbotapi.BotAPI, c
 }
 
 // Метод для отправки сообщений всем пользователям, кроме указанных
-func (t *Telegram) sendMessageToUsers(adminID int64) {
+func (t *Telegram) sendMessageToUsers(ctx context.Context, adminID int64) {
 	data := t.adminMessageData[adminID]
 	if data == nil {
 		msg := tgbotapi.NewMessage(adminID, "Ошибка при отправке сообщения.")
 		t.Bot.Send(msg)
 		return
 	}
+	defer delete(t.adminMessageData, adminID)
 
-	users, err := t.userService.GetAllUsers()
+	users, err := t.userService.GetAllUsers(ctx)
 	if err != nil {
 		log.Println(err)
 		msg := tgbotapi.NewMessage(adminID, "Ошибка при получении списка пользователей.")
@@ -1190,20 +1529,60 @@ func (t *Telegram) sendMessageToUsers(adminID int64) {
 	for _, username := range data.IgnoredList {
 		ignoredUsernames[username] = struct{}{}
 	}
-
+	var excludedIDs []int64
 	for _, user := range users {
-		if _, ignored := ignoredUsernames[user.Username]; !ignored {
-			log.Printf("Sending message to user: %s", user.Username)
-			msg := tgbotapi.NewMessage(user.TelegramID, data.Message)
-			t.Bot.Send(msg)
-		} else {
+		if _, ignored := ignoredUsernames[user.Username]; ignored {
 			log.Printf("Ignoring user: %s", user.Username)
+			excludedIDs = append(excludedIDs, user.TelegramID)
+		}
+	}
+
+	// Рассылка из бота проходит тот же путь, что и рассылка через API, только отправляется сразу
+	broadcast, err := t.broadcastService.CreateBroadcast(ctx, models.Broadcast{
+		Text:        data.Message,
+		Audience:    models.BroadcastAudienceAll,
+		ExcludedIDs: excludedIDs,
+		CreatedBy:   adminID,
+	})
+	if err == nil {
+		var claimed bool
+		broadcast, claimed, err = t.broadcastService.ClaimBroadcast(ctx, broadcast.ID)
+		if err == nil && !claimed {
+			// Рассылку уже забрал SendDueBroadcasts — она будет отправлена им
+			t.Bot.Send(tgbotapi.NewMessage(adminID, "Сообщение поставлено в очередь на отправку."))
+			return
 		}
 	}
+	if err != nil {
+		log.Println(err)
+		t.Bot.Send(tgbotapi.NewMessage(adminID, "Ошибка при отправке сообщения."))
+		return
+	}
 
-	msg := tgbotapi.NewMessage(adminID, "Сообщение отправлено всем пользователям.")
-	t.Bot.Send(msg)
-	delete(t.adminMessageData, adminID)
+	// Рассылка всем идёт дольше updateTimeout и не должна задерживать остальные обновления, поэтому
+	// отправляется в фоне; если бот остановится на середине, её дошлёт SendDueBroadcasts
+	t.Bot.Send(tgbotapi.NewMessage(adminID, "Сообщение отправляется. Когда рассылка закончится, я пришлю итог."))
+	go t.deliverAdminBroadcast(context.WithoutCancel(ctx), adminID, broadcast, len(excludedIDs))
+}
+
+// deliverAdminBroadcast отправляет рассылку из /message и сообщает администратору итог.
+func (t *Telegram) deliverAdminBroadcast(ctx context.Context, adminID int64, broadcast models.Broadcast, excluded int) {
+	stats, err := t.deliverBroadcast(ctx, broadcast)
+	if err != nil {
+		log.Errorf("broadcast %d not delivered: %v", broadcast.ID, err)
+		t.Bot.Send(tgbotapi.NewMessage(adminID, "Ошибка при отправке сообщения."))
+		return
+	}
+	// Текст и исключённые получатели хранятся в самой рассылке; в журнале только число исключённых
+	t.auditService.Record(ctx, adminID, models.AuditActionBroadcast, 0, map[string]interface{}{
+		"broadcast_id": broadcast.ID, "recipients": stats.Sent, "excluded": excluded,
+	})
+
+	text := "Сообщение отправлено всем пользователям."
+	if stats.Failed > 0 {
+		text += fmt.Sprintf(" Не удалось доставить: %d.", stats.Failed)
+	}
+	t.Bot.Send(tgbotapi.NewMessage(adminID, text))
 }
 
 func (t *Telegram) createUserSelectionKeyboard(users []models.User, data *AdminMessageState, addActionButton bool, actionLabel string, actionCallback string, addCancelButton bool) tgbotapi.InlineKeyboardMarkup {
@@ -1276,6 +1655,26 @@ func (t *Telegram) createUserSelectionKeyboard(users []models.User, data *AdminM
 	return tgbotapi.NewInlineKeyboardMarkup(rows...)
 }
 
+// withoutOwner убирает владельца бота из списков для блокировки.
+func withoutOwner(users []models.User) []models.User {
+	var filtered []models.User
+	for _, u := range users {
+		if u.Role != models.RoleOwner {
+			filtered = append(filtered, u)
+		}
+	}
+	return filtered
+}
+
+// selectedUser возвращает пользователя по нику из выбора. Ник из устаревшей клавиатуры, которого уже нет
+// в списке, возвращается без Telegram ID: событие в журнале всё равно пишется.
+func selectedUser(byUsername map[string]models.User, username string) models.User {
+	if u, ok := byUsername[username]; ok {
+		return u
+	}
+	return models.User{Username: username}
+}
+
 func filterUsersByIgnored(users []models.User, data *AdminMessageState) []models.User {
 	if data == nil || len(data.IgnoredList) == 0 {
 		return users
@@ -1297,84 +1696,95 @@ func filterUsersByIgnored(users []models.User, data *AdminMessageState) []models
 
 func formatUserButtonText(user models.User) string {
 	username := strings.TrimSpace(user.Username)
-	firstName := strings.TrimSpace(user.FirstName)
-	lastName := strings.TrimSpace(user.LastName)
 
 	display := "@" + username
-	name := strings.TrimSpace(strings.Join([]string{firstName, lastName}, " "))
+	name := FormatUserName(user)
 	if name != "" {
 		display += " — " + name
 	}
 	return display
 }
 
-func (t *Telegram) NotifyUpcomingBirthdays() {
-	now := time.Now().In(time.Local)
+// NotifyUpcomingOccasions уведомляет администраторов о событиях, до которых осталось столько дней,
+// сколько задано в настройках типа события. Дни рождения — один из типов событий.
+// at — логическое время запуска: догоняющий запуск после простоя считает дни от пропущенной даты.
+func (t *Telegram) NotifyUpcomingOccasions(ctx context.Context, at time.Time) error {
+	now := at.In(time.Local)
 	notifyDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 
-	usersWithBirthdayIn3Days, err := t.userService.GetUsersWithBirthdayInDays()
+	dueOccasions, err := t.occasionService.GetDueOccasionReminders(ctx, notifyDate)
 	if err != nil {
-		log.Println("Error getting users with birthday in 3 days:", err)
-		return
+		log.Println("Error getting upcoming occasions:", err)
+		return err
 	}
 
-	if len(usersWithBirthdayIn3Days) == 0 {
-		return
+	if len(dueOccasions) == 0 {
+		return nil
 	}
 
-	admins, err := t.userService.GetAllAdmins()
+	// Напоминания получают все, чьей роли выдано право receive_reminders (администраторы и организаторы)
+	admins, err := t.userService.GetUsersByRoles(ctx, RolesWithPermission(PermissionReceiveReminders))
 	if err != nil {
-		log.Println("Error getting all admins:", err)
-		return
+		log.Println("Error getting reminder recipients:", err)
+		return err
 	}
 
-	for _, birthdayUser := range usersWithBirthdayIn3Days {
+	failed := 0
+	for _, upcoming := range dueOccasions {
 		for _, admin := range admins {
-			sent, err := t.userService.HasBirthdayNotification(admin.TelegramID, birthdayUser.TelegramID, notifyDate)
-			if err != nil {
-				log.Println("Error checking birthday notification:", err)
+			if !admin.NotifyReminders {
 				continue
 			}
-			if sent {
-				continue
-			}
-
-			message := fmt.Sprintf("У нашего коллеги @%s скоро день рождения! Не забудьте его поздравить!", birthdayUser.Username)
-			msg := tgbotapi.NewMessage(admin.TelegramID, message)
-			//fmt.Printf("Notifying admin %s about upcoming birthday of %s", admin.Username, birthdayUser.Username)
-			if _, err := t.Bot.Send(msg); err != nil {
-				log.Printf("Error notifying admin %s about birthday of %s: %v", admin.Username, birthdayUser.Username, err)
-				continue
+			msg := tgbotapi.NewMessage(admin.TelegramID, RenderOccasionReminder(upcoming))
+			send := func() error {
+				_, err := t.Bot.Send(msg)
+				return err
 			}
-
-			if err := t.userService.SaveBirthdayNotification(admin.TelegramID, birthdayUser.TelegramID, notifyDate); err != nil {
-				log.Println("Error saving birthday notification:", err)
+			if _, err := t.deliverOccasionNotification(ctx, admin, upcoming, notifyDate, send); err != nil {
+				log.Printf("Error notifying admin %s about %s of %s: %v", admin.Username, upcoming.Type.Code, upcoming.User.Username, err)
+				failed++
 			}
 		}
 	}
+	if failed > 0 {
+		return fmt.Errorf("%d occasion reminder(s) not delivered", failed)
+	}
+	return nil
+}
+
+// deliverOccasionNotification отправляет напоминание не больше одного раза: дни рождения учитываются
+// в birthday_notifications, остальные события — в occasion_notifications.
+func (t *Telegram) deliverOccasionNotification(ctx context.Context, admin models.User, upcoming models.UpcomingOccasion, date time.Time, send func() error) (bool, error) {
+	if upcoming.Type.Code == models.OccasionTypeBirthday {
+		return t.userService.DeliverBirthdayNotification(ctx, admin.TelegramID, upcoming.User.TelegramID, date, send)
+	}
+	return t.occasionService.DeliverOccasionNotification(ctx, admin.TelegramID, upcoming.Occasion.ID, date, send)
 }
 
-func (t *Telegram) SyncUserProfiles() {
-	users, err := t.userService.GetAllUsers()
+// SyncUserProfiles обновляет ники и имена из Telegram. Ошибка возвращается, если не удалось получить ни одного профиля.
+func (t *Telegram) SyncUserProfiles(ctx context.Context) error {
+	users, err := t.userService.GetAllUsers(ctx)
 	if err != nil {
 		log.Println("Error getting users for profile sync:", err)
-		return
+		return err
 	}
 
 	if len(users) == 0 {
-		return
+		return nil
 	}
 
 	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
 	baseDelay := 250 * time.Millisecond
 	jitter := 250 * time.Millisecond
 
+	failed := 0
 	for _, user := range users {
 		chat, err := t.Bot.GetChat(tgbotapi.ChatInfoConfig{
 			ChatConfig: tgbotapi.ChatConfig{ChatID: user.TelegramID},
 		})
 		if err != nil {
 			log.Printf("Error getting chat %d: %v", user.TelegramID, err)
+			failed++
 			time.Sleep(baseDelay + time.Duration(rng.Intn(int(jitter.Milliseconds())))*time.Millisecond)
 			continue
 		}
@@ -1388,11 +1798,16 @@ func (t *Telegram) SyncUserProfiles() {
 			user.FirstName = newFirstName
 			user.LastName = newLastName
 
-			if err := t.userService.UpdateUser(user); err != nil {
+			if err := t.userService.UpdateUser(ctx, user); err != nil {
 				log.Printf("Error updating user %d: %v", user.TelegramID, err)
 			}
 		}
 
 		time.Sleep(baseDelay + time.Duration(rng.Intn(int(jitter.Milliseconds())))*time.Millisecond)
 	}
+
+	if failed == len(users) {
+		return fmt.Errorf("profile sync: no profiles fetched out of %d", len(users))
+	}
+	return nil
 }
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gift-bot/pkg/models"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleAPIKeyCreateCommand выпускает API-ключ: /api_key_create имя область[,область]. Ключ показывается один раз.
+func (t *Telegram) handleAPIKeyCreateCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, args []string) {
+	if len(args) != 2 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Использование: /api_key_create имя область[,область]\n\n"+
+			"Области доступа: "+strings.Join(APIScopes, ", ")+". admin включает все остальные.\n"+
+			"Пример: /api_key_create hr-sync read:users,write:users"))
+		return
+	}
+
+	scopes, err := ParseAPIScopes(args[1])
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Неизвестная область доступа в «%s». Доступны: %s.", args[1], strings.Join(APIScopes, ", "))))
+		return
+	}
+
+	key, plain, err := t.apiKeyService.CreateAPIKey(ctx, args[0], scopes, chatID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при создании ключа."))
+		return
+	}
+	t.auditService.Record(ctx, chatID, models.AuditActionAPIKeyCreate, 0, map[string]interface{}{
+		"api_key_id": key.ID, "name": key.Name, "scopes": key.Scopes,
+	})
+
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Ключ №%d «%s» создан, области: %s.\n\n%s\n\n"+
+		"Ключ показывается только один раз — сохраните его и удалите это сообщение. "+
+		"Передавайте ключ в заголовке Authorization: Bearer <ключ>.",
+		key.ID, key.Name, strings.Join(key.Scopes, ", "), plain)))
+}
+
+func (t *Telegram) handleAPIKeysCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) {
+	keys, err := t.apiKeyService.GetActiveAPIKeys(ctx)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении списка ключей."))
+		return
+	}
+	if len(keys) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Действующих API-ключей нет. Создать: /api_key_create"))
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("Действующие API-ключи:\n\n")
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%d. %s (%s…) — %s\n", key.ID, key.Name, key.KeyPrefix, strings.Join(key.Scopes, ", "))
+		fmt.Fprintf(&b, "    создан %s", key.CreatedAt.Format("02.01.2006"))
+		if key.LastUsedAt != nil {
+			fmt.Fprintf(&b, ", использован %s", key.LastUsedAt.Format("02.01.2006 15:04"))
+		} else {
+			b.WriteString(", не использовался")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\nОтозвать ключ: /api_key_revoke ID")
+	bot.Send(tgbotapi.NewMessage(chatID, b.String()))
+}
+
+func (t *Telegram) handleAPIKeyRevokeCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, args []string) {
+	if len(args) != 1 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Использование: /api_key_revoke ID"))
+		return
+	}
+
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "ID ключа должен быть числом."))
+		return
+	}
+
+	revoked, err := t.apiKeyService.RevokeAPIKey(ctx, id)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при отзыве ключа."))
+		return
+	}
+	if !revoked {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Действующего ключа №%d нет.", id)))
+		return
+	}
+	t.auditService.Record(ctx, chatID, models.AuditActionAPIKeyRevoke, 0, map[string]interface{}{"api_key_id": id})
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Ключ №%d отозван.", id)))
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gift-bot/pkg/config"
+	"gift-bot/pkg/models"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	registrationApproveCallback = "reg_approve"
+	registrationRejectCallback  = "reg_reject"
+)
+
+// registrationApprovalEnabled сообщает, нужно ли подтверждение администратора для новых пользователей.
+func registrationApprovalEnabled() bool {
+	return config.GlobalСonfig.Telegram.RegistrationApproval
+}
+
+// newUserStatus возвращает статус, с которым создаётся новый пользователь.
+func newUserStatus() string {
+	if registrationApprovalEnabled() {
+		return models.UserStatusPending
+	}
+	return models.UserStatusActive
+}
+
+// isConfiguredOwner сообщает, что telegramID указан в OWNER_TELEGRAM_ID.
+func isConfiguredOwner(telegramID int64) bool {
+	owner := config.GlobalСonfig.Telegram.OwnerTelegramID
+	return owner != 0 && owner == telegramID
+}
+
+// awaitingOwner сообщает, что chatID — владелец из OWNER_TELEGRAM_ID, а владельца в боте ещё нет.
+func (t *Telegram) awaitingOwner(ctx context.Context, chatID int64) bool {
+	if !isConfiguredOwner(chatID) {
+		return false
+	}
+	owners, err := t.userService.GetUsersByRoles(ctx, []string{models.RoleOwner})
+	if err != nil {
+		log.Errorf("get owners err: %v", err)
+		return false
+	}
+	return len(owners) == 0
+}
+
+// notifyAdminsAboutRegistration сообщает администраторам о новом пользователе,
+// а для заявок в очереди добавляет кнопки «Одобрить» и «Отклонить».
+func (t *Telegram) notifyAdminsAboutRegistration(ctx context.Context, user models.User) {
+	admins, err := t.userService.GetAllAdmins(ctx)
+	if err != nil {
+		log.Println("Error getting all admins:", err)
+		return
+	}
+
+	for _, admin := range admins {
+		var msg tgbotapi.MessageConfig
+		if user.Status == models.UserStatusPending {
+			msg = tgbotapi.NewMessage(admin.TelegramID, "Новая заявка на регистрацию:\n"+formatRegistrationRequest(user))
+			msg.ReplyMarkup = registrationReviewKeyboard(user.TelegramID)
+		} else {
+			msg = tgbotapi.NewMessage(admin.TelegramID, fmt.Sprintf("Пользователь @%s зарегистрировался в боте", user.Username))
+		}
+		t.Bot.Send(msg)
+	}
+}
+
+func registrationReviewKeyboard(telegramID int64) tgbotapi.InlineKeyboardMarkup {
+	id := strconv.FormatInt(telegramID, 10)
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Одобрить", registrationApproveCallback+":"+id),
+		tgbotapi.NewInlineKeyboardButtonData("Отклонить", registrationRejectCallback+":"+id),
+	))
+}
+
+func formatRegistrationRequest(user models.User) string {
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	text := fmt.Sprintf("%s (@%s), дата рождения %s", name, user.Username, user.Birthdate.Format("02.01.2006"))
+	if user.Team != "" {
+		text += fmt.Sprintf(", команда «%s»", user.Team)
+	}
+	return text
+}
+
+// handleRegistrationCallback одобряет или отклоняет заявку по нажатию кнопки администратором.
+func (t *Telegram) handleRegistrationCallback(ctx context.Context, update tgbotapi.Update, bot *tgbotapi.BotAPI, chatID int64, action, arg string) {
+	applicantID, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return
+	}
+
+	status := models.UserStatusActive
+	if action == registrationRejectCallback {
+		status = models.UserStatusRejected
+	}
+
+	reviewed, err := t.userService.ReviewRegistration(ctx, applicantID, status, chatID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при обработке заявки."))
+		return
+	}
+	t.clearInlineKeyboard(bot, update)
+	if !reviewed {
+		bot.Send(tgbotapi.NewMessage(chatID, "Заявка уже рассмотрена другим администратором."))
+		return
+	}
+
+	applicant, err := t.userService.GetUser(ctx, models.User{TelegramID: applicantID})
+	if err != nil {
+		log.Println("Error getting reviewed user:", err)
+	}
+
+	if status == models.UserStatusActive {
+		bot.Send(tgbotapi.NewMessage(applicantID, "Администратор подтвердил вашу регистрацию. Добро пожаловать! Список команд — /help"))
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Заявка @%s одобрена.", applicant.Username)))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(applicantID, "К сожалению, ваша заявка на регистрацию отклонена."))
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Заявка @%s отклонена.", applicant.Username)))
+}
+
+// handlePendingCommand показывает администратору заявки, ожидающие решения.
+func (t *Telegram) handlePendingCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) {
+	users, err := t.userService.GetPendingUsers(ctx)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении заявок."))
+		return
+	}
+	if len(users) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Нет заявок, ожидающих подтверждения."))
+		return
+	}
+
+	for _, user := range users {
+		msg := tgbotapi.NewMessage(chatID, formatRegistrationRequest(user))
+		msg.ReplyMarkup = registrationReviewKeyboard(user.TelegramID)
+		bot.Send(msg)
+	}
+}
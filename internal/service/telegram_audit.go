@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gift-bot/pkg/models"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	auditCallback = "audit"
+	auditPageSize = 10
+)
+
+// handleAuditCommand показывает журнал действий администраторов: /audit [действие] [@ник].
+func (t *Telegram) handleAuditCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, args []string) {
+	filter := models.AuditFilter{}
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "@") {
+			user, found, err := t.findUserByUsername(ctx, arg)
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении списка пользователей."))
+				return
+			}
+			if !found {
+				bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Пользователь %s не найден.", arg)))
+				return
+			}
+			filter.TelegramID = user.TelegramID
+			continue
+		}
+		filter.Action = strings.ToLower(arg)
+	}
+
+	text, keyboard, err := t.auditPage(ctx, filter, 0)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении журнала."))
+		return
+	}
+	msg := tgbotapi.NewMessage(chatID, text)
+	if keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+	bot.Send(msg)
+}
+
+// handleAuditCallback листает журнал. Данные кнопки: audit:<страница>:<действие>:<telegram_id>.
+func (t *Telegram) handleAuditCallback(ctx context.Context, update tgbotapi.Update, bot *tgbotapi.BotAPI, chatID int64, arg string) {
+	parts := strings.Split(arg, ":")
+	if len(parts) != 3 {
+		return
+	}
+	page, err := strconv.Atoi(parts[0])
+	if err != nil || page < 0 {
+		return
+	}
+	filter := models.AuditFilter{Action: parts[1]}
+	filter.TelegramID, _ = strconv.ParseInt(parts[2], 10, 64)
+
+	text, keyboard, err := t.auditPage(ctx, filter, page)
+	if err != nil {
+		log.Println("Error getting audit page:", err)
+		return
+	}
+	var edit tgbotapi.EditMessageTextConfig
+	if keyboard != nil {
+		edit = tgbotapi.NewEditMessageTextAndMarkup(chatID, update.CallbackQuery.Message.MessageID, text, *keyboard)
+	} else {
+		edit = tgbotapi.NewEditMessageText(chatID, update.CallbackQuery.Message.MessageID, text)
+	}
+	bot.Send(edit)
+}
+
+func (t *Telegram) auditPage(ctx context.Context, filter models.AuditFilter, page int) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	filter.Limit = auditPageSize
+	filter.Offset = page * auditPageSize
+	events, total, err := t.auditService.GetAuditEvents(ctx, filter)
+	if err != nil {
+		return "", nil, err
+	}
+	if total == 0 {
+		return "Записей в журнале нет.", nil, nil
+	}
+
+	names := t.auditUserNames(ctx)
+	pages := (total + auditPageSize - 1) / auditPageSize
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Журнал действий (страница %d из %d, всего %d):\n\n", page+1, pages, total)
+	for _, e := range events {
+		fmt.Fprintf(&b, "%s — %s — %s", e.CreatedAt.Format("02.01.2006 15:04"), auditUserName(names, e.ActorTelegramID), e.Action)
+		if e.TargetTelegramID != nil {
+			fmt.Fprintf(&b, " → %s", auditUserName(names, *e.TargetTelegramID))
+		}
+		if payload := string(e.Payload); payload != "" && payload != "{}" {
+			fmt.Fprintf(&b, "\n    %s", payload)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\nФильтры: /audit [действие] [@ник]")
+
+	var row []tgbotapi.InlineKeyboardButton
+	suffix := fmt.Sprintf("%s:%d", filter.Action, filter.TelegramID)
+	if page > 0 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("<<", fmt.Sprintf("%s:%d:%s", auditCallback, page-1, suffix)))
+	}
+	if page+1 < pages {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(">>", fmt.Sprintf("%s:%d:%s", auditCallback, page+1, suffix)))
+	}
+	if len(row) == 0 {
+		return b.String(), nil, nil
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(row)
+	return b.String(), &keyboard, nil
+}
+
+// auditUserNames сопоставляет Telegram ID с ником для всех известных пользователей, включая заблокированных.
+func (t *Telegram) auditUserNames(ctx context.Context) map[int64]string {
+	names := map[int64]string{}
+	active, err := t.userService.GetAllUsers(ctx)
+	if err != nil {
+		log.Println("Error getting users for audit:", err)
+	}
+	blocked, err := t.userService.GetBlockedUsers(ctx)
+	if err != nil {
+		log.Println("Error getting blocked users for audit:", err)
+	}
+	for _, u := range append(active, blocked...) {
+		names[u.TelegramID] = formatUserMention(u)
+	}
+	return names
+}
+
+func auditUserName(names map[int64]string, telegramID int64) string {
+	switch telegramID {
+	case 0:
+		return "удалённый пользователь"
+	case models.AuditActorAPI:
+		return "HTTP API"
+	}
+	if name, ok := names[telegramID]; ok {
+		return name
+	}
+	return fmt.Sprintf("id%d", telegramID)
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gift-bot/pkg/models"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	birthdaysPageCallback = "birthdays"
+	birthdaysPageSize     = 10
+)
+
+var monthNames = [...]string{"", "январе", "феврале", "марте", "апреле", "мае", "июне",
+	"июле", "августе", "сентябре", "октябре", "ноябре", "декабре"}
+
+func (t *Telegram) handleBirthdaysCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, args []string) {
+	user, err := t.userService.GetUser(ctx, models.User{TelegramID: chatID})
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Команда доступна только зарегистрированным пользователям. Введите /login."))
+		return
+	}
+
+	if len(args) > 0 && strings.EqualFold(args[0], "month") {
+		if !HasPermission(user, PermissionViewBirthdays) {
+			bot.Send(tgbotapi.NewMessage(chatID, "У вас нет прав для использования этой команды."))
+			return
+		}
+		t.sendMonthBirthdays(ctx, bot, chatID)
+		return
+	}
+
+	text, keyboard, err := t.birthdaysPage(ctx, 0)
+	if err != nil {
+		log.Println(err)
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении списка дней рождения."))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	if keyboard != nil {
+		msg.ReplyMarkup = *keyboard
+	}
+	bot.Send(msg)
+}
+
+func (t *Telegram) handleBirthdaysPageCallback(ctx context.Context, update tgbotapi.Update, bot *tgbotapi.BotAPI, chatID int64, arg string) {
+	page, err := strconv.Atoi(arg)
+	if err != nil {
+		return
+	}
+
+	if _, err := t.userService.GetUser(ctx, models.User{TelegramID: chatID}); err != nil {
+		return
+	}
+
+	text, keyboard, err := t.birthdaysPage(ctx, page)
+	if err != nil {
+		log.Println(err)
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении списка дней рождения."))
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageText(chatID, update.CallbackQuery.Message.MessageID, text)
+	edit.ReplyMarkup = keyboard
+	bot.Send(edit)
+}
+
+// birthdaysPage формирует страницу списка ближайших дней рождения на год вперёд. Год рождения не показывается.
+func (t *Telegram) birthdaysPage(ctx context.Context, page int) (string, *tgbotapi.InlineKeyboardMarkup, error) {
+	birthdays, err := t.occasionService.GetUpcomingBirthdays(ctx, time.Now(), 365)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(birthdays) == 0 {
+		return "Пока нет дней рождения, которые можно показать.", nil, nil
+	}
+
+	totalPages := (len(birthdays) + birthdaysPageSize - 1) / birthdaysPageSize
+	if page < 0 {
+		page = 0
+	}
+	if page >= totalPages {
+		page = totalPages - 1
+	}
+
+	start := page * birthdaysPageSize
+	end := start + birthdaysPageSize
+	if end > len(birthdays) {
+		end = len(birthdays)
+	}
+
+	var b strings.Builder
+	b.WriteString("Ближайшие дни рождения:\n\n")
+	for _, u := range birthdays[start:end] {
+		b.WriteString(formatBirthdayLine(u))
+	}
+
+	if totalPages == 1 {
+		return b.String(), nil, nil
+	}
+
+	var navRow []tgbotapi.InlineKeyboardButton
+	if page > 0 {
+		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData("<<", fmt.Sprintf("%s:%d", birthdaysPageCallback, page-1)))
+	}
+	navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("Стр. %d/%d", page+1, totalPages), "noop"))
+	if page < totalPages-1 {
+		navRow = append(navRow, tgbotapi.NewInlineKeyboardButtonData(">>", fmt.Sprintf("%s:%d", birthdaysPageCallback, page+1)))
+	}
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(navRow)
+	return b.String(), &keyboard, nil
+}
+
+func (t *Telegram) sendMonthBirthdays(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) {
+	now := time.Now()
+	firstDay := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	lastDay := firstDay.AddDate(0, 1, -1)
+
+	birthdays, err := t.occasionService.GetUpcomingBirthdays(ctx, firstDay, daysBetween(firstDay, lastDay))
+	if err != nil {
+		log.Println(err)
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении списка дней рождения."))
+		return
+	}
+
+	if len(birthdays) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("В %s дней рождения нет.", monthNames[now.Month()])))
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Дни рождения в %s:\n\n", monthNames[now.Month()])
+	for _, u := range birthdays {
+		fmt.Fprintf(&b, "%s — %s\n", u.NextDate.Format("02.01"), formatUserButtonText(u.User))
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, b.String()))
+}
+
+func formatBirthdayLine(u models.UpcomingOccasion) string {
+	line := fmt.Sprintf("%s — %s", u.NextDate.Format("02.01"), formatUserButtonText(u.User))
+	switch u.DaysLeft {
+	case 0:
+		return line + " (сегодня 🎉)\n"
+	case 1:
+		return line + " (завтра)\n"
+	default:
+		return line + fmt.Sprintf(" (через %d дн.)\n", u.DaysLeft)
+	}
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"gift-bot/pkg/models"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+// SendDueBroadcasts отправляет рассылки, время которых наступило, и дослает брошенные на середине.
+// Вызывается по расписанию и после создания рассылки через API.
+func (t *Telegram) SendDueBroadcasts(ctx context.Context) {
+	broadcasts, err := t.broadcastService.ClaimDueBroadcasts(ctx, time.Now())
+	if err != nil {
+		log.Errorf("claim due broadcasts err: %v", err)
+		return
+	}
+	for _, broadcast := range broadcasts {
+		stats, err := t.deliverBroadcast(ctx, broadcast)
+		if err != nil {
+			log.Errorf("broadcast %d not delivered: %v", broadcast.ID, err)
+			continue
+		}
+		log.Printf("Broadcast %d delivered: %d sent, %d failed", broadcast.ID, stats.Sent, stats.Failed)
+	}
+}
+
+// deliverBroadcast — общий конвейер для /message и API: определяет получателей, отправляет и записывает результат по каждому.
+// Возвращает итог по всей рассылке, включая доставки до перехвата.
+// Рассылка должна быть заранее переведена в статус sending через ClaimBroadcast или ClaimDueBroadcasts.
+func (t *Telegram) deliverBroadcast(ctx context.Context, broadcast models.Broadcast) (models.BroadcastStats, error) {
+	recipients, err := t.broadcastService.PrepareRecipients(ctx, broadcast)
+	if err != nil {
+		return models.BroadcastStats{}, err
+	}
+
+	stats := models.BroadcastStats{Total: len(recipients)}
+	claimed := time.Now()
+	for _, user := range recipients {
+		if time.Since(claimed) >= broadcastClaimRefresh {
+			if err := t.broadcastService.ExtendClaim(ctx, broadcast.ID); err != nil {
+				log.Errorf("broadcast %d claim not extended: %v", broadcast.ID, err)
+			}
+			claimed = time.Now()
+		}
+		log.Printf("Sending broadcast %d to user: %s", broadcast.ID, user.Username)
+		_, sendErr := t.Bot.Send(tgbotapi.NewMessage(user.TelegramID, RenderBroadcast(broadcast, user)))
+		if sendErr != nil {
+			log.Printf("Broadcast %d to %d failed: %v", broadcast.ID, user.TelegramID, sendErr)
+			stats.Failed++
+		} else {
+			stats.Sent++
+		}
+		if err := t.broadcastService.RecordDelivery(ctx, broadcast.ID, user.TelegramID, sendErr); err != nil {
+			log.Errorf("broadcast %d delivery to %d not recorded: %v", broadcast.ID, user.TelegramID, err)
+		}
+	}
+
+	if err := t.broadcastService.FinishBroadcast(ctx, broadcast.ID); err != nil {
+		return stats, err
+	}
+	// После перехвата брошенной рассылки здесь отправлен только остаток, поэтому итог берётся по всем доставкам
+	_, total, err := t.broadcastService.GetBroadcast(ctx, broadcast.ID)
+	if err != nil {
+		log.Errorf("broadcast %d stats not read: %v", broadcast.ID, err)
+		return stats, nil
+	}
+	return total, nil
+}
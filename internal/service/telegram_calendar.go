@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gift-bot/pkg/models"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	calendarCallback       = "calendar"
+	calendarRotateArgument = "rotate"
+)
+
+func (t *Telegram) handleCalendarCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) {
+	if _, err := t.userService.GetUser(ctx, models.User{TelegramID: chatID}); err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Команда доступна только зарегистрированным пользователям. Введите /login."))
+		return
+	}
+
+	exists, err := t.calendarService.HasCalendarToken(ctx, chatID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении ссылки на календарь."))
+		return
+	}
+
+	if !exists {
+		t.issueCalendarLink(ctx, bot, chatID)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, "У вас уже есть ссылка на календарь. Бот не хранит её в открытом виде, "+
+		"поэтому показать её повторно нельзя.\n\nМожно создать новую ссылку — старая сразу перестанет работать.")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Создать новую ссылку", calendarCallback+":"+calendarRotateArgument),
+	))
+	bot.Send(msg)
+}
+
+func (t *Telegram) handleCalendarCallback(ctx context.Context, update tgbotapi.Update, bot *tgbotapi.BotAPI, chatID int64, arg string) {
+	if arg != calendarRotateArgument {
+		return
+	}
+	if _, err := t.userService.GetUser(ctx, models.User{TelegramID: chatID}); err != nil {
+		return
+	}
+
+	t.clearInlineKeyboard(bot, update)
+	t.issueCalendarLink(ctx, bot, chatID)
+}
+
+func (t *Telegram) issueCalendarLink(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) {
+	token, err := t.calendarService.IssueCalendarToken(ctx, chatID)
+	if err != nil {
+		log.Println("Error issuing calendar token:", err)
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при создании ссылки на календарь."))
+		return
+	}
+
+	text := fmt.Sprintf("Ваша личная ссылка на календарь дней рождения и событий коллег:\n\n%s\n\n"+
+		"Добавьте её в приложении календаря как подписку по URL (Google Календарь: «Добавить календарь» → «По URL»). "+
+		"Не пересылайте ссылку другим. Если она попала в чужие руки, введите /calendar и создайте новую.",
+		t.calendarService.CalendarFeedURL(token))
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.DisableWebPagePreview = true
+	bot.Send(msg)
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	upcomingHolidaysDays  = 365
+	upcomingHolidaysLimit = 15
+)
+
+// SendHolidayGreetings рассылает всем активным пользователям поздравление с сегодняшним праздником.
+// Доставка учитывается по каждому пользователю, поэтому повторный запуск в тот же день не дублирует сообщения.
+// at — логическое время запуска: по нему выбирается дата праздника.
+func (t *Telegram) SendHolidayGreetings(ctx context.Context, at time.Time) {
+	now := at.In(time.Local)
+	greetDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	holidays, err := t.holidayService.GetUpcomingHolidays(ctx, greetDate, 0)
+	if err != nil {
+		log.Println("Error getting today's holidays:", err)
+		return
+	}
+
+	if len(holidays) == 0 {
+		return
+	}
+
+	users, err := t.userService.GetAllUsers(ctx)
+	if err != nil {
+		log.Println("Error getting users for holiday greetings:", err)
+		return
+	}
+
+	for _, upcoming := range holidays {
+		holiday := upcoming.Holiday
+		sentCount := 0
+		for _, user := range users {
+			// Пользователь отключил поздравления с праздниками в /profile
+			if !user.NotifyHolidays {
+				continue
+			}
+			sent, err := t.holidayService.HasHolidayDelivery(ctx, holiday.ID, user.TelegramID, greetDate)
+			if err != nil {
+				log.Println("Error checking holiday delivery:", err)
+				continue
+			}
+			if sent {
+				continue
+			}
+
+			msg := tgbotapi.NewMessage(user.TelegramID, RenderHolidayGreeting(holiday, user))
+			if _, err := t.Bot.Send(msg); err != nil {
+				log.Printf("Error sending %s greeting to %s: %v", holiday.Code, user.Username, err)
+				continue
+			}
+			sentCount++
+
+			if err := t.holidayService.SaveHolidayDelivery(ctx, holiday.ID, user.TelegramID, greetDate); err != nil {
+				log.Println("Error saving holiday delivery:", err)
+			}
+		}
+		log.Printf("Holiday %s: greetings sent to %d user(s)", holiday.Code, sentCount)
+	}
+}
+
+func (t *Telegram) handleHolidaysCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) {
+	upcoming, err := t.holidayService.GetUpcomingHolidays(ctx, time.Now(), upcomingHolidaysDays)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении списка праздников."))
+		return
+	}
+
+	if len(upcoming) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Праздники не настроены. Проверьте файл календаря праздников."))
+		return
+	}
+
+	if len(upcoming) > upcomingHolidaysLimit {
+		upcoming = upcoming[:upcomingHolidaysLimit]
+	}
+
+	var b strings.Builder
+	b.WriteString("Ближайшие праздники:\n\n")
+	for _, u := range upcoming {
+		fmt.Fprintf(&b, "%d. %s — %s", u.Holiday.ID, u.NextDate.Format("02.01.2006"), u.Holiday.Name)
+		if u.DaysLeft == 0 {
+			b.WriteString(" (сегодня)")
+		} else {
+			fmt.Fprintf(&b, " (через %d дн.)", u.DaysLeft)
+		}
+		if strings.TrimSpace(u.Holiday.GreetingOverride) != "" {
+			b.WriteString(" ✏️")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\n✏️ — текст поздравления изменён вручную. Посмотреть или изменить текст: /holiday_greeting ID [текст].")
+	bot.Send(tgbotapi.NewMessage(chatID, b.String()))
+}
+
+func (t *Telegram) handleHolidayGreetingCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, args []string, greeting string) {
+	if len(args) < 1 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Использование: /holiday_greeting ID [текст | сброс]\n"+
+			"Без текста — показать текущее поздравление. В тексте можно использовать {name} — имя получателя."))
+		return
+	}
+
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "ID праздника должен быть числом."))
+		return
+	}
+
+	holiday, err := t.holidayService.GetHoliday(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		bot.Send(tgbotapi.NewMessage(chatID, "Праздник не найден."))
+		return
+	}
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении праздника."))
+		return
+	}
+
+	switch {
+	case greeting == "":
+		text := fmt.Sprintf("«%s», поздравление из календаря:\n%s", holiday.Name, holiday.Greeting)
+		if strings.TrimSpace(holiday.GreetingOverride) != "" {
+			text += fmt.Sprintf("\n\nДействует изменённый текст:\n%s", holiday.GreetingOverride)
+		}
+		bot.Send(tgbotapi.NewMessage(chatID, text))
+		return
+	case strings.EqualFold(greeting, "сброс"):
+		greeting = ""
+	}
+
+	if err := t.holidayService.SetHolidayGreetingOverride(ctx, id, greeting); err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при сохранении поздравления."))
+		return
+	}
+
+	if greeting == "" {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Для «%s» восстановлено поздравление из календаря.", holiday.Name)))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Поздравление для «%s» обновлено.", holiday.Name)))
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gift-bot/pkg/models"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultInviteDays = 7
+	maxInviteDays     = 90
+	maxInviteUses     = 1000
+)
+
+// handleInviteStart начинает регистрацию по ссылке-приглашению t.me/<bot>?start=<code>.
+func (t *Telegram) handleInviteStart(ctx context.Context, update tgbotapi.Update, bot *tgbotapi.BotAPI, chatID int64, code string) {
+	existingUser, err := t.userService.GetUser(ctx, models.User{TelegramID: chatID})
+	if err == nil && existingUser.TelegramID != 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Вы уже зарегистрированы в боте."))
+		return
+	}
+
+	invite, err := t.inviteService.ValidateInviteCode(ctx, code)
+	switch {
+	case errors.Is(err, ErrInviteNotFound):
+		bot.Send(tgbotapi.NewMessage(chatID, "Приглашение не найдено. Проверьте ссылку или попросите новую у администратора."))
+		return
+	case errors.Is(err, ErrInviteExpired), errors.Is(err, ErrInviteExhausted):
+		bot.Send(tgbotapi.NewMessage(chatID, "Приглашение больше не действует. Попросите у администратора новую ссылку."))
+		return
+	case err != nil:
+		log.Println("Error validating invite code:", err)
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при проверке приглашения."))
+		return
+	}
+
+	t.adminMessageData[chatID] = &AdminMessageState{
+		User: models.User{
+			TelegramID: chatID,
+			Username:   update.Message.Chat.UserName,
+			FirstName:  update.Message.Chat.FirstName,
+			LastName:   update.Message.Chat.LastName,
+			Role:       models.RoleUser,
+			Team:       invite.Team,
+			CreatedAt:  time.Now(),
+			UpdatedAt:  time.Now(),
+		},
+		InviteCodeID: invite.ID,
+	}
+	t.loginState[chatID] = false
+	t.messageState[chatID] = waitingBirthdateState
+
+	bot.Send(tgbotapi.NewMessage(chatID, "Приглашение принято! Введите вашу дату рождения в формате ДД.ММ.ГГГГ:"))
+}
+
+func (t *Telegram) handleInviteCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, args []string) {
+	days, uses := defaultInviteDays, 1
+	var err error
+	if len(args) > 0 {
+		days, err = strconv.Atoi(args[0])
+		if err != nil || days < 1 || days > maxInviteDays {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Срок действия — число дней от 1 до %d.\n"+
+				"Использование: /invite [дни] [использований] [команда]", maxInviteDays)))
+			return
+		}
+	}
+	if len(args) > 1 {
+		uses, err = strconv.Atoi(args[1])
+		if err != nil || uses < 1 || uses > maxInviteUses {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Количество использований — число от 1 до %d.", maxInviteUses)))
+			return
+		}
+	}
+	team := ""
+	if len(args) > 2 {
+		team = strings.Join(args[2:], " ")
+	}
+
+	invite, err := t.inviteService.CreateInviteCode(ctx, chatID, time.Duration(days)*24*time.Hour, uses, team)
+	if err != nil {
+		log.Println("Error creating invite code:", err)
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при создании приглашения."))
+		return
+	}
+
+	t.auditService.Record(ctx, chatID, models.AuditActionInviteCreate, 0, map[string]interface{}{
+		"invite_id": invite.ID, "days": days, "max_uses": invite.MaxUses, "team": invite.Team,
+	})
+
+	text := fmt.Sprintf("Приглашение №%d создано.\nДействует до %s, использований: %d.",
+		invite.ID, invite.ExpiresAt.Format("02.01.2006 15:04"), invite.MaxUses)
+	if invite.Team != "" {
+		text += fmt.Sprintf("\nКоманда: «%s».", invite.Team)
+	}
+	text += "\n\nСсылка для регистрации:\n" + t.inviteLink(invite)
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.DisableWebPagePreview = true
+	bot.Send(msg)
+}
+
+func (t *Telegram) handleInvitesCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) {
+	invites, err := t.inviteService.GetActiveInviteCodes(ctx)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении списка приглашений."))
+		return
+	}
+
+	if len(invites) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Действующих приглашений нет. Создать: /invite"))
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("Действующие приглашения:\n\n")
+	for _, invite := range invites {
+		fmt.Fprintf(&b, "%d. до %s, использовано %d из %d", invite.ID, invite.ExpiresAt.Format("02.01.2006 15:04"), invite.Uses, invite.MaxUses)
+		if invite.Team != "" {
+			fmt.Fprintf(&b, ", команда «%s»", invite.Team)
+		}
+		fmt.Fprintf(&b, "\n%s\n\n", t.inviteLink(invite))
+	}
+	b.WriteString("Отозвать приглашение: /invite_revoke ID")
+
+	msg := tgbotapi.NewMessage(chatID, b.String())
+	msg.DisableWebPagePreview = true
+	bot.Send(msg)
+}
+
+func (t *Telegram) handleInviteRevokeCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, args []string) {
+	if len(args) != 1 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Использование: /invite_revoke ID"))
+		return
+	}
+
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "ID приглашения должен быть числом."))
+		return
+	}
+
+	if err := t.inviteService.RevokeInviteCode(ctx, id); err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при отзыве приглашения."))
+		return
+	}
+	t.auditService.Record(ctx, chatID, models.AuditActionInviteRevoke, 0, map[string]interface{}{"invite_id": id})
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Приглашение №%d отозвано.", id)))
+}
+
+func (t *Telegram) inviteLink(invite models.InviteCode) string {
+	return fmt.Sprintf("https://t.me/%s?start=%s", t.Bot.Self.UserName, invite.Code)
+}
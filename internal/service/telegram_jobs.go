@@ -0,0 +1,99 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gift-bot/pkg/models"
+	"gift-bot/pkg/scheduler"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// handleJobsCommand показывает периодические задачи: расписание, ближайший и последний запуск.
+func (t *Telegram) handleJobsCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) {
+	jobs, err := t.jobService.ListJobs(ctx)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении списка задач."))
+		return
+	}
+	if len(jobs) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Периодических задач нет."))
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("Периодические задачи:\n\n")
+	for _, job := range jobs {
+		fmt.Fprintf(&b, "%s — %s\n", job.Name, job.Spec)
+		if job.Running {
+			b.WriteString("    выполняется сейчас\n")
+		}
+		fmt.Fprintf(&b, "    следующий запуск: %s\n", job.Next.Format("02.01.2006 15:04"))
+		if job.LastRun != nil {
+			fmt.Fprintf(&b, "    последний: %s\n", formatJobRun(*job.LastRun))
+		} else {
+			b.WriteString("    ещё не запускалась\n")
+		}
+	}
+	b.WriteString("\nЗапустить вручную: /job_run имя")
+	bot.Send(tgbotapi.NewMessage(chatID, b.String()))
+}
+
+// handleJobRunCommand запускает задачу вне расписания и сообщает результат, когда она закончится.
+func (t *Telegram) handleJobRunCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, args []string) {
+	if len(args) != 1 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Использование: /job_run имя\n\nСписок задач: /jobs"))
+		return
+	}
+
+	name := args[0]
+	done, err := t.jobService.TriggerJob(name)
+	switch {
+	case errors.Is(err, scheduler.ErrUnknownJob):
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Задача «%s» не найдена. Список задач: /jobs", name)))
+		return
+	case errors.Is(err, scheduler.ErrJobRunning):
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Задача %s уже выполняется.", name)))
+		return
+	case err != nil:
+		bot.Send(tgbotapi.NewMessage(chatID, "Не удалось запустить задачу: бот останавливается."))
+		return
+	}
+	t.auditService.Record(ctx, chatID, models.AuditActionJobRun, 0, map[string]interface{}{"job": name})
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Задача %s запущена. Сообщу, когда она закончится.", name)))
+
+	started := time.Now()
+	go func() {
+		if err := <-done; err != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Задача %s завершилась с ошибкой: %v", name, err)))
+			return
+		}
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Задача %s выполнена за %s.", name, time.Since(started).Round(time.Second))))
+	}()
+}
+
+func formatJobRun(run models.JobRun) string {
+	text := run.StartedAt.Format("02.01.2006 15:04")
+	switch run.Trigger {
+	case models.JobTriggerManual:
+		text += " (вручную)"
+	case models.JobTriggerCatchUp:
+		text += fmt.Sprintf(" (догоняющий за %s)", run.ScheduledFor.Format("02.01 15:04"))
+	}
+	switch run.Status {
+	case models.JobRunStatusSuccess:
+		text += ", успешно"
+	case models.JobRunStatusFailure:
+		text += ", ошибка: " + run.Error
+	default:
+		// Запуск без finished_at после перезапуска бота — он был прерван
+		text += ", не завершён"
+	}
+	if run.FinishedAt != nil {
+		text += fmt.Sprintf(" за %s", run.FinishedAt.Sub(run.StartedAt).Round(time.Second))
+	}
+	return text
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gift-bot/pkg/models"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+// loginLocked сообщает пользователю о действующей блокировке входа по секретному слову.
+func (t *Telegram) loginLocked(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) bool {
+	until, locked, err := t.loginAttemptService.GetLoginLock(ctx, chatID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при проверке попыток входа. Попробуйте позже."))
+		return true
+	}
+	if !locked {
+		return false
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Слишком много неудачных попыток ввода секретного слова. "+
+		"Попробуйте снова после %s или обратитесь к администратору.", until.Format("15:04 02.01.2006"))))
+	return true
+}
+
+func (t *Telegram) notifyAdminsAboutLockout(ctx context.Context, attempt models.LoginAttempt) {
+	who := fmt.Sprintf("ID %d", attempt.TelegramID)
+	if attempt.Username != "" {
+		who = fmt.Sprintf("@%s (ID %d)", attempt.Username, attempt.TelegramID)
+	}
+	t.notifyAdmins(ctx, fmt.Sprintf("%s ввёл неверное секретное слово %d раза подряд. Вход заблокирован до %s (блокировка №%d).\n"+
+		"Снять блокировку: /login_unlock %d",
+		who, maxLoginAttempts, attempt.LockedUntil.Format("15:04 02.01.2006"), attempt.Lockouts, attempt.TelegramID))
+}
+
+func (t *Telegram) handleLockoutsCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) {
+	attempts, err := t.loginAttemptService.GetLockedLogins(ctx)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении блокировок."))
+		return
+	}
+	if len(attempts) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Заблокированных попыток входа нет."))
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("Заблокированные попытки входа:\n\n")
+	for _, a := range attempts {
+		username := "без ника"
+		if a.Username != "" {
+			username = "@" + a.Username
+		}
+		fmt.Fprintf(&b, "ID %d — %s — до %s, блокировка №%d\n", a.TelegramID, username, a.LockedUntil.Format("15:04 02.01.2006"), a.Lockouts)
+	}
+	b.WriteString("\nСнять блокировку: /login_unlock ID")
+	bot.Send(tgbotapi.NewMessage(chatID, b.String()))
+}
+
+func (t *Telegram) handleLoginUnlockCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, args []string) {
+	if len(args) != 1 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Использование: /login_unlock ID\nСписок блокировок — /lockouts"))
+		return
+	}
+	telegramID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "ID должен быть числом. Список блокировок — /lockouts"))
+		return
+	}
+
+	unlocked, err := t.loginAttemptService.UnlockLogin(ctx, telegramID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при снятии блокировки."))
+		return
+	}
+	if !unlocked {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Для ID %d нет неудачных попыток входа.", telegramID)))
+		return
+	}
+
+	t.auditService.Record(ctx, chatID, models.AuditActionLoginUnlock, telegramID, nil)
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Блокировка входа для ID %d снята.", telegramID)))
+	if _, err := bot.Send(tgbotapi.NewMessage(telegramID, "Администратор снял блокировку входа. Можно снова ввести /login.")); err != nil {
+		log.Printf("Error notifying %d about unlock: %v", telegramID, err)
+	}
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"gift-bot/pkg/metrics"
+	"net/http"
+	"path"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// publicCommands — команды без отдельного права. Вместе с commandPermissions это все команды бота;
+// прочие слова со слешем попадают в метрики как unknown, чтобы пользователи не плодили серии.
+var publicCommands = map[string]bool{
+	"/start": true, "/chat": true, "/login": true, "/help": true, "/birthdays": true, "/profile": true,
+	"/export_my_data": true, "/delete_me": true, "/calendar": true, "/santa": true,
+}
+
+func updateTypeLabel(update tgbotapi.Update) string {
+	switch {
+	case update.Message != nil:
+		return "message"
+	case update.CallbackQuery != nil:
+		return "callback"
+	}
+	return "other"
+}
+
+func commandLabel(update tgbotapi.Update) string {
+	if update.Message == nil {
+		return ""
+	}
+	command, _ := parseCommand(update.Message.Text)
+	if !strings.HasPrefix(command, "/") {
+		return ""
+	}
+	if _, ok := commandPermissions[command]; ok || publicCommands[command] {
+		return command
+	}
+	return "unknown"
+}
+
+// sendMetricsTransport считает отправленные и неотправленные сообщения по ответам Bot API.
+// Bot API возвращает HTTP-статус, совпадающий с error_code, поэтому тело ответа читать не нужно.
+type sendMetricsTransport struct {
+	next http.RoundTripper
+}
+
+func (s sendMetricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := path.Base(req.URL.Path)
+	resp, err := s.next.RoundTrip(req)
+	if !strings.HasPrefix(method, "send") {
+		return resp, err
+	}
+	switch {
+	case err != nil:
+		metrics.MessagesFailed.WithLabelValues(method, "network").Inc()
+	case resp.StatusCode == http.StatusOK:
+		metrics.MessagesSent.WithLabelValues(method).Inc()
+	default:
+		metrics.MessagesFailed.WithLabelValues(method, metrics.SendErrorClass(resp.StatusCode)).Inc()
+	}
+	return resp, err
+}
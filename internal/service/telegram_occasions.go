@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gift-bot/pkg/models"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const upcomingOccasionsDays = 30
+
+func (t *Telegram) handleOccasionsCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, args []string) {
+	if len(args) > 0 {
+		t.sendUserOccasions(ctx, bot, chatID, args[0])
+		return
+	}
+
+	upcoming, err := t.occasionService.GetUpcomingOccasions(ctx, time.Now(), upcomingOccasionsDays)
+	if err != nil {
+		log.Println(err)
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении списка событий."))
+		return
+	}
+
+	if len(upcoming) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("В ближайшие %d дней событий нет.", upcomingOccasionsDays)))
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "События на ближайшие %d дней:\n\n", upcomingOccasionsDays)
+	for _, u := range upcoming {
+		fmt.Fprintf(&b, "%s — %s — %s", u.NextDate.Format("02.01"), formatUserMention(u.User), formatOccasionTitle(u.Type, u.Occasion))
+		if u.DaysLeft == 0 {
+			b.WriteString(" (сегодня)\n")
+		} else {
+			fmt.Fprintf(&b, " (через %d дн.)\n", u.DaysLeft)
+		}
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, b.String()))
+}
+
+func (t *Telegram) sendUserOccasions(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, username string) {
+	target, found, err := t.findUserByUsername(ctx, username)
+	if err != nil {
+		log.Println(err)
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении списка пользователей."))
+		return
+	}
+	if !found {
+		bot.Send(tgbotapi.NewMessage(chatID, "Пользователь не найден."))
+		return
+	}
+
+	occasions, err := t.occasionService.GetUserOccasions(ctx, target.TelegramID)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении списка событий."))
+		return
+	}
+
+	types, err := t.occasionTypesByCode(ctx)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении типов событий."))
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "События %s:\n\n", formatUserMention(target))
+	if !target.Birthdate.IsZero() {
+		fmt.Fprintf(&b, "— %s — %s\n", target.Birthdate.Format("02.01"), types[models.OccasionTypeBirthday].Title)
+	}
+	for _, o := range occasions {
+		fmt.Fprintf(&b, "%d. %s — %s\n", o.ID, o.Date.Format("02.01.2006"), formatOccasionTitle(types[o.TypeCode], o))
+	}
+	if target.Birthdate.IsZero() && len(occasions) == 0 {
+		b.WriteString("Событий нет.")
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, b.String()))
+}
+
+func (t *Telegram) handleOccasionAddCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, args []string) {
+	if len(args) < 3 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Использование: /occasion_add @ник тип ДД.ММ.ГГГГ [название]\n"+
+			"Список типов — /occasion_types. Для custom и one_off название обязательно."))
+		return
+	}
+
+	types, err := t.occasionTypesByCode(ctx)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении типов событий."))
+		return
+	}
+
+	typeCode := strings.ToLower(args[1])
+	occasionType, ok := types[typeCode]
+	if !ok {
+		bot.Send(tgbotapi.NewMessage(chatID, "Неизвестный тип события. Список типов — /occasion_types."))
+		return
+	}
+	if typeCode == models.OccasionTypeBirthday {
+		bot.Send(tgbotapi.NewMessage(chatID, "День рождения хранится в профиле пользователя и указывается при регистрации."))
+		return
+	}
+
+	date, err := parseDate(args[2])
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Неверный формат даты. Введите дату в формате ДД.ММ.ГГГГ."))
+		return
+	}
+
+	title := strings.TrimSpace(strings.Join(args[3:], " "))
+	if title == "" && (typeCode == "custom" || typeCode == "one_off") {
+		bot.Send(tgbotapi.NewMessage(chatID, "Для этого типа события укажите название."))
+		return
+	}
+
+	target, found, err := t.findUserByUsername(ctx, args[0])
+	if err != nil {
+		log.Println(err)
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении списка пользователей."))
+		return
+	}
+	if !found {
+		bot.Send(tgbotapi.NewMessage(chatID, "Пользователь не найден."))
+		return
+	}
+
+	id, err := t.occasionService.CreateOccasion(ctx, models.Occasion{
+		UserTelegramID: target.TelegramID,
+		TypeCode:       typeCode,
+		Title:          title,
+		Date:           date,
+		CreatedBy:      chatID,
+	})
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при сохранении события."))
+		return
+	}
+
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Событие №%d добавлено: %s — %s, %s.",
+		id, formatUserMention(target), formatOccasionTitle(occasionType, models.Occasion{Title: title}), date.Format("02.01.2006"))))
+}
+
+func (t *Telegram) handleOccasionDeleteCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, args []string) {
+	if len(args) != 1 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Использование: /occasion_delete ID. ID можно узнать через /occasions @ник."))
+		return
+	}
+
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "ID события должен быть числом."))
+		return
+	}
+
+	if err := t.occasionService.DeleteOccasion(ctx, id); err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при удалении события."))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Событие №%d удалено.", id)))
+}
+
+func (t *Telegram) handleOccasionTypesCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) {
+	types, err := t.occasionService.GetOccasionTypes(ctx)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении типов событий."))
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString("Типы событий:\n\n")
+	for _, ot := range types {
+		repeat := "ежегодно"
+		if !ot.Recurring {
+			repeat = "разово"
+		}
+		fmt.Fprintf(&b, "%s — %s (%s), напоминание за %d дн.:\n%s\n\n", ot.Code, ot.Title, repeat, ot.LeadDays, ot.ReminderTemplate)
+	}
+	b.WriteString("Плейсхолдеры шаблона: {user}, {name}, {title}, {date}, {days}, {years}.")
+	bot.Send(tgbotapi.NewMessage(chatID, b.String()))
+}
+
+func (t *Telegram) handleOccasionTypeCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, args []string) {
+	if len(args) < 2 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Использование: /occasion_type код дни [шаблон]"))
+		return
+	}
+
+	types, err := t.occasionTypesByCode(ctx)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении типов событий."))
+		return
+	}
+
+	occasionType, ok := types[strings.ToLower(args[0])]
+	if !ok {
+		bot.Send(tgbotapi.NewMessage(chatID, "Неизвестный тип события. Список типов — /occasion_types."))
+		return
+	}
+
+	leadDays, err := strconv.Atoi(args[1])
+	if err != nil || leadDays < 0 || leadDays > 365 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Количество дней должно быть числом от 0 до 365."))
+		return
+	}
+
+	occasionType.LeadDays = leadDays
+	if template := strings.TrimSpace(strings.Join(args[2:], " ")); template != "" {
+		occasionType.ReminderTemplate = template
+	}
+
+	if err := t.occasionService.UpdateOccasionType(ctx, occasionType); err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при сохранении типа события."))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Тип «%s»: напоминание за %d дн.\n%s",
+		occasionType.Title, occasionType.LeadDays, occasionType.ReminderTemplate)))
+}
+
+func (t *Telegram) occasionTypesByCode(ctx context.Context) (map[string]models.OccasionType, error) {
+	types, err := t.occasionService.GetOccasionTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byCode := make(map[string]models.OccasionType, len(types))
+	for _, ot := range types {
+		byCode[ot.Code] = ot
+	}
+	return byCode, nil
+}
+
+func formatOccasionTitle(occasionType models.OccasionType, occasion models.Occasion) string {
+	title := strings.TrimSpace(occasion.Title)
+	if title == "" || title == occasionType.Title {
+		return occasionType.Title
+	}
+	return fmt.Sprintf("%s: %s", occasionType.Title, title)
+}
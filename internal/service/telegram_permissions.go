@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gift-bot/pkg/models"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+// commandPermissions — права, которые нужны для команд. Команды, которых здесь нет, доступны всем.
+// Проверка выполняется один раз в handleCommand, а не в каждом обработчике.
+var commandPermissions = map[string]Permission{
+	"/message":          PermissionBroadcast,
+	"/block":            PermissionBlock,
+	"/unblock":          PermissionBlock,
+	"/lockouts":         PermissionBlock,
+	"/login_unlock":     PermissionBlock,
+	"/admin_add":        PermissionManageRoles,
+	"/admin_remove":     PermissionManageRoles,
+	"/role":             PermissionManageRoles,
+	"/list":             PermissionManageUsers,
+	"/set_team":         PermissionManageUsers,
+	"/pending":          PermissionManageUsers,
+	"/export_user":      PermissionManageUsers,
+	"/offboard":         PermissionManageUsers,
+	"/invite":           PermissionManageInvites,
+	"/invites":          PermissionManageInvites,
+	"/invite_revoke":    PermissionManageInvites,
+	"/occasions":        PermissionManageEvents,
+	"/occasion_add":     PermissionManageEvents,
+	"/occasion_delete":  PermissionManageEvents,
+	"/occasion_types":   PermissionManageEvents,
+	"/occasion_type":    PermissionManageEvents,
+	"/holidays":         PermissionManageEvents,
+	"/holiday_greeting": PermissionManageEvents,
+	"/santa_open":       PermissionManageEvents,
+	"/santa_exclude":    PermissionManageEvents,
+	"/santa_draw":       PermissionManageEvents,
+	"/santa_audit":      PermissionManageEvents,
+	"/santa_close":      PermissionManageEvents,
+	"/audit":            PermissionViewAudit,
+	"/api_keys":         PermissionManageAPIKeys,
+	"/api_key_create":   PermissionManageAPIKeys,
+	"/api_key_revoke":   PermissionManageAPIKeys,
+	"/jobs":             PermissionManageJobs,
+	"/job_run":          PermissionManageJobs,
+}
+
+// callbackPermissions — то же для inline-кнопок, обрабатываемых в handleCallback.
+var callbackPermissions = map[string]Permission{
+	registrationApproveCallback: PermissionManageUsers,
+	registrationRejectCallback:  PermissionManageUsers,
+	offboardCallback:            PermissionManageUsers,
+	auditCallback:               PermissionViewAudit,
+}
+
+// authorize проверяет, что у пользователя есть право, и сообщает ему об ошибке, если нет.
+func (t *Telegram) authorize(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, permission Permission) (models.User, bool) {
+	user, err := t.userService.GetUser(ctx, models.User{TelegramID: chatID})
+	if err != nil {
+		log.Println(err)
+		msg := tgbotapi.NewMessage(chatID, "Ошибка при получении данных пользователя.")
+		bot.Send(msg)
+		return models.User{}, false
+	}
+
+	if !HasPermission(user, permission) {
+		msg := tgbotapi.NewMessage(chatID, "У вас нет прав для использования этой команды.")
+		bot.Send(msg)
+		return models.User{}, false
+	}
+	return user, true
+}
+
+func (t *Telegram) authorizeCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, command string) bool {
+	permission, ok := commandPermissions[command]
+	if !ok {
+		return true
+	}
+	_, ok = t.authorize(ctx, bot, chatID, permission)
+	return ok
+}
+
+func (t *Telegram) authorizeCallback(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, action string) bool {
+	permission, ok := callbackPermissions[action]
+	if !ok {
+		return true
+	}
+	_, ok = t.authorize(ctx, bot, chatID, permission)
+	return ok
+}
+
+// handleRoleCommand назначает роль: /role @ник admin|organiser|user. Роль владельца не выдаётся и не снимается.
+func (t *Telegram) handleRoleCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, args []string) {
+	if len(args) != 2 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Использование: /role @ник роль\n\nРоли:\n"+
+			"admin — администратор, все права\n"+
+			"organiser — организатор: события, праздники, Тайный Санта и напоминания\n"+
+			"user — обычный пользователь"))
+		return
+	}
+
+	role := strings.ToLower(strings.TrimSpace(args[1]))
+	if !IsAssignableRole(role) {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Неизвестная роль «%s». Доступны: %s.", args[1], strings.Join(assignableRoles, ", "))))
+		return
+	}
+
+	target, found, err := t.findUserByUsername(ctx, args[0])
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении списка пользователей."))
+		return
+	}
+	if !found {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Пользователь %s не найден.", args[0])))
+		return
+	}
+	if target.Role == models.RoleOwner {
+		bot.Send(tgbotapi.NewMessage(chatID, "Роль владельца бота изменить нельзя."))
+		return
+	}
+
+	changed, err := t.userService.SetUserRole(ctx, target.TelegramID, role)
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при изменении роли."))
+		return
+	}
+	if !changed {
+		bot.Send(tgbotapi.NewMessage(chatID, "Роль владельца бота изменить нельзя."))
+		return
+	}
+
+	t.auditService.Record(ctx, chatID, models.AuditActionRoleChange, target.TelegramID, map[string]interface{}{
+		"from": target.Role, "to": role,
+	})
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("%s теперь %s.", formatUserMention(target), RoleTitle(role))))
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gift-bot/pkg/models"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	deleteMeCallback       = "delete_me"
+	offboardCallback       = "offboard"
+	confirmArgument        = "confirm"
+	cancelArgument         = "cancel"
+	dataExportFileTemplate = "gift-bot-data-%d.json"
+)
+
+func (t *Telegram) handleExportMyDataCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) {
+	if _, err := t.userService.GetUser(ctx, models.User{TelegramID: chatID}); err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Команда доступна только зарегистрированным пользователям."))
+		return
+	}
+	t.sendDataExport(ctx, bot, chatID, chatID, "Все данные, которые бот хранит о вас.")
+}
+
+// sendDataExport отправляет в чат chatID JSON-файл с данными пользователя telegramID.
+func (t *Telegram) sendDataExport(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, telegramID int64, caption string) {
+	data, err := t.privacyService.ExportUserData(ctx, telegramID)
+	if err != nil {
+		log.Println("Error exporting user data:", err)
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при выгрузке данных."))
+		return
+	}
+
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{
+		Name:  fmt.Sprintf(dataExportFileTemplate, telegramID),
+		Bytes: data,
+	})
+	doc.Caption = caption
+	if _, err := bot.Send(doc); err != nil {
+		log.Printf("Error sending data export to %d: %v", chatID, err)
+	}
+}
+
+func (t *Telegram) handleDeleteMeCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) {
+	user, err := t.userService.GetUser(ctx, models.User{TelegramID: chatID})
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Команда доступна только зарегистрированным пользователям."))
+		return
+	}
+	if user.Role == models.RoleOwner {
+		bot.Send(tgbotapi.NewMessage(chatID, "Владелец бота не может удалить свой аккаунт."))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, "Удалить ваш аккаунт и все данные о вас: дату рождения, события, участие в Тайном Санте и историю уведомлений? "+
+		"Восстановить их будет нельзя. Чтобы сохранить копию, сначала выполните /export_my_data.")
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Удалить навсегда", deleteMeCallback+":"+confirmArgument),
+		tgbotapi.NewInlineKeyboardButtonData("Отмена", deleteMeCallback+":"+cancelArgument),
+	))
+	bot.Send(msg)
+}
+
+func (t *Telegram) handleDeleteMeCallback(ctx context.Context, update tgbotapi.Update, bot *tgbotapi.BotAPI, chatID int64, arg string) {
+	t.clearInlineKeyboard(bot, update)
+	if arg != confirmArgument {
+		bot.Send(tgbotapi.NewMessage(chatID, "Удаление отменено."))
+		return
+	}
+
+	user, err := t.userService.GetUser(ctx, models.User{TelegramID: chatID})
+	if err != nil {
+		return
+	}
+	if err := t.privacyService.DeleteUserData(ctx, chatID); errors.Is(err, ErrOwnerProtected) {
+		bot.Send(tgbotapi.NewMessage(chatID, "Владелец бота не может удалить свой аккаунт."))
+		return
+	} else if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при удалении данных. Попробуйте позже или обратитесь к администратору."))
+		return
+	}
+	t.forgetChat(chatID)
+
+	bot.Send(tgbotapi.NewMessage(chatID, "Ваш аккаунт и все данные удалены. Чтобы снова пользоваться ботом, понадобится новое приглашение."))
+	t.notifyAdmins(ctx, fmt.Sprintf("Пользователь %s удалил свой аккаунт и данные.", formatUserMention(user)))
+}
+
+// handleExportUserCommand выгружает администратору данные сотрудника, например перед увольнением.
+func (t *Telegram) handleExportUserCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, args []string) {
+	target, ok := t.resolveOffboardingTarget(ctx, bot, chatID, args, "/export_user @ник")
+	if !ok {
+		return
+	}
+	t.sendDataExport(ctx, bot, chatID, target.TelegramID, fmt.Sprintf("Данные пользователя %s.", formatUserMention(target)))
+}
+
+func (t *Telegram) handleOffboardCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, args []string) {
+	target, ok := t.resolveOffboardingTarget(ctx, bot, chatID, args, "/offboard @ник")
+	if !ok {
+		return
+	}
+	if target.TelegramID == chatID {
+		bot.Send(tgbotapi.NewMessage(chatID, "Чтобы удалить свой аккаунт, используйте /delete_me."))
+		return
+	}
+	if target.Role == models.RoleOwner {
+		bot.Send(tgbotapi.NewMessage(chatID, "Владельца бота удалить нельзя."))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Удалить пользователя %s и все данные о нём? Восстановить их будет нельзя. "+
+		"Копию можно получить командой /export_user.", formatUserButtonText(target)))
+	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Удалить навсегда", offboardCallback+":"+strconv.FormatInt(target.TelegramID, 10)),
+		tgbotapi.NewInlineKeyboardButtonData("Отмена", offboardCallback+":"+cancelArgument),
+	))
+	bot.Send(msg)
+}
+
+func (t *Telegram) handleOffboardCallback(ctx context.Context, update tgbotapi.Update, bot *tgbotapi.BotAPI, chatID int64, arg string) {
+	t.clearInlineKeyboard(bot, update)
+	if arg == cancelArgument {
+		bot.Send(tgbotapi.NewMessage(chatID, "Удаление отменено."))
+		return
+	}
+
+	targetID, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return
+	}
+	target, err := t.userService.GetUser(ctx, models.User{TelegramID: targetID})
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Пользователь уже удалён."))
+		return
+	}
+	if err := t.privacyService.DeleteUserData(ctx, targetID); errors.Is(err, ErrOwnerProtected) {
+		bot.Send(tgbotapi.NewMessage(chatID, "Владельца бота удалить нельзя."))
+		return
+	} else if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при удалении данных пользователя."))
+		return
+	}
+	t.forgetChat(targetID)
+	// Данные удалённого пользователя в журнал не пишем: фиксируем только сам факт удаления
+	t.auditService.Record(ctx, chatID, models.AuditActionOffboard, 0, nil)
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Пользователь %s и его данные удалены.", formatUserMention(target))))
+}
+
+// resolveOffboardingTarget ищет пользователя по нику среди активных и заблокированных.
+func (t *Telegram) resolveOffboardingTarget(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, args []string, usage string) (models.User, bool) {
+	if len(args) != 1 {
+		bot.Send(tgbotapi.NewMessage(chatID, "Использование: "+usage))
+		return models.User{}, false
+	}
+
+	username := strings.TrimPrefix(strings.TrimSpace(args[0]), "@")
+	target, found, err := t.findUserByUsername(ctx, username)
+	if err == nil && !found {
+		var blocked []models.User
+		blocked, err = t.userService.GetBlockedUsers(ctx)
+		for _, u := range blocked {
+			if strings.EqualFold(u.Username, username) {
+				target, found = u, true
+				break
+			}
+		}
+	}
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении списка пользователей."))
+		return models.User{}, false
+	}
+	if !found {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Пользователь @%s не найден.", username)))
+		return models.User{}, false
+	}
+	return target, true
+}
+
+// forgetChat сбрасывает незавершённые диалоги удалённого пользователя.
+func (t *Telegram) forgetChat(chatID int64) {
+	delete(t.messageState, chatID)
+	delete(t.adminMessageData, chatID)
+	delete(t.loginState, chatID)
+}
+
+func (t *Telegram) notifyAdmins(ctx context.Context, text string) {
+	admins, err := t.userService.GetAllAdmins(ctx)
+	if err != nil {
+		log.Println("Error getting all admins:", err)
+		return
+	}
+	for _, admin := range admins {
+		t.Bot.Send(tgbotapi.NewMessage(admin.TelegramID, text))
+	}
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gift-bot/pkg/models"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	profileCallback                = "profile"
+	waitingProfileBirthdateState   = "waiting_profile_birthdate"
+	waitingProfileNameState        = "waiting_profile_name"
+	profileBirthdateArgument       = "birthdate"
+	profileNameArgument            = "name"
+	profileHideBirthdayArgument    = "hide"
+	profileNotifyHolidaysArgument  = "holidays"
+	profileNotifyRemindersArgument = "reminders"
+)
+
+func (t *Telegram) handleProfileCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) {
+	user, err := t.userService.GetUser(ctx, models.User{TelegramID: chatID})
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Команда доступна только зарегистрированным пользователям. Введите /login."))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, formatProfile(user))
+	msg.ReplyMarkup = profileKeyboard(user)
+	bot.Send(msg)
+}
+
+// handleProfileCallback переключает настройки профиля или начинает ввод нового значения.
+func (t *Telegram) handleProfileCallback(ctx context.Context, update tgbotapi.Update, bot *tgbotapi.BotAPI, chatID int64, arg string) {
+	user, err := t.userService.GetUser(ctx, models.User{TelegramID: chatID})
+	if err != nil {
+		return
+	}
+
+	switch arg {
+	case profileBirthdateArgument:
+		t.messageState[chatID] = waitingProfileBirthdateState
+		bot.Send(tgbotapi.NewMessage(chatID, "Введите дату рождения в формате ДД.ММ.ГГГГ или «отмена»:"))
+		return
+	case profileNameArgument:
+		t.messageState[chatID] = waitingProfileNameState
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Введите имя, которое будут видеть коллеги (до %d символов). "+
+			"Отправьте «-», чтобы использовать имя из Telegram, или «отмена»:", maxDisplayNameLength)))
+		return
+	case profileHideBirthdayArgument:
+		user.HideBirthday = !user.HideBirthday
+	case profileNotifyHolidaysArgument:
+		user.NotifyHolidays = !user.NotifyHolidays
+	case profileNotifyRemindersArgument:
+		user.NotifyReminders = !user.NotifyReminders
+	default:
+		return
+	}
+
+	if err := t.userService.UpdateUserProfile(ctx, user); err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при сохранении профиля."))
+		return
+	}
+
+	edit := tgbotapi.NewEditMessageTextAndMarkup(chatID, update.CallbackQuery.Message.MessageID, formatProfile(user), profileKeyboard(user))
+	if _, err := bot.Send(edit); err != nil {
+		log.Printf("Error updating profile message: %v", err)
+	}
+}
+
+// handleProfileState принимает новую дату рождения или отображаемое имя после нажатия кнопки в /profile.
+func (t *Telegram) handleProfileState(ctx context.Context, update tgbotapi.Update, bot *tgbotapi.BotAPI, chatID int64, text string) bool {
+	state := t.messageState[chatID]
+	if update.Message == nil || (state != waitingProfileBirthdateState && state != waitingProfileNameState) {
+		return false
+	}
+
+	if strings.EqualFold(strings.TrimSpace(text), "отмена") {
+		t.messageState[chatID] = ""
+		bot.Send(tgbotapi.NewMessage(chatID, "Изменение профиля отменено."))
+		return true
+	}
+
+	user, err := t.userService.GetUser(ctx, models.User{TelegramID: chatID})
+	if err != nil {
+		t.messageState[chatID] = ""
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при получении данных пользователя."))
+		return true
+	}
+
+	switch state {
+	case waitingProfileBirthdateState:
+		birthdate, err := parseDate(text)
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, "Неверный формат даты. Пожалуйста, введите дату в формате ДД.ММ.ГГГГ:"))
+			return true
+		}
+		user.Birthdate = birthdate
+	case waitingProfileNameState:
+		name := strings.TrimSpace(text)
+		if name == "-" {
+			name = ""
+		}
+		if err := validateDisplayName(name); err != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Имя должно быть одной строкой не длиннее %d символов. Попробуйте снова:", maxDisplayNameLength)))
+			return true
+		}
+		user.DisplayName = name
+	}
+
+	if err := t.userService.UpdateUserProfile(ctx, user); err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при сохранении профиля."))
+		return true
+	}
+	t.messageState[chatID] = ""
+
+	msg := tgbotapi.NewMessage(chatID, "Профиль обновлён.\n\n"+formatProfile(user))
+	msg.ReplyMarkup = profileKeyboard(user)
+	bot.Send(msg)
+	return true
+}
+
+func formatProfile(user models.User) string {
+	var b strings.Builder
+	b.WriteString("Ваш профиль:\n\n")
+	fmt.Fprintf(&b, "Ник: @%s\n", user.Username)
+	fmt.Fprintf(&b, "Имя в Telegram: %s\n", strings.TrimSpace(user.FirstName+" "+user.LastName))
+	if user.DisplayName != "" {
+		fmt.Fprintf(&b, "Имя для коллег: %s\n", user.DisplayName)
+	}
+	if !user.Birthdate.IsZero() {
+		fmt.Fprintf(&b, "Дата рождения: %s\n", user.Birthdate.Format("02.01.2006"))
+	} else {
+		b.WriteString("Дата рождения: не указана\n")
+	}
+	fmt.Fprintf(&b, "День рождения скрыт от коллег: %s\n", formatYesNo(user.HideBirthday))
+	if user.Team != "" {
+		fmt.Fprintf(&b, "Команда: %s\n", user.Team)
+	}
+	fmt.Fprintf(&b, "Поздравления с праздниками: %s\n", formatOnOff(user.NotifyHolidays))
+	if HasPermission(user, PermissionReceiveReminders) {
+		fmt.Fprintf(&b, "Напоминания о событиях коллег: %s\n", formatOnOff(user.NotifyReminders))
+	}
+	fmt.Fprintf(&b, "Зарегистрирован: %s", user.CreatedAt.Format("02.01.2006"))
+	return b.String()
+}
+
+func profileKeyboard(user models.User) tgbotapi.InlineKeyboardMarkup {
+	hideLabel := "Скрыть день рождения"
+	if user.HideBirthday {
+		hideLabel = "Показывать день рождения"
+	}
+
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Изменить дату рождения", profileCallback+":"+profileBirthdateArgument),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Изменить имя", profileCallback+":"+profileNameArgument),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(hideLabel, profileCallback+":"+profileHideBirthdayArgument),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Праздники: "+formatOnOff(user.NotifyHolidays), profileCallback+":"+profileNotifyHolidaysArgument),
+		),
+	}
+	if HasPermission(user, PermissionReceiveReminders) {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Напоминания: "+formatOnOff(user.NotifyReminders), profileCallback+":"+profileNotifyRemindersArgument),
+		))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+func formatOnOff(enabled bool) string {
+	if enabled {
+		return "вкл"
+	}
+	return "выкл"
+}
+
+func formatYesNo(value bool) string {
+	if value {
+		return "да"
+	}
+	return "нет"
+}
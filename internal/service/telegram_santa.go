@@ -0,0 +1,403 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"gift-bot/pkg/models"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	santaJoinCallback  = "santa_join"
+	santaLeaveCallback = "santa_leave"
+)
+
+func (t *Telegram) handleSetTeamCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, args []string) {
+	if len(args) < 1 {
+		msg := tgbotapi.NewMessage(chatID, "Использование: /set_team @ник Команда. Без названия команда будет сброшена.")
+		bot.Send(msg)
+		return
+	}
+
+	target, found, err := t.findUserByUsername(ctx, args[0])
+	if err != nil {
+		log.Println(err)
+		msg := tgbotapi.NewMessage(chatID, "Ошибка при получении списка пользователей.")
+		bot.Send(msg)
+		return
+	}
+	if !found {
+		msg := tgbotapi.NewMessage(chatID, "Пользователь не найден.")
+		bot.Send(msg)
+		return
+	}
+
+	team := strings.TrimSpace(strings.Join(args[1:], " "))
+	if err := t.userService.SetUserTeam(ctx, target.TelegramID, team); err != nil {
+		msg := tgbotapi.NewMessage(chatID, "Ошибка при сохранении команды.")
+		bot.Send(msg)
+		return
+	}
+
+	text := fmt.Sprintf("Команда пользователя @%s сброшена.", target.Username)
+	if team != "" {
+		text = fmt.Sprintf("Пользователь @%s добавлен в команду «%s».", target.Username, team)
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, text))
+}
+
+// activeSantaEvent возвращает текущее событие или сообщает, что его нет.
+func (t *Telegram) activeSantaEvent(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) (models.SantaEvent, bool) {
+	event, err := t.santaService.GetActiveSantaEvent(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		msg := tgbotapi.NewMessage(chatID, "Сейчас нет активного Тайного Санты.")
+		bot.Send(msg)
+		return models.SantaEvent{}, false
+	}
+	if err != nil {
+		log.Println(err)
+		msg := tgbotapi.NewMessage(chatID, "Ошибка при получении события Тайного Санты.")
+		bot.Send(msg)
+		return models.SantaEvent{}, false
+	}
+	return event, true
+}
+
+func santaKeyboard(eventID int64, joined bool) tgbotapi.InlineKeyboardMarkup {
+	if joined {
+		return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Выйти из игры", fmt.Sprintf("%s:%d", santaLeaveCallback, eventID)),
+		))
+	}
+	return tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Участвовать", fmt.Sprintf("%s:%d", santaJoinCallback, eventID)),
+	))
+}
+
+func (t *Telegram) handleSantaCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) {
+	user, err := t.userService.GetUser(ctx, models.User{TelegramID: chatID})
+	if err != nil {
+		msg := tgbotapi.NewMessage(chatID, "Сначала зарегистрируйтесь в боте через /login.")
+		bot.Send(msg)
+		return
+	}
+
+	event, ok := t.activeSantaEvent(ctx, bot, chatID)
+	if !ok {
+		return
+	}
+
+	participants, err := t.santaService.GetSantaParticipants(ctx, event.ID)
+	if err != nil {
+		msg := tgbotapi.NewMessage(chatID, "Ошибка при получении списка участников.")
+		bot.Send(msg)
+		return
+	}
+
+	joined := false
+	for _, p := range participants {
+		if p.TelegramID == user.TelegramID {
+			joined = true
+			break
+		}
+	}
+
+	if event.Status == models.SantaStatusRegistration {
+		status := "Вы пока не участвуете."
+		if joined {
+			status = "Вы участвуете."
+		}
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("«%s»: идёт регистрация, участников — %d.\n%s",
+			event.Title, len(participants), status))
+		msg.ReplyMarkup = santaKeyboard(event.ID, joined)
+		bot.Send(msg)
+		return
+	}
+
+	if !joined {
+		msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("«%s»: жеребьёвка уже проведена, вы не участвуете.", event.Title))
+		bot.Send(msg)
+		return
+	}
+
+	pairs, err := t.santaService.GetSantaPairs(ctx, event.ID)
+	if err != nil {
+		msg := tgbotapi.NewMessage(chatID, "Ошибка при получении результатов жеребьёвки.")
+		bot.Send(msg)
+		return
+	}
+
+	byID := make(map[int64]models.User, len(participants))
+	for _, p := range participants {
+		byID[p.TelegramID] = p
+	}
+	for _, pair := range pairs {
+		if pair.GiverTelegramID == user.TelegramID {
+			t.sendSantaRecipient(ctx, event, pair, byID[pair.RecipientTelegramID])
+			return
+		}
+	}
+
+	msg := tgbotapi.NewMessage(chatID, "Для вас не нашлось пары. Обратитесь к администратору.")
+	bot.Send(msg)
+}
+
+func (t *Telegram) handleSantaCallback(ctx context.Context, update tgbotapi.Update, bot *tgbotapi.BotAPI, chatID int64, action string, arg string) {
+	eventID, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return
+	}
+
+	if _, err := t.userService.GetUser(ctx, models.User{TelegramID: chatID}); err != nil {
+		msg := tgbotapi.NewMessage(chatID, "Сначала зарегистрируйтесь в боте через /login.")
+		bot.Send(msg)
+		return
+	}
+
+	var text string
+	joined := action == santaJoinCallback
+	if joined {
+		err = t.santaService.JoinSantaEvent(ctx, eventID, chatID)
+		text = "Вы участвуете в Тайном Санте! Получателя пришлём после жеребьёвки."
+	} else {
+		err = t.santaService.LeaveSantaEvent(ctx, eventID, chatID)
+		text = "Вы вышли из Тайного Санты."
+	}
+
+	if errors.Is(err, ErrSantaWrongStatus) {
+		t.clearInlineKeyboard(bot, update)
+		msg := tgbotapi.NewMessage(chatID, "Регистрация на это событие уже закрыта.")
+		bot.Send(msg)
+		return
+	}
+	if err != nil {
+		msg := tgbotapi.NewMessage(chatID, "Ошибка при обновлении участия.")
+		bot.Send(msg)
+		return
+	}
+
+	editMsg := tgbotapi.NewEditMessageReplyMarkup(chatID, update.CallbackQuery.Message.MessageID, santaKeyboard(eventID, joined))
+	bot.Send(editMsg)
+	bot.Send(tgbotapi.NewMessage(chatID, text))
+}
+
+func (t *Telegram) handleSantaOpenCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, args []string) {
+	if _, err := t.santaService.GetActiveSantaEvent(ctx); err == nil {
+		msg := tgbotapi.NewMessage(chatID, "Уже есть активный Тайный Санта. Закройте его через /santa_close.")
+		bot.Send(msg)
+		return
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		log.Println(err)
+		msg := tgbotapi.NewMessage(chatID, "Ошибка при получении события Тайного Санты.")
+		bot.Send(msg)
+		return
+	}
+
+	year := time.Now().Year()
+	title := strings.TrimSpace(strings.Join(args, " "))
+	if title == "" {
+		title = fmt.Sprintf("Тайный Санта %d", year)
+	}
+
+	event := models.SantaEvent{
+		Title:                title,
+		Year:                 year,
+		ExcludeSameTeam:      true,
+		ExcludePreviousPairs: true,
+		CreatedBy:            chatID,
+	}
+	eventID, err := t.santaService.OpenSantaEvent(ctx, event)
+	if err != nil {
+		msg := tgbotapi.NewMessage(chatID, "Ошибка при создании события.")
+		bot.Send(msg)
+		return
+	}
+
+	users, err := t.userService.GetAllUsers(ctx)
+	if err != nil {
+		log.Println(err)
+		msg := tgbotapi.NewMessage(chatID, "Событие создано, но не удалось получить список пользователей для приглашения.")
+		bot.Send(msg)
+		return
+	}
+
+	invite := fmt.Sprintf("🎅 Открыта регистрация на «%s»!\nНажмите кнопку, чтобы участвовать. "+
+		"Получателя подарка бот пришлёт вам лично после жеребьёвки.", title)
+	for _, user := range users {
+		msg := tgbotapi.NewMessage(user.TelegramID, invite)
+		msg.ReplyMarkup = santaKeyboard(eventID, false)
+		if _, err := t.Bot.Send(msg); err != nil {
+			log.Printf("Error sending santa invite to %s: %v", user.Username, err)
+		}
+	}
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Регистрация на «%s» открыта, приглашения отправлены (%d).\n"+
+		"Пары из одной команды и пары прошлого года исключаются. Когда все соберутся, запустите /santa_draw.",
+		title, len(users)))
+	bot.Send(msg)
+}
+
+func (t *Telegram) handleSantaExcludeCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64, args []string) {
+	if len(args) != 2 {
+		msg := tgbotapi.NewMessage(chatID, "Использование: /santa_exclude @ник1 @ник2")
+		bot.Send(msg)
+		return
+	}
+
+	event, ok := t.activeSantaEvent(ctx, bot, chatID)
+	if !ok {
+		return
+	}
+
+	var ids [2]int64
+	for i, username := range args {
+		user, found, err := t.findUserByUsername(ctx, username)
+		if err != nil {
+			log.Println(err)
+			msg := tgbotapi.NewMessage(chatID, "Ошибка при получении списка пользователей.")
+			bot.Send(msg)
+			return
+		}
+		if !found {
+			msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Пользователь %s не найден.", username))
+			bot.Send(msg)
+			return
+		}
+		ids[i] = user.TelegramID
+	}
+
+	if ids[0] == ids[1] {
+		msg := tgbotapi.NewMessage(chatID, "Укажите двух разных пользователей.")
+		bot.Send(msg)
+		return
+	}
+
+	err := t.santaService.AddSantaExclusion(ctx, models.SantaExclusion{
+		EventID:          event.ID,
+		FirstTelegramID:  ids[0],
+		SecondTelegramID: ids[1],
+	})
+	if err != nil {
+		msg := tgbotapi.NewMessage(chatID, "Ошибка при сохранении исключения.")
+		bot.Send(msg)
+		return
+	}
+
+	msg := tgbotapi.NewMessage(chatID, "Исключение сохранено: эти участники не будут дарить подарки друг другу.")
+	bot.Send(msg)
+}
+
+func (t *Telegram) handleSantaDrawCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) {
+	event, ok := t.activeSantaEvent(ctx, bot, chatID)
+	if !ok {
+		return
+	}
+
+	pairs, err := t.santaService.DrawSantaPairs(ctx, event.ID)
+	switch {
+	case errors.Is(err, ErrSantaWrongStatus):
+		bot.Send(tgbotapi.NewMessage(chatID, "Жеребьёвка для этого события уже проведена."))
+		return
+	case errors.Is(err, ErrSantaNotEnoughParticipants):
+		bot.Send(tgbotapi.NewMessage(chatID, "Для жеребьёвки нужно хотя бы 2 участника."))
+		return
+	case errors.Is(err, ErrSantaNoPairing):
+		bot.Send(tgbotapi.NewMessage(chatID, "Не удалось составить пары с текущими ограничениями. "+
+			"Проверьте команды участников и исключения."))
+		return
+	case err != nil:
+		log.Println(err)
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при проведении жеребьёвки."))
+		return
+	}
+
+	participants, err := t.santaService.GetSantaParticipants(ctx, event.ID)
+	if err != nil {
+		log.Println(err)
+		bot.Send(tgbotapi.NewMessage(chatID, "Жеребьёвка проведена, но не удалось получить участников для рассылки."))
+		return
+	}
+	byID := make(map[int64]models.User, len(participants))
+	for _, p := range participants {
+		byID[p.TelegramID] = p
+	}
+
+	notified := 0
+	for _, pair := range pairs {
+		if t.sendSantaRecipient(ctx, event, pair, byID[pair.RecipientTelegramID]) {
+			notified++
+		}
+	}
+
+	msg := tgbotapi.NewMessage(chatID, fmt.Sprintf("Жеребьёвка проведена: пар — %d, уведомлено — %d.\n"+
+		"Проверить результат без раскрытия пар можно через /santa_audit.", len(pairs), notified))
+	bot.Send(msg)
+}
+
+// sendSantaRecipient лично сообщает дарителю его получателя и отмечает пару как уведомлённую.
+func (t *Telegram) sendSantaRecipient(ctx context.Context, event models.SantaEvent, pair models.SantaPair, recipient models.User) bool {
+	text := fmt.Sprintf("🎁 «%s»: вы Тайный Санта для %s.\nНикому не рассказывайте!", event.Title, formatUserButtonText(recipient))
+	if _, err := t.Bot.Send(tgbotapi.NewMessage(pair.GiverTelegramID, text)); err != nil {
+		log.Printf("Error sending santa recipient to %d: %v", pair.GiverTelegramID, err)
+		return false
+	}
+	if err := t.santaService.MarkSantaPairNotified(ctx, event.ID, pair.GiverTelegramID); err != nil {
+		log.Println("Error marking santa pair notified:", err)
+	}
+	return true
+}
+
+func (t *Telegram) handleSantaAuditCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) {
+	event, ok := t.activeSantaEvent(ctx, bot, chatID)
+	if !ok {
+		return
+	}
+
+	audit, err := t.santaService.AuditSantaDraw(ctx, event.ID)
+	if err != nil {
+		log.Println(err)
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при проверке жеребьёвки."))
+		return
+	}
+
+	if audit.Event.Status == models.SantaStatusRegistration {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("«%s»: идёт регистрация, участников — %d. Жеребьёвка ещё не проводилась.",
+			event.Title, audit.Participants)))
+		return
+	}
+
+	digest := "нарушена"
+	if audit.DigestValid {
+		digest = "совпадает"
+	}
+	text := fmt.Sprintf("Проверка «%s»:\n"+
+		"Участников: %d\nПар: %d\nУведомлено: %d\n"+
+		"Дарят сами себе: %d\nБез получателя: %d\nБез дарителя: %d\n"+
+		"Нарушения «одна команда»: %d\nПовторы прошлого года: %d\nНарушения исключений: %d\n"+
+		"Контрольная сумма пар: %s (%s)",
+		event.Title, audit.Participants, audit.Pairs, audit.Notified,
+		audit.SelfPairs, audit.GiversWithoutPair, audit.RecipientsWithoutPair,
+		audit.TeamViolations, audit.PreviousPairViolations, audit.ExclusionViolations,
+		digest, event.DrawDigest)
+	bot.Send(tgbotapi.NewMessage(chatID, text))
+}
+
+func (t *Telegram) handleSantaCloseCommand(ctx context.Context, bot *tgbotapi.BotAPI, chatID int64) {
+	event, ok := t.activeSantaEvent(ctx, bot, chatID)
+	if !ok {
+		return
+	}
+
+	if err := t.santaService.CloseSantaEvent(ctx, event.ID); err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "Ошибка при закрытии события."))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("Событие «%s» закрыто.", event.Title)))
+}
@@ -1,59 +1,330 @@
 package service
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"gift-bot/internal/repository"
 	"gift-bot/pkg/models"
+	"strings"
 	"time"
+	"unicode/utf8"
+)
+
+// maxDisplayNameLength — наибольшая длина отображаемого имени в символах.
+const maxDisplayNameLength = 64
+
+var (
+	// ErrUserNotFound возвращается, если пользователя с таким Telegram ID нет.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUnknownRole возвращается при попытке назначить роль, которой нет среди назначаемых.
+	ErrUnknownRole = errors.New("unknown role")
+	// ErrInvalidDisplayName возвращается, если отображаемое имя длиннее maxDisplayNameLength или занимает несколько строк.
+	ErrInvalidDisplayName = errors.New("invalid display name")
 )
 
 type UserServiceImpl struct {
-	repo repository.UserRepository
+	repo         repository.UserRepository
+	tx           repository.Transactor
+	auditService AuditService
+}
+
+func NewUserService(repo repository.UserRepository, tx repository.Transactor, auditService AuditService) *UserServiceImpl {
+	return &UserServiceImpl{repo: repo, tx: tx, auditService: auditService}
+}
+
+func (u UserServiceImpl) CreateUser(ctx context.Context, user models.User) error {
+	return u.repo.CreateUser(ctx, user)
+}
+
+func (u UserServiceImpl) GetUser(ctx context.Context, user models.User) (models.User, error) {
+	return u.repo.GetUser(ctx, user)
+}
+
+func (u UserServiceImpl) GetAllUsers(ctx context.Context) ([]models.User, error) {
+	return u.repo.GetAllUsers(ctx)
+}
+
+func (u UserServiceImpl) GetBlockedUsers(ctx context.Context) ([]models.User, error) {
+	return u.repo.GetBlockedUsers(ctx)
+}
+
+// BlockUsers блокирует пользователей по никам и пишет в журнал событие на каждого в одной транзакции.
+func (u UserServiceImpl) BlockUsers(ctx context.Context, actorTelegramID int64, users []models.User) error {
+	return u.tx.WithTx(ctx, func(ctx context.Context) error {
+		if err := u.repo.DeleteUsersByUsernames(ctx, usernamesOf(users)); err != nil {
+			return err
+		}
+		return u.auditUsers(ctx, actorTelegramID, models.AuditActionBlock, users)
+	})
+}
+
+// UnblockUsers — обратная операция к BlockUsers.
+func (u UserServiceImpl) UnblockUsers(ctx context.Context, actorTelegramID int64, users []models.User) error {
+	return u.tx.WithTx(ctx, func(ctx context.Context) error {
+		if err := u.repo.UnblockUsersByUsernames(ctx, usernamesOf(users)); err != nil {
+			return err
+		}
+		return u.auditUsers(ctx, actorTelegramID, models.AuditActionUnblock, users)
+	})
+}
+
+// auditUsers пишет по событию на каждого пользователя. Пользователь указывается только через target_telegram_id:
+// ник в payload пережил бы удаление пользователя, а Telegram ID PurgeUser обезличивает.
+func (u UserServiceImpl) auditUsers(ctx context.Context, actorTelegramID int64, action string, users []models.User) error {
+	for _, user := range users {
+		if err := u.auditService.Save(ctx, actorTelegramID, action, user.TelegramID, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func usernamesOf(users []models.User) []string {
+	usernames := make([]string, 0, len(users))
+	for _, user := range users {
+		usernames = append(usernames, user.Username)
+	}
+	return usernames
+}
+
+func (u UserServiceImpl) UpdateUser(ctx context.Context, user models.User) error {
+	return u.repo.UpdateUser(ctx, user)
+}
+
+func (u UserServiceImpl) SetUserTeam(ctx context.Context, telegramID int64, team string) error {
+	return u.repo.SetUserTeam(ctx, telegramID, team)
+}
+
+func (u UserServiceImpl) GetAllAdmins(ctx context.Context) ([]models.User, error) {
+	return u.repo.GetAllAdmins(ctx)
+}
+
+// DeliverBirthdayNotification отмечает уведомление и вызывает send в одной транзакции.
+// Возвращает false, если уведомление за эту дату уже отправлено. Ошибка send откатывает отметку,
+// и следующий запуск попробует снова.
+func (u UserServiceImpl) DeliverBirthdayNotification(ctx context.Context, adminTelegramID int64, userTelegramID int64, date time.Time, send func() error) (bool, error) {
+	delivered := false
+	err := u.tx.WithTx(ctx, func(ctx context.Context) error {
+		claimed, err := u.repo.SaveBirthdayNotification(ctx, adminTelegramID, userTelegramID, date)
+		if err != nil || !claimed {
+			return err
+		}
+		if err := send(); err != nil {
+			return err
+		}
+		delivered = true
+		return nil
+	})
+	return delivered, err
+}
+
+func (u UserServiceImpl) GetPendingUsers(ctx context.Context) ([]models.User, error) {
+	return u.repo.GetPendingUsers(ctx)
+}
+
+// ReviewRegistration рассматривает заявку и пишет решение в журнал в одной транзакции.
+// Возвращает false, если заявка уже рассмотрена.
+func (u UserServiceImpl) ReviewRegistration(ctx context.Context, telegramID int64, status string, reviewerID int64) (bool, error) {
+	action := models.AuditActionRegistrationApprove
+	if status == models.UserStatusRejected {
+		action = models.AuditActionRegistrationReject
+	}
+	reviewed := false
+	err := u.tx.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		if reviewed, err = u.repo.ReviewRegistration(ctx, telegramID, status, reviewerID); err != nil || !reviewed {
+			return err
+		}
+		return u.auditService.Save(ctx, reviewerID, action, telegramID, nil)
+	})
+	return reviewed, err
+}
+
+func (u UserServiceImpl) UpdateUserProfile(ctx context.Context, user models.User) error {
+	if err := validateDisplayName(user.DisplayName); err != nil {
+		return err
+	}
+	return u.repo.UpdateUserProfile(ctx, user)
+}
+
+// validateDisplayName проверяет отображаемое имя одинаково для бота и API.
+func validateDisplayName(name string) error {
+	if utf8.RuneCountInString(name) > maxDisplayNameLength || strings.ContainsAny(name, "\r\n") {
+		return ErrInvalidDisplayName
+	}
+	return nil
 }
 
-func NewUserService(repo repository.UserRepository) *UserServiceImpl {
-	return &UserServiceImpl{repo: repo}
+func (u UserServiceImpl) GetUsersByRoles(ctx context.Context, roles []string) ([]models.User, error) {
+	return u.repo.GetUsersByRoles(ctx, roles)
 }
 
-func (u UserServiceImpl) CreateUser(user models.User) error {
-	return u.repo.CreateUser(user)
+func (u UserServiceImpl) SetUserRole(ctx context.Context, telegramID int64, role string) (bool, error) {
+	return u.repo.SetUserRole(ctx, telegramID, role)
 }
 
-func (u UserServiceImpl) GetUser(user models.User) (models.User, error) {
-	return u.repo.GetUser(user)
+// SearchUsers возвращает страницу пользователей под фильтром и их общее число.
+func (u UserServiceImpl) SearchUsers(ctx context.Context, filter models.UserFilter) ([]models.User, int, error) {
+	filter.Limit, filter.Offset = PageBounds(filter.Limit, filter.Offset)
+	return u.repo.SearchUsers(ctx, filter)
 }
 
-func (u UserServiceImpl) GetAllUsers() ([]models.User, error) {
-	return u.repo.GetAllUsers()
+// GetUserByTelegramID возвращает пользователя в любом статусе, включая заблокированных.
+func (u UserServiceImpl) GetUserByTelegramID(ctx context.Context, telegramID int64) (models.User, error) {
+	user, err := u.repo.GetUser(ctx, models.User{TelegramID: telegramID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.User{}, ErrUserNotFound
+	}
+	return user, err
 }
 
-func (u UserServiceImpl) GetBlockedUsers() ([]models.User, error) {
-	return u.repo.GetBlockedUsers()
+// PatchUser меняет только переданные поля и возвращает пользователя после изменения.
+// Изменение и событие журнала от имени actorTelegramID (в payload — список полей без значений) пишутся в одной транзакции.
+func (u UserServiceImpl) PatchUser(ctx context.Context, telegramID int64, patch models.UserPatch, actorTelegramID int64, payload map[string]interface{}) (models.User, error) {
+	if patch.DisplayName != nil {
+		name := strings.TrimSpace(*patch.DisplayName)
+		if err := validateDisplayName(name); err != nil {
+			return models.User{}, err
+		}
+		patch.DisplayName = &name
+	}
+	err := u.tx.WithTx(ctx, func(ctx context.Context) error {
+		patched, err := u.repo.PatchUser(ctx, telegramID, patch)
+		if err != nil {
+			return err
+		}
+		if !patched {
+			return ErrUserNotFound
+		}
+		return u.auditService.Save(ctx, actorTelegramID, models.AuditActionUserUpdate, telegramID,
+			withPayload(payload, map[string]interface{}{"fields": patchedFields(patch)}))
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+	return u.GetUserByTelegramID(ctx, telegramID)
 }
 
-func (u UserServiceImpl) DeleteUsersByUsernames(usernames []string) error {
-	return u.repo.DeleteUsersByUsernames(usernames)
+// patchedFields перечисляет поля, переданные в patch, для журнала.
+func patchedFields(patch models.UserPatch) []string {
+	fields := []string{}
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"first_name", patch.FirstName != nil},
+		{"last_name", patch.LastName != nil},
+		{"display_name", patch.DisplayName != nil},
+		{"team", patch.Team != nil},
+		{"birthdate", patch.Birthdate != nil},
+		{"hide_birthday", patch.HideBirthday != nil},
+		{"notify_holidays", patch.NotifyHolidays != nil},
+		{"notify_reminders", patch.NotifyReminders != nil},
+	} {
+		if f.set {
+			fields = append(fields, f.name)
+		}
+	}
+	return fields
 }
 
-func (u UserServiceImpl) UnblockUsersByUsernames(usernames []string) error {
-	return u.repo.UnblockUsersByUsernames(usernames)
+// withPayload дополняет payload события журнала полями extra, не меняя исходную карту.
+func withPayload(payload, extra map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(payload)+len(extra))
+	for k, v := range payload {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return merged
 }
 
-func (u UserServiceImpl) UpdateUser(user models.User) error {
-	return u.repo.UpdateUser(user)
+// BlockUser блокирует пользователя и пишет событие в журнал от имени actorTelegramID в одной транзакции.
+// Владельца заблокировать нельзя.
+func (u UserServiceImpl) BlockUser(ctx context.Context, telegramID int64, actorTelegramID int64, payload map[string]interface{}) (models.User, error) {
+	return u.setBlocked(ctx, telegramID, true, actorTelegramID, payload)
 }
 
-func (u UserServiceImpl) GetUsersWithBirthdayInDays() ([]models.User, error) {
-	return u.repo.GetUsersWithBirthdayInDays()
+func (u UserServiceImpl) UnblockUser(ctx context.Context, telegramID int64, actorTelegramID int64, payload map[string]interface{}) (models.User, error) {
+	return u.setBlocked(ctx, telegramID, false, actorTelegramID, payload)
 }
 
-func (u UserServiceImpl) GetAllAdmins() ([]models.User, error) {
-	return u.repo.GetAllAdmins()
+func (u UserServiceImpl) setBlocked(ctx context.Context, telegramID int64, blocked bool, actorTelegramID int64, payload map[string]interface{}) (models.User, error) {
+	action := models.AuditActionUnblock
+	if blocked {
+		action = models.AuditActionBlock
+	}
+	var user models.User
+	err := u.tx.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		if user, err = u.GetUserByTelegramID(ctx, telegramID); err != nil {
+			return err
+		}
+		changed, err := u.repo.SetUserBlocked(ctx, telegramID, blocked)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			return ErrOwnerProtected
+		}
+		return u.auditService.Save(ctx, actorTelegramID, action, telegramID, payload)
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+	user.Blocked = blocked
+	return user, nil
 }
 
-func (u UserServiceImpl) HasBirthdayNotification(adminTelegramID int64, userTelegramID int64, date time.Time) (bool, error) {
-	return u.repo.HasBirthdayNotification(adminTelegramID, userTelegramID, date)
+// BootstrapOwner делает пользователя владельцем, если владельца ещё нет: так новая установка получает
+// первого администратора из OWNER_TELEGRAM_ID. Возвращает false, если владелец уже есть
+// или пользователь ещё не зарегистрирован.
+func (u UserServiceImpl) BootstrapOwner(ctx context.Context, telegramID int64) (bool, error) {
+	promoted := false
+	err := u.tx.WithTx(ctx, func(ctx context.Context) error {
+		user, err := u.GetUserByTelegramID(ctx, telegramID)
+		if errors.Is(err, ErrUserNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if promoted, err = u.repo.ClaimOwner(ctx, telegramID); err != nil || !promoted {
+			return err
+		}
+		return u.auditService.Save(ctx, telegramID, models.AuditActionRoleChange, telegramID, map[string]interface{}{
+			"from": user.Role, "to": models.RoleOwner, "source": "config",
+		})
+	})
+	return promoted, err
 }
 
-func (u UserServiceImpl) SaveBirthdayNotification(adminTelegramID int64, userTelegramID int64, date time.Time) error {
-	return u.repo.SaveBirthdayNotification(adminTelegramID, userTelegramID, date)
+// ChangeUserRole назначает одну из ролей assignableRoles и возвращает пользователя с прежней ролью.
+// Смена роли и событие журнала от имени actorTelegramID пишутся в одной транзакции.
+func (u UserServiceImpl) ChangeUserRole(ctx context.Context, telegramID int64, role string, actorTelegramID int64, payload map[string]interface{}) (models.User, error) {
+	if !IsAssignableRole(role) {
+		return models.User{}, ErrUnknownRole
+	}
+	var user models.User
+	err := u.tx.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		if user, err = u.GetUserByTelegramID(ctx, telegramID); err != nil {
+			return err
+		}
+		changed, err := u.repo.SetUserRole(ctx, telegramID, role)
+		if err != nil {
+			return err
+		}
+		if !changed {
+			return ErrOwnerProtected
+		}
+		return u.auditService.Save(ctx, actorTelegramID, models.AuditActionRoleChange, telegramID,
+			withPayload(payload, map[string]interface{}{"from": user.Role, "to": role}))
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+	return user, nil
 }
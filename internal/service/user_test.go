@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"gift-bot/pkg/models"
+)
+
+func TestUserChangesAreAudited(t *testing.T) {
+	ctx := context.Background()
+	repos := newTestRepositories(t)
+	audit := NewAuditService(repos.AuditRepository)
+	users := NewUserService(repos.UserRepository, repos.Transactor, audit)
+	for _, user := range []models.User{
+		{TelegramID: 1, Username: "owner", Role: models.RoleOwner},
+		{TelegramID: 2, Username: "bob", Role: models.RoleUser},
+	} {
+		if err := users.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	name := "Роберт"
+	if _, err := users.PatchUser(ctx, 2, models.UserPatch{DisplayName: &name}, 1, map[string]interface{}{"source": "test"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.ChangeUserRole(ctx, 2, models.RoleAdmin, 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := users.ChangeUserRole(ctx, 1, models.RoleUser, 2, nil); !errors.Is(err, ErrOwnerProtected) {
+		t.Fatalf("ChangeUserRole of the owner = %v, want %v", err, ErrOwnerProtected)
+	}
+	if _, err := users.PatchUser(ctx, 99, models.UserPatch{DisplayName: &name}, 1, nil); !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("PatchUser of a missing user = %v, want %v", err, ErrUserNotFound)
+	}
+
+	events, total, err := audit.GetAuditEvents(ctx, models.AuditFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 {
+		t.Fatalf("got %d audit events, want 2: rejected changes must not be audited", total)
+	}
+	want := map[string]map[string]interface{}{
+		models.AuditActionUserUpdate: {"source": "test", "fields": []interface{}{"display_name"}},
+		models.AuditActionRoleChange: {"from": models.RoleUser, "to": models.RoleAdmin},
+	}
+	for _, event := range events {
+		var payload map[string]interface{}
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			t.Fatal(err)
+		}
+		if event.ActorTelegramID != 1 || event.TargetTelegramID == nil || *event.TargetTelegramID != 2 ||
+			!reflect.DeepEqual(payload, want[event.Action]) {
+			t.Errorf("audit event %s by %d: %v, want %v", event.Action, event.ActorTelegramID, payload, want[event.Action])
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gift-bot/pkg/config"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrWebAuthInvalid = errors.New("web auth: invalid signature or expired")
+
+const (
+	// telegramLoginMaxAge — сколько действительны данные от Telegram Login Widget.
+	telegramLoginMaxAge = 24 * time.Hour
+	// WebSessionTTL — срок жизни сессии веб-панели.
+	WebSessionTTL = 12 * time.Hour
+)
+
+// VerifyTelegramLogin проверяет подпись данных Telegram Login Widget и возвращает Telegram ID.
+// Алгоритм: https://core.telegram.org/widgets/login#checking-authorization
+func VerifyTelegramLogin(fields map[string]string, now time.Time) (int64, error) {
+	hash := fields["hash"]
+	if hash == "" {
+		return 0, ErrWebAuthInvalid
+	}
+
+	var lines []string
+	for key, value := range fields {
+		if key != "hash" {
+			lines = append(lines, key+"="+value)
+		}
+	}
+	sort.Strings(lines)
+
+	secret := sha256.Sum256([]byte(config.GlobalСonfig.Telegram.Token))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(hash)) {
+		return 0, ErrWebAuthInvalid
+	}
+
+	authDate, err := strconv.ParseInt(fields["auth_date"], 10, 64)
+	if err != nil || now.Sub(time.Unix(authDate, 0)) > telegramLoginMaxAge {
+		return 0, ErrWebAuthInvalid
+	}
+	telegramID, err := strconv.ParseInt(fields["id"], 10, 64)
+	if err != nil {
+		return 0, ErrWebAuthInvalid
+	}
+	return telegramID, nil
+}
+
+// SignWebSession формирует значение cookie сессии: <telegram_id>.<expires_unix>.<подпись>.
+// Права пользователя в cookie не хранятся и проверяются по БД на каждом запросе.
+func SignWebSession(telegramID int64, expires time.Time) string {
+	payload := fmt.Sprintf("%d.%d", telegramID, expires.Unix())
+	return payload + "." + webSign("session", payload)
+}
+
+// ParseWebSession проверяет подпись и срок cookie сессии и возвращает Telegram ID.
+func ParseWebSession(value string, now time.Time) (int64, error) {
+	i := strings.LastIndexByte(value, '.')
+	if i < 0 {
+		return 0, ErrWebAuthInvalid
+	}
+	payload, signature := value[:i], value[i+1:]
+	if !hmac.Equal([]byte(webSign("session", payload)), []byte(signature)) {
+		return 0, ErrWebAuthInvalid
+	}
+
+	parts := strings.Split(payload, ".")
+	if len(parts) != 2 {
+		return 0, ErrWebAuthInvalid
+	}
+	telegramID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, ErrWebAuthInvalid
+	}
+	expires, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || now.After(time.Unix(expires, 0)) {
+		return 0, ErrWebAuthInvalid
+	}
+	return telegramID, nil
+}
+
+// WebCSRFToken привязывает токен формы к сессии: чужой сайт не знает значение cookie и не может его подделать.
+func WebCSRFToken(session string) string {
+	return webSign("csrf", session)
+}
+
+// ValidWebCSRFToken сравнивает токен из формы с токеном сессии за постоянное время.
+func ValidWebCSRFToken(session, token string) bool {
+	return hmac.Equal([]byte(WebCSRFToken(session)), []byte(token))
+}
+
+// webSign подписывает данные веб-панели ключом, производным от токена бота.
+func webSign(purpose, data string) string {
+	key := sha256.Sum256([]byte("gift-bot web " + purpose + ":" + config.GlobalСonfig.Telegram.Token))
+	mac := hmac.New(sha256.New, key[:])
+	mac.Write([]byte(data))
+	return hex.EncodeToString(mac.Sum(nil))
+}
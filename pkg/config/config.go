@@ -4,38 +4,86 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	DB           PostgresConfig
-	ServerConfig ServerConfig
-	Telegram     TelegramConfig
+	// Storage — хранилище данных: StoragePostgres или StorageSQLite
+	Storage string
+	// MigrateOnStart — применять миграции при запуске бота. Если выключено, схему обновляет `gift-bot migrate up`
+	MigrateOnStart bool
+	DB             PostgresConfig
+	SQLite         SQLiteConfig
+	ServerConfig   ServerConfig
+	Telegram       TelegramConfig
+	Holidays       HolidaysConfig
+	Jobs           JobsConfig
 }
 
 type PostgresConfig struct {
-	Host       string
-	Port       string
-	Username   string
-	Name       string
-	Password   string
-	SSL        string
-	Migrations string
+	Host     string
+	Port     string
+	Username string
+	Name     string
+	Password string
+	SSL      string
+	// LeaderLockKey — ключ advisory-блокировки, которой экземпляры бота с общей БД выбирают ведущего
+	LeaderLockKey int64
+}
+
+// SQLiteConfig — встроенное хранилище для небольших команд: один файл, один экземпляр бота.
+type SQLiteConfig struct {
+	Path string
 }
 
 type ServerConfig struct {
-	Port     string
-	GinMode  string
-	Timezone string
+	// Host — адрес, на котором слушает HTTP-сервер. Пустой — все интерфейсы
+	Host      string
+	Port      string
+	GinMode   string
+	Timezone  string
+	PublicURL string
+	// CORSOrigins — источники, которым браузер разрешит запросы к API. Пустой список выключает CORS
+	CORSOrigins []string
 }
 
 type TelegramConfig struct {
 	Token    string
 	Secret   string
 	ProxyURL string
+	// RegistrationApproval включает очередь заявок: новые пользователи ждут подтверждения администратора
+	RegistrationApproval bool
+	// OwnerTelegramID — Telegram ID владельца бота для новой установки. 0 — не задан
+	OwnerTelegramID int64
 }
 
+type HolidaysConfig struct {
+	File string
+}
+
+// JobsConfig — cron-расписания периодических задач в часовом поясе ServerConfig.Timezone.
+type JobsConfig struct {
+	OccasionReminders string
+	HolidayGreetings  string
+	ProfileSync       string
+	DueBroadcasts     string
+	// Окна догоняющих запусков: после простоя бота задача выполняется за пропущенные даты не старше окна.
+	// 0 выключает догоняющие запуски
+	OccasionRemindersCatchUp time.Duration
+	HolidayGreetingsCatchUp  time.Duration
+}
+
+const (
+	StoragePostgres = "postgres"
+	StorageSQLite   = "sqlite"
+)
+
+// defaultLeaderLockKey — байты строки "gift-bot"
+const defaultLeaderLockKey = 0x676966742d626f74
+
 var GlobalСonfig Config
 
 func (c *Config) Init() {
@@ -45,22 +93,49 @@ func (c *Config) Init() {
 
 	// Server
 	c.ServerConfig.GinMode = getEnvWithDefault("SERVER_GINMODE", "debug")
+	c.ServerConfig.Host = getEnvWithDefault("SERVER_HOST", "")
 	c.ServerConfig.Port = mustGetEnv("SERVER_PORT")
 	c.ServerConfig.Timezone = "Europe/Moscow"
+	c.ServerConfig.PublicURL = getEnvWithDefault("SERVER_PUBLIC_URL", "http://localhost:"+c.ServerConfig.Port)
+	c.ServerConfig.CORSOrigins = getEnvAsList("CORS_ALLOWED_ORIGINS")
 
-	// PostgreSQL
-	c.DB.Host = mustGetEnv("PG_HOST")
-	c.DB.Port = mustGetEnv("PG_PORT")
-	c.DB.Username = mustGetEnv("PG_USER")
-	c.DB.Name = mustGetEnv("PG_NAME")
-	c.DB.Password = mustGetEnv("PG_PASSWORD")
-	c.DB.SSL = getEnvWithDefault("PG_SSLMODE", "disable")
-	c.DB.Migrations = "db/migrations"
+	// Хранилище
+	c.Storage = getEnvWithDefault("STORAGE", StoragePostgres)
+	switch c.Storage {
+	case StoragePostgres:
+		c.DB.Host = mustGetEnv("PG_HOST")
+		c.DB.Port = mustGetEnv("PG_PORT")
+		c.DB.Username = mustGetEnv("PG_USER")
+		c.DB.Name = mustGetEnv("PG_NAME")
+		c.DB.Password = mustGetEnv("PG_PASSWORD")
+		c.DB.SSL = getEnvWithDefault("PG_SSLMODE", "disable")
+		c.DB.LeaderLockKey = getEnvAsInt64WithDefault("PG_LEADER_LOCK_KEY", defaultLeaderLockKey)
+	case StorageSQLite:
+		c.SQLite.Path = getEnvWithDefault("SQLITE_PATH", "gift-bot.db")
+	default:
+		log.Fatalf("op: pkg/config/Init unknown STORAGE %q, want %s or %s", c.Storage, StoragePostgres, StorageSQLite)
+	}
+	c.MigrateOnStart = getEnvAsBoolWithDefault("MIGRATE_ON_START", true)
 
 	// Telegram
 	c.Telegram.Token = mustGetEnv("TELEGRAM_TOKEN")
-	c.Telegram.Secret = mustGetEnv("TELEGRAM_SECRET")
+	// Необязательно: если задано, работает регистрация по общему секретному слову (/login) наряду с приглашениями
+	c.Telegram.Secret = getEnvWithDefault("TELEGRAM_SECRET", "")
 	c.Telegram.ProxyURL = getEnvWithDefault("TELEGRAM_PROXY_URL", "")
+	c.Telegram.RegistrationApproval = getEnvAsBoolWithDefault("REGISTRATION_APPROVAL", false)
+	c.Telegram.OwnerTelegramID = getEnvAsInt64WithDefault("OWNER_TELEGRAM_ID", 0)
+
+	// Holidays
+	c.Holidays.File = getEnvWithDefault("HOLIDAYS_FILE", "holidays.yaml")
+
+	// Jobs
+	c.Jobs.OccasionReminders = getEnvWithDefault("JOB_OCCASION_REMINDERS_CRON", "0 9 * * *")
+	c.Jobs.HolidayGreetings = getEnvWithDefault("JOB_HOLIDAY_GREETINGS_CRON", "0 10 * * *")
+	c.Jobs.ProfileSync = getEnvWithDefault("JOB_PROFILE_SYNC_CRON", "0 4 * * *")
+	c.Jobs.DueBroadcasts = getEnvWithDefault("JOB_DUE_BROADCASTS_CRON", "* * * * *")
+	c.Jobs.OccasionRemindersCatchUp = getEnvAsDurationWithDefault("JOB_OCCASION_REMINDERS_CATCH_UP", 72*time.Hour)
+	// Поздравление с праздником имеет смысл только в тот же день
+	c.Jobs.HolidayGreetingsCatchUp = getEnvAsDurationWithDefault("JOB_HOLIDAY_GREETINGS_CATCH_UP", 12*time.Hour)
 }
 
 func mustGetEnv(key string) string {
@@ -94,6 +169,59 @@ func getEnvAsBool(key string) bool {
 	return b
 }
 
+// getEnvAsBoolWithDefault разбирает булеву переменную окружения, если она задана
+func getEnvAsBoolWithDefault(key string, defaultValue bool) bool {
+	const op = "pkg/config/getEnvAsBoolWithDefault"
+	s := os.Getenv(key)
+	if s == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		log.Fatalf("op: %s cannot parse %s=%q as bool: %v", op, key, s, err)
+	}
+	return b
+}
+
+// getEnvAsInt64WithDefault разбирает целое число, если переменная задана
+func getEnvAsInt64WithDefault(key string, defaultValue int64) int64 {
+	const op = "pkg/config/getEnvAsInt64WithDefault"
+	s := os.Getenv(key)
+	if s == "" {
+		return defaultValue
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		log.Fatalf("op: %s cannot parse %s=%q as int64: %v", op, key, s, err)
+	}
+	return i
+}
+
+// getEnvAsDurationWithDefault разбирает длительность (90m, 72h), если переменная задана
+func getEnvAsDurationWithDefault(key string, defaultValue time.Duration) time.Duration {
+	const op = "pkg/config/getEnvAsDurationWithDefault"
+	s := os.Getenv(key)
+	if s == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		log.Fatalf("op: %s cannot parse %s=%q as duration: %v", op, key, s, err)
+	}
+	return d
+}
+
+// getEnvAsList разбирает список значений через запятую; пустые элементы пропускаются
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // getEnvWithDefault возвращает значение переменной окружения или значение по умолчанию
 func getEnvWithDefault(key, defaultValue string) string {
 	if val := os.Getenv(key); val != "" {
@@ -0,0 +1,102 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Event — событие на весь день (VALUE=DATE).
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Date        time.Time
+	// Yearly добавляет правило ежегодного повторения.
+	Yearly bool
+	// LeapDay — событие 29 февраля; в невисокосные годы оно переносится на 28-е.
+	LeapDay bool
+}
+
+type Calendar struct {
+	ProdID string
+	Name   string
+	Events []Event
+}
+
+// Marshal сериализует календарь в формат iCalendar (RFC 5545).
+func (c Calendar) Marshal(now time.Time) []byte {
+	var buf bytes.Buffer
+	w := func(line string) {
+		buf.WriteString(fold(line))
+		buf.WriteString("\r\n")
+	}
+
+	stamp := now.UTC().Format("20060102T150405Z")
+
+	w("BEGIN:VCALENDAR")
+	w("VERSION:2.0")
+	w("PRODID:" + escape(c.ProdID))
+	w("CALSCALE:GREGORIAN")
+	w("METHOD:PUBLISH")
+	if c.Name != "" {
+		w("X-WR-CALNAME:" + escape(c.Name))
+	}
+	for _, e := range c.Events {
+		w("BEGIN:VEVENT")
+		w("UID:" + escape(e.UID))
+		w("DTSTAMP:" + stamp)
+		w("DTSTART;VALUE=DATE:" + e.Date.Format("20060102"))
+		w("DTEND;VALUE=DATE:" + e.Date.AddDate(0, 0, 1).Format("20060102"))
+		if e.Yearly {
+			w(yearlyRule(e.LeapDay))
+		}
+		w("SUMMARY:" + escape(e.Summary))
+		if e.Description != "" {
+			w("DESCRIPTION:" + escape(e.Description))
+		}
+		w("TRANSP:TRANSPARENT")
+		w("END:VEVENT")
+	}
+	w("END:VCALENDAR")
+	return buf.Bytes()
+}
+
+// yearlyRule для 29 февраля использует последний день февраля, чтобы событие было и в невисокосные годы.
+func yearlyRule(leapDay bool) string {
+	if leapDay {
+		return "RRULE:FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=-1"
+	}
+	return "RRULE:FREQ=YEARLY"
+}
+
+func escape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, ";", `\;`)
+	s = strings.ReplaceAll(s, ",", `\,`)
+	s = strings.ReplaceAll(s, "\r\n", `\n`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return s
+}
+
+// fold переносит строки длиннее 75 октетов, не разрывая UTF-8 символы.
+func fold(line string) string {
+	const limit = 75
+	if len(line) <= limit {
+		return line
+	}
+
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := utf8.RuneLen(r)
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
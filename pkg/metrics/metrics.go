@@ -0,0 +1,123 @@
+// Package metrics содержит метрики Prometheus, которые отдаются на /metrics.
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "gift_bot"
+
+var (
+	// TelegramUpdates — входящие обновления. type: message, callback или other; command — команда из сообщения
+	// (известные команды как есть, остальные — unknown, текст без команды — пусто).
+	TelegramUpdates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "telegram_updates_total",
+		Help:      "Telegram updates received, by update type and command.",
+	}, []string{"type", "command"})
+
+	UpdateDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "telegram_update_duration_seconds",
+		Help:      "Time spent handling a Telegram update.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"type"})
+
+	RateLimited = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "telegram_rate_limited_total",
+		Help:      "Telegram updates dropped by the per-chat rate limiter.",
+	})
+
+	MessagesSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "telegram_messages_sent_total",
+		Help:      "Messages successfully sent through the Bot API, by method.",
+	}, []string{"method"})
+
+	// MessagesFailed — неудачные отправки. class: forbidden (бот заблокирован пользователем), rate_limited,
+	// bad_request, server_error, network или other.
+	MessagesFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "telegram_messages_failed_total",
+		Help:      "Messages the Bot API did not accept, by method and error class.",
+	}, []string{"method", "class"})
+
+	DBReconnects = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_reconnects_total",
+		Help:      "Database reconnects after a failed ping, by result.",
+	}, []string{"result"})
+
+	JobRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "job_runs_total",
+		Help:      "Scheduled job runs, by job and outcome.",
+	}, []string{"job", "outcome"})
+
+	JobDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "job_duration_seconds",
+		Help:      "Scheduled job run duration.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 15, 30, 60, 120, 300, 600},
+	}, []string{"job"})
+
+	// Leader — 1, если экземпляр держит advisory-блокировку и выполняет задачи и опрос Telegram.
+	Leader = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "leader",
+		Help:      "Whether this instance is the elected leader (1) or a standby (0).",
+	})
+
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP handler latency, by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+)
+
+// ObserveJob записывает длительность и исход запуска задачи.
+func ObserveJob(job string, started time.Time, err error) {
+	JobDuration.WithLabelValues(job).Observe(time.Since(started).Seconds())
+	outcome := "success"
+	if err != nil {
+		outcome = "failure"
+	}
+	JobRuns.WithLabelValues(job, outcome).Inc()
+}
+
+// SendErrorClass относит HTTP-статус ответа Bot API к классу ошибки.
+func SendErrorClass(status int) string {
+	switch {
+	case status == 403:
+		return "forbidden"
+	case status == 429:
+		return "rate_limited"
+	case status == 400:
+		return "bad_request"
+	case status >= 500:
+		return "server_error"
+	}
+	return "other"
+}
+
+// Gin измеряет время обработки HTTP-запросов. Маршрут берётся из шаблона (/api/users/:telegram_id),
+// а не из пути, чтобы число серий не росло; запросы мимо маршрутов попадают в route="unmatched".
+func Gin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		HTTPRequestDuration.WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(started).Seconds())
+	}
+}
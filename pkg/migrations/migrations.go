@@ -0,0 +1,141 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"gift-bot/db"
+	"gift-bot/pkg/config"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/jmoiron/sqlx"
+)
+
+// Migrator применяет встроенные миграции (пакет gift-bot/db) к БД выбранного хранилища.
+type Migrator struct {
+	m      *migrate.Migrate
+	source source.Driver
+	// conn — соединение Postgres, отданное под миграции. Для SQLite nil: драйвер migrate
+	// закрыл бы вместе с собой всю БД, поэтому Close его не вызывает
+	conn *sql.Conn
+}
+
+// Status — состояние схемы: применённая версия и последняя из встроенных миграций.
+type Status struct {
+	// Version — 0, если не применено ни одной миграции
+	Version uint
+	// Dirty — миграция Version упала на середине; схему нужно поправить вручную и выполнить Force
+	Dirty  bool
+	Latest uint
+}
+
+// New готовит миграции для хранилища storage (config.StoragePostgres или config.StorageSQLite).
+func New(ctx context.Context, sqlDB *sqlx.DB, storage string) (*Migrator, error) {
+	var (
+		files  fs.FS
+		dir    string
+		driver database.Driver
+		conn   *sql.Conn
+		err    error
+	)
+	switch storage {
+	case config.StorageSQLite:
+		files, dir = db.SQLiteMigrations, "migrations_sqlite"
+		driver, err = sqlite3.WithInstance(sqlDB.DB, &sqlite3.Config{})
+	case config.StoragePostgres:
+		files, dir = db.PostgresMigrations, "migrations"
+		// Отдельное соединение, а не весь пул: postgres.WithInstance занял бы соединение до закрытия пула
+		if conn, err = sqlDB.Conn(ctx); err != nil {
+			return nil, fmt.Errorf("get db connection: %w", err)
+		}
+		driver, err = postgres.WithConnection(ctx, conn, &postgres.Config{})
+	default:
+		return nil, fmt.Errorf("unknown storage %q", storage)
+	}
+	if err != nil {
+		closeConn(conn)
+		return nil, fmt.Errorf("create %s migrate driver: %w", storage, err)
+	}
+
+	src, err := iofs.New(files, dir)
+	if err != nil {
+		closeConn(conn)
+		return nil, fmt.Errorf("open embedded migrations: %w", err)
+	}
+	m, err := migrate.NewWithInstance("iofs", src, storage, driver)
+	if err != nil {
+		closeConn(conn)
+		return nil, fmt.Errorf("create migrate instance: %w", err)
+	}
+	return &Migrator{m: m, source: src, conn: conn}, nil
+}
+
+// Up применяет все неприменённые миграции. Если применять нечего, возвращает nil.
+func (m *Migrator) Up() error {
+	if err := m.m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return err
+	}
+	return nil
+}
+
+// Down откатывает steps последних миграций.
+func (m *Migrator) Down(steps int) error {
+	if steps <= 0 {
+		return fmt.Errorf("steps must be positive, got %d", steps)
+	}
+	err := m.m.Steps(-steps)
+	var short migrate.ErrShortLimit
+	switch {
+	case errors.As(err, &short):
+		return fmt.Errorf("rolled back %d of %d migrations: no more applied", uint(steps)-short.Short, steps)
+	case errors.Is(err, os.ErrNotExist):
+		return errors.New("no applied migrations to roll back")
+	}
+	return err
+}
+
+// Force записывает version как применённую и снимает признак dirty, не выполняя миграций.
+func (m *Migrator) Force(version int) error {
+	return m.m.Force(version)
+}
+
+func (m *Migrator) Status() (Status, error) {
+	var st Status
+	version, dirty, err := m.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return st, err
+	}
+	st.Version, st.Dirty = version, dirty
+
+	latest, err := m.source.First()
+	for err == nil {
+		st.Latest = latest
+		latest, err = m.source.Next(latest)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return st, fmt.Errorf("read embedded migrations: %w", err)
+	}
+	return st, nil
+}
+
+// Close освобождает соединение, занятое под миграции. Саму БД не закрывает.
+func (m *Migrator) Close() error {
+	if m.conn == nil {
+		return nil
+	}
+	return m.conn.Close()
+}
+
+func closeConn(conn *sql.Conn) {
+	if conn != nil {
+		_ = conn.Close()
+	}
+}
@@ -1,16 +1,425 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type User struct {
-	ID         int64     `json:"id" db:"id"`
-	TelegramID int64     `json:"telegram_id" db:"telegram_id"`
-	Username   string    `json:"username" db:"username"`
-	FirstName  string    `json:"first_name" db:"first_name"`
-	LastName   string    `json:"last_name" db:"last_name"`
-	Role       string    `json:"role" db:"role"`
-	Birthdate  time.Time `json:"birthdate" db:"birthdate"`
-	CreatedAt  time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
-	Blocked    bool      `json:"blocked" db:"blocked"`
+	ID           int64     `json:"id" db:"id"`
+	TelegramID   int64     `json:"telegram_id" db:"telegram_id"`
+	Username     string    `json:"username" db:"username"`
+	FirstName    string    `json:"first_name" db:"first_name"`
+	LastName     string    `json:"last_name" db:"last_name"`
+	Role         string    `json:"role" db:"role"`
+	Team         string    `json:"team" db:"team"`
+	Birthdate    time.Time `json:"birthdate" db:"birthdate"`
+	HideBirthday bool      `json:"hide_birthday" db:"hide_birthday"`
+	// DisplayName — имя, которое пользователь задал сам; если пусто, используется имя из Telegram
+	DisplayName     string    `json:"display_name" db:"display_name"`
+	NotifyHolidays  bool      `json:"notify_holidays" db:"notify_holidays"`
+	NotifyReminders bool      `json:"notify_reminders" db:"notify_reminders"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`
+	Blocked         bool      `json:"blocked" db:"blocked"`
+	Status          string    `json:"status" db:"status"`
+	ApprovedBy      *int64    `json:"approved_by,omitempty" db:"approved_by"`
+}
+
+// Роли пользователей. Владелец (owner) — единственный и не может быть снят или заблокирован через бота.
+const (
+	RoleOwner     = "owner"
+	RoleAdmin     = "admin"
+	RoleOrganiser = "organiser"
+	RoleUser      = "user"
+)
+
+// Статусы регистрации пользователя: pending — заявка ждёт решения администратора.
+const (
+	UserStatusActive   = "active"
+	UserStatusPending  = "pending"
+	UserStatusRejected = "rejected"
+)
+
+const (
+	SantaStatusRegistration = "registration"
+	SantaStatusDrawn        = "drawn"
+	SantaStatusClosed       = "closed"
+)
+
+type SantaEvent struct {
+	ID                   int64      `json:"id" db:"id"`
+	Title                string     `json:"title" db:"title"`
+	Year                 int        `json:"year" db:"year"`
+	Status               string     `json:"status" db:"status"`
+	ExcludeSameTeam      bool       `json:"exclude_same_team" db:"exclude_same_team"`
+	ExcludePreviousPairs bool       `json:"exclude_previous_pairs" db:"exclude_previous_pairs"`
+	CreatedBy            int64      `json:"created_by" db:"created_by"`
+	DrawDigest           string     `json:"draw_digest" db:"draw_digest"`
+	CreatedAt            time.Time  `json:"created_at" db:"created_at"`
+	DrawnAt              *time.Time `json:"drawn_at" db:"drawn_at"`
+}
+
+type SantaPair struct {
+	EventID             int64      `json:"event_id" db:"event_id"`
+	GiverTelegramID     int64      `json:"giver_telegram_id" db:"giver_telegram_id"`
+	RecipientTelegramID int64      `json:"recipient_telegram_id" db:"recipient_telegram_id"`
+	NotifiedAt          *time.Time `json:"notified_at" db:"notified_at"`
+}
+
+type SantaExclusion struct {
+	EventID          int64 `json:"event_id" db:"event_id"`
+	FirstTelegramID  int64 `json:"first_telegram_id" db:"first_telegram_id"`
+	SecondTelegramID int64 `json:"second_telegram_id" db:"second_telegram_id"`
+}
+
+// SantaAudit содержит агрегированную проверку жеребьёвки без раскрытия пар.
+type SantaAudit struct {
+	Event                  SantaEvent `json:"event"`
+	Participants           int        `json:"participants"`
+	Pairs                  int        `json:"pairs"`
+	Notified               int        `json:"notified"`
+	SelfPairs              int        `json:"self_pairs"`
+	GiversWithoutPair      int        `json:"givers_without_pair"`
+	RecipientsWithoutPair  int        `json:"recipients_without_pair"`
+	TeamViolations         int        `json:"team_violations"`
+	PreviousPairViolations int        `json:"previous_pair_violations"`
+	ExclusionViolations    int        `json:"exclusion_violations"`
+	DigestValid            bool       `json:"digest_valid"`
+}
+
+const OccasionTypeBirthday = "birthday"
+
+type OccasionType struct {
+	Code             string `json:"code" db:"code"`
+	Title            string `json:"title" db:"title"`
+	Recurring        bool   `json:"recurring" db:"recurring"`
+	LeadDays         int    `json:"lead_days" db:"lead_days"`
+	ReminderTemplate string `json:"reminder_template" db:"reminder_template"`
+}
+
+// Occasion — событие, привязанное к пользователю. Дни рождения хранятся в users.birthdate
+// и представляются как Occasion с типом birthday и нулевым ID.
+type Occasion struct {
+	ID             int64     `json:"id" db:"id"`
+	UserTelegramID int64     `json:"user_telegram_id" db:"user_telegram_id"`
+	TypeCode       string    `json:"type_code" db:"type_code"`
+	Title          string    `json:"title" db:"title"`
+	Date           time.Time `json:"date" db:"occasion_date"`
+	CreatedBy      int64     `json:"created_by" db:"created_by"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+}
+
+// UpcomingOccasion — ближайшее наступление события относительно заданной даты.
+type UpcomingOccasion struct {
+	Occasion Occasion     `json:"occasion"`
+	Type     OccasionType `json:"type"`
+	User     User         `json:"user"`
+	NextDate time.Time    `json:"next_date"`
+	DaysLeft int          `json:"days_left"`
+	Years    int          `json:"years"`
+}
+
+// Holiday — праздник компании. Year = 0 означает ежегодный праздник.
+type Holiday struct {
+	ID               int64     `json:"id" db:"id"`
+	Code             string    `json:"code" db:"code"`
+	Name             string    `json:"name" db:"name"`
+	Month            int       `json:"month" db:"month"`
+	Day              int       `json:"day" db:"day"`
+	Year             int       `json:"year" db:"year"`
+	Greeting         string    `json:"greeting" db:"greeting"`
+	GreetingOverride string    `json:"greeting_override" db:"greeting_override"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type UpcomingHoliday struct {
+	Holiday  Holiday   `json:"holiday"`
+	NextDate time.Time `json:"next_date"`
+	DaysLeft int       `json:"days_left"`
+}
+
+type InviteCode struct {
+	ID        int64     `json:"id" db:"id"`
+	Code      string    `json:"code" db:"code"`
+	CreatedBy int64     `json:"created_by" db:"created_by"`
+	Team      string    `json:"team" db:"team"`
+	MaxUses   int       `json:"max_uses" db:"max_uses"`
+	Uses      int       `json:"uses" db:"uses"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+	Revoked   bool      `json:"revoked" db:"revoked"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// UserDataExport — все данные о пользователе, которые хранит бот. Отдаётся по /export_my_data.
+type UserDataExport struct {
+	ExportedAt            time.Time              `json:"exported_at"`
+	Profile               User                   `json:"profile"`
+	HasCalendarToken      bool                   `json:"has_calendar_token"`
+	Occasions             []Occasion             `json:"occasions"`
+	BirthdayNotifications []BirthdayNotification `json:"birthday_notifications"`
+	OccasionNotifications []OccasionNotification `json:"occasion_notifications"`
+	HolidayDeliveries     []HolidayDelivery      `json:"holiday_deliveries"`
+	SantaParticipations   []SantaParticipation   `json:"santa_participations"`
+	SantaExclusions       []SantaExclusion       `json:"santa_exclusions"`
+	InviteCodes           []InviteCode           `json:"invite_codes"`
+}
+
+// BirthdayNotification — отметка о напоминании администратору про день рождения пользователя.
+type BirthdayNotification struct {
+	AdminTelegramID int64     `json:"admin_telegram_id" db:"admin_telegram_id"`
+	UserTelegramID  int64     `json:"user_telegram_id" db:"user_telegram_id"`
+	NotifyDate      time.Time `json:"notify_date" db:"notify_date"`
+}
+
+type OccasionNotification struct {
+	AdminTelegramID int64     `json:"admin_telegram_id" db:"admin_telegram_id"`
+	OccasionID      int64     `json:"occasion_id" db:"occasion_id"`
+	NotifyDate      time.Time `json:"notify_date" db:"notify_date"`
+}
+
+type HolidayDelivery struct {
+	HolidayID   int64     `json:"holiday_id" db:"holiday_id"`
+	HolidayName string    `json:"holiday_name" db:"holiday_name"`
+	GreetDate   time.Time `json:"greet_date" db:"greet_date"`
+}
+
+// SantaParticipation — участие в Тайном Санте. Получатель указывается только для самого дарителя,
+// кто дарит подарок пользователю, в выгрузку не попадает.
+type SantaParticipation struct {
+	EventID             int64     `json:"event_id" db:"event_id"`
+	Title               string    `json:"title" db:"title"`
+	Year                int       `json:"year" db:"year"`
+	JoinedAt            time.Time `json:"joined_at" db:"joined_at"`
+	RecipientTelegramID *int64    `json:"recipient_telegram_id,omitempty" db:"recipient_telegram_id"`
+}
+
+// LoginAttempt — неудачные попытки ввода секретного слова и блокировка входа для чата.
+type LoginAttempt struct {
+	TelegramID     int64      `json:"telegram_id" db:"telegram_id"`
+	Username       string     `json:"username" db:"username"`
+	FailedAttempts int        `json:"failed_attempts" db:"failed_attempts"`
+	Lockouts       int        `json:"lockouts" db:"lockouts"`
+	LockedUntil    *time.Time `json:"locked_until" db:"locked_until"`
+	LastAttemptAt  time.Time  `json:"last_attempt_at" db:"last_attempt_at"`
+}
+
+// Действия администраторов, которые попадают в журнал аудита.
+const (
+	AuditActionBlock               = "block"
+	AuditActionUnblock             = "unblock"
+	AuditActionAdminAdd            = "admin_add"
+	AuditActionAdminRemove         = "admin_remove"
+	AuditActionRoleChange          = "role_change"
+	AuditActionBroadcast           = "broadcast"
+	AuditActionRegistrationApprove = "registration_approve"
+	AuditActionRegistrationReject  = "registration_reject"
+	AuditActionInviteCreate        = "invite_create"
+	AuditActionInviteRevoke        = "invite_revoke"
+	AuditActionOffboard            = "offboard"
+	AuditActionLoginUnlock         = "login_unlock"
+	AuditActionUserUpdate          = "user_update"
+	AuditActionAPIKeyCreate        = "api_key_create"
+	AuditActionAPIKeyRevoke        = "api_key_revoke"
+	AuditActionJobRun              = "job_run"
+)
+
+// AuditActorAPI — автор событий, выполненных через HTTP API, а не из Telegram.
+const AuditActorAPI int64 = -1
+
+// AuditEvent — запись журнала: кто (actor) что сделал (action) с кем (target).
+type AuditEvent struct {
+	ID               int64           `json:"id" db:"id"`
+	ActorTelegramID  int64           `json:"actor_telegram_id" db:"actor_telegram_id"`
+	Action           string          `json:"action" db:"action"`
+	TargetTelegramID *int64          `json:"target_telegram_id,omitempty" db:"target_telegram_id"`
+	Payload          json.RawMessage `json:"payload" db:"payload"`
+	CreatedAt        time.Time       `json:"created_at" db:"created_at"`
+}
+
+// Области доступа API-ключей. admin включает все остальные.
+const (
+	APIScopeReadUsers  = "read:users"
+	APIScopeWriteUsers = "write:users"
+	APIScopeBroadcast  = "broadcast"
+	APIScopeAdmin      = "admin"
+)
+
+// APIKey — ключ доступа к HTTP API. Сам ключ не хранится, только его SHA-256 и первые символы для узнавания.
+type APIKey struct {
+	ID         int64      `json:"id" db:"id"`
+	Name       string     `json:"name" db:"name"`
+	KeyPrefix  string     `json:"key_prefix" db:"key_prefix"`
+	Scopes     []string   `json:"scopes" db:"scopes"`
+	CreatedBy  int64      `json:"created_by" db:"created_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// Аудитория рассылки: все активные пользователи, участники команд или явный список.
+const (
+	BroadcastAudienceAll   = "all"
+	BroadcastAudienceTeams = "teams"
+	BroadcastAudienceUsers = "users"
+)
+
+const (
+	BroadcastStatusScheduled = "scheduled"
+	BroadcastStatusSending   = "sending"
+	BroadcastStatusDone      = "done"
+)
+
+const (
+	DeliveryStatusPending = "pending"
+	DeliveryStatusSent    = "sent"
+	DeliveryStatusFailed  = "failed"
+)
+
+// Broadcast — рассылка из /message или через API. Получатели определяются в момент отправки.
+type Broadcast struct {
+	ID int64 `json:"id" db:"id"`
+	// Text — текст сообщения; если IsTemplate, в нём подставляются данные получателя
+	Text        string     `json:"text" db:"text"`
+	IsTemplate  bool       `json:"is_template" db:"is_template"`
+	Audience    string     `json:"audience" db:"audience"`
+	Teams       []string   `json:"teams" db:"teams"`
+	UserIDs     []int64    `json:"user_ids" db:"user_ids"`
+	ExcludedIDs []int64    `json:"excluded_ids" db:"excluded_ids"`
+	Status      string     `json:"status" db:"status"`
+	ScheduledAt time.Time  `json:"scheduled_at" db:"scheduled_at"`
+	CreatedBy   int64      `json:"created_by" db:"created_by"`
+	APIKeyID    *int64     `json:"api_key_id,omitempty" db:"api_key_id"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// BroadcastStats — сводка доставки рассылки по получателям.
+type BroadcastStats struct {
+	Total   int `json:"total"`
+	Pending int `json:"pending"`
+	Sent    int `json:"sent"`
+	Failed  int `json:"failed"`
+}
+
+// UserFilter — параметры поиска пользователей в API. Пустые поля не ограничивают выборку.
+type UserFilter struct {
+	// Query ищет подстроку в нике, имени, фамилии и отображаемом имени
+	Query   string
+	Role    string
+	Team    string
+	Status  string
+	Blocked *bool
+	Limit   int
+	Offset  int
+}
+
+// UserPatch — поля пользователя, которые можно изменить через API. nil означает «не менять».
+type UserPatch struct {
+	FirstName       *string
+	LastName        *string
+	DisplayName     *string
+	Team            *string
+	Birthdate       *time.Time
+	HideBirthday    *bool
+	NotifyHolidays  *bool
+	NotifyReminders *bool
+}
+
+// AuditFilter — условия выборки журнала. Нулевые значения означают «без фильтра».
+// TelegramID совпадает и с автором, и с объектом действия.
+type AuditFilter struct {
+	Action           string
+	ActorTelegramID  int64
+	TargetTelegramID int64
+	TelegramID       int64
+	Since            time.Time
+	Until            time.Time
+	Limit            int
+	Offset           int
+}
+
+// Запуски периодических задач: по расписанию, вручную командой /job_run или догоняющий —
+// за время, пропущенное, пока бот не работал.
+const (
+	JobTriggerSchedule = "schedule"
+	JobTriggerManual   = "manual"
+	JobTriggerCatchUp  = "catch_up"
+)
+
+const (
+	JobRunStatusRunning = "running"
+	JobRunStatusSuccess = "success"
+	JobRunStatusFailure = "failure"
+)
+
+// JobRun — запись о запуске задачи в таблице job_runs. ScheduledFor — логическое время запуска по расписанию;
+// у догоняющего запуска оно раньше StartedAt.
+type JobRun struct {
+	ID           int64      `json:"id" db:"id"`
+	Job          string     `json:"job" db:"job"`
+	Trigger      string     `json:"trigger" db:"trigger"`
+	Status       string     `json:"status" db:"status"`
+	Error        string     `json:"error" db:"error"`
+	ScheduledFor time.Time  `json:"scheduled_for" db:"scheduled_for"`
+	StartedAt    time.Time  `json:"started_at" db:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+}
+
+// JobInfo — задача планировщика для /jobs: расписание, ближайший и последний запуск.
+type JobInfo struct {
+	Name    string
+	Spec    string
+	Next    time.Time
+	Running bool
+	LastRun *JobRun
+}
+
+const (
+	HealthStatusOK      = "ok"
+	HealthStatusFail    = "fail"
+	HealthStatusOverdue = "overdue"
+	// HealthStatusStandby — резервный экземпляр не опрашивает Telegram и не выполняет задачи, это не ошибка
+	HealthStatusStandby = "standby"
+)
+
+const (
+	InstanceRoleLeader  = "leader"
+	InstanceRoleStandby = "standby"
+)
+
+// HealthReport — ответ /healthz и /readyz: состояние зависимостей и периодических задач.
+type HealthReport struct {
+	Status    string         `json:"status"`
+	StartedAt time.Time      `json:"started_at"`
+	Role      string         `json:"role"`
+	Database  DatabaseHealth `json:"database"`
+	Telegram  TelegramHealth `json:"telegram"`
+	Jobs      []JobHealth    `json:"jobs"`
+}
+
+type DatabaseHealth struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// TelegramHealth — состояние long polling: когда последний getUpdates завершился успешно и когда пришло последнее обновление.
+type TelegramHealth struct {
+	Status       string     `json:"status"`
+	LastPollAt   *time.Time `json:"last_poll_at,omitempty"`
+	LastUpdateAt *time.Time `json:"last_update_at,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+}
+
+// JobHealth — последний запуск периодической задачи. Interval — ожидаемый период между запусками.
+type JobHealth struct {
+	Name          string     `json:"name"`
+	Status        string     `json:"status"`
+	Interval      string     `json:"interval"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+	LastRunAt     *time.Time `json:"last_run_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
 }
@@ -0,0 +1,127 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Elector выбирает ведущий экземпляр через сессионную advisory-блокировку pg_try_advisory_lock.
+// Блокировка живёт, пока открыто соединение, на котором её взяли, поэтому Elector держит
+// отдельное соединение из пула и проверяет его каждые interval. Если соединение потеряно
+// или Manager заменил пул после переподключения, Elector отпускает блокировку и пытается взять её снова.
+type Elector struct {
+	manager  *Manager
+	key      int64
+	interval time.Duration
+
+	conn   *sql.Conn
+	db     *sqlx.DB
+	leader bool
+}
+
+func (m *Manager) NewElector(key int64, interval time.Duration) *Elector {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &Elector{manager: m, key: key, interval: interval}
+}
+
+// Run проверяет блокировку до отмены ctx и вызывает onChange при каждой смене роли.
+// Первая проверка выполняется сразу. При выходе блокировка отпускается и onChange получает false.
+func (e *Elector) Run(ctx context.Context, onChange func(leader bool)) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		leader := e.check(ctx)
+		if leader != e.leader {
+			e.leader = leader
+			e.logf("Leader lock %d: leader=%t", e.key, leader)
+			onChange(leader)
+		}
+
+		select {
+		case <-ctx.Done():
+			e.release()
+			if e.leader {
+				e.leader = false
+				onChange(false)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check возвращает, держит ли экземпляр блокировку после проверки.
+func (e *Elector) check(ctx context.Context) bool {
+	db := e.manager.DB()
+	if e.conn != nil {
+		switch {
+		case e.db != db:
+			e.logf("Leader lock %d: DB connection replaced, re-acquiring", e.key)
+			e.release()
+		default:
+			err := e.ping(ctx)
+			if err == nil {
+				return true
+			}
+			e.logf("Leader lock %d: connection lost: %v", e.key, err)
+			e.release()
+		}
+	}
+	if db == nil {
+		return false
+	}
+
+	acquired, err := e.tryLock(ctx, db)
+	if err != nil {
+		e.logf("Leader lock %d: can't acquire: %v", e.key, err)
+		return false
+	}
+	return acquired
+}
+
+func (e *Elector) ping(ctx context.Context) error {
+	if e.manager.cfg.PingTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.manager.cfg.PingTimeout)
+		defer cancel()
+	}
+	return e.conn.PingContext(ctx)
+}
+
+func (e *Elector) tryLock(ctx context.Context, db *sqlx.DB) (bool, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", e.key).Scan(&acquired); err != nil || !acquired {
+		_ = conn.Close()
+		return false, err
+	}
+	e.conn, e.db = conn, db
+	return true, nil
+}
+
+// release закрывает соединение с блокировкой. Соединение не возвращается в пул, а разрывается:
+// вместе с сессией Postgres снимает и блокировку, даже если pg_advisory_unlock уже не дойдёт до сервера.
+func (e *Elector) release() {
+	if e.conn == nil {
+		return
+	}
+	_ = e.conn.Raw(func(any) error { return driver.ErrBadConn })
+	_ = e.conn.Close()
+	e.conn, e.db = nil, nil
+}
+
+func (e *Elector) logf(format string, args ...any) {
+	if e.manager.logf != nil {
+		e.manager.logf(format, args...)
+	}
+}
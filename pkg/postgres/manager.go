@@ -2,10 +2,12 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"time"
 
 	"gift-bot/pkg/config"
+	"gift-bot/pkg/metrics"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -43,6 +45,17 @@ func (m *Manager) DB() *sqlx.DB {
 	return m.db
 }
 
+// Ping проверяет текущее соединение с таймаутом из RetryConfig.PingTimeout.
+func (m *Manager) Ping(ctx context.Context) error {
+	db := m.DB()
+	if db == nil {
+		return errors.New("db: not connected")
+	}
+	ctx, cancel := context.WithTimeout(ctx, m.cfg.PingTimeout)
+	defer cancel()
+	return db.PingContext(ctx)
+}
+
 func (m *Manager) Reconnect(ctx context.Context) error {
 	db, err := ConnectWithRetry(ctx, m.driver, m.dsn, m.cfg, m.logf)
 	if err != nil {
@@ -85,7 +98,7 @@ func (m *Manager) MonitorAndReconnect(ctx context.Context, interval time.Duratio
 		case <-ticker.C:
 			db := m.DB()
 			if db == nil {
-				_ = m.Reconnect(ctx)
+				m.reconnect(ctx)
 				continue
 			}
 			pingCtx := ctx
@@ -99,10 +112,20 @@ func (m *Manager) MonitorAndReconnect(ctx context.Context, interval time.Duratio
 				if m.logf != nil {
 					m.logf("DB ping failed: %v", err)
 				}
-				if err := m.Reconnect(ctx); err != nil && m.logf != nil {
-					m.logf("DB reconnect failed: %v", err)
-				}
+				m.reconnect(ctx)
 			}
 		}
 	}
 }
+
+// reconnect переподключается из MonitorAndReconnect и учитывает попытку в метриках.
+func (m *Manager) reconnect(ctx context.Context) {
+	if err := m.Reconnect(ctx); err != nil {
+		metrics.DBReconnects.WithLabelValues("error").Inc()
+		if m.logf != nil {
+			m.logf("DB reconnect failed: %v", err)
+		}
+		return
+	}
+	metrics.DBReconnects.WithLabelValues("ok").Inc()
+}
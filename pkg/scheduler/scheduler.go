@@ -0,0 +1,358 @@
+// Package scheduler запускает периодические задачи по cron-расписанию и записывает каждый запуск.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gift-bot/pkg/metrics"
+	"gift-bot/pkg/models"
+	"github.com/robfig/cron/v3"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrUnknownJob = errors.New("scheduler: unknown job")
+	ErrJobRunning = errors.New("scheduler: job is already running")
+	ErrStopped    = errors.New("scheduler: stopped")
+)
+
+// maxCatchUpRuns ограничивает число догоняющих запусков одной задачи после простоя.
+const maxCatchUpRuns = 10
+
+// Job — задача. ctx отменяется при остановке планировщика. at — логическое время запуска:
+// время по расписанию (в том числе пропущенное, если запуск догоняющий) или текущее время при ручном запуске.
+type Job func(ctx context.Context, at time.Time) error
+
+// Store сохраняет запуски задач (таблица job_runs).
+type Store interface {
+	StartJobRun(ctx context.Context, job, trigger string, scheduledFor, startedAt time.Time) (int64, error)
+	FinishJobRun(ctx context.Context, id int64, status, errText string, finishedAt time.Time) error
+	GetLastScheduledRun(ctx context.Context, job string) (time.Time, error)
+}
+
+// Option настраивает задачу при регистрации.
+type Option func(*entry)
+
+// CatchUp включает догоняющие запуски: при старте задача выполняется за каждое время по расписанию,
+// пропущенное не раньше чем window назад, — по одному разу и с исходным логическим временем.
+// Подходит для идемпотентных задач, которые дедуплицируют результат по дате.
+func CatchUp(window time.Duration) Option {
+	return func(e *entry) {
+		e.catchUpWindow = window
+	}
+}
+
+type entry struct {
+	name          string
+	spec          string
+	schedule      cron.Schedule
+	job           Job
+	id            cron.EntryID
+	catchUpWindow time.Duration
+	running       atomic.Bool
+}
+
+type Scheduler struct {
+	cron     *cron.Cron
+	location *time.Location
+	store    Store
+	onFinish func(name string, err error)
+
+	mu    sync.RWMutex
+	jobs  map[string]*entry
+	order []string
+	ctx   context.Context
+	// lease — контекст запусков по расписанию и догоняющих: Pause отменяет его, и выполняющиеся задачи
+	// прерываются, а не продолжаются параллельно с новым ведущим экземпляром
+	lease       context.Context
+	cancelLease context.CancelFunc
+	started     bool
+	stopped     bool
+	paused      atomic.Bool
+	manual      sync.WaitGroup
+}
+
+// New создаёт планировщик; расписания считаются в часовом поясе loc, поэтому переход на летнее время
+// не сдвигает запуски.
+func New(store Store, loc *time.Location) *Scheduler {
+	s := &Scheduler{
+		cron:     cron.New(cron.WithLocation(loc)),
+		location: loc,
+		store:    store,
+		jobs:     make(map[string]*entry),
+		ctx:      context.Background(),
+	}
+	s.renewLease()
+	return s
+}
+
+// renewLease выдаёт новый контекст запусков. Вызывается под s.mu или до начала работы.
+func (s *Scheduler) renewLease() {
+	s.lease, s.cancelLease = context.WithCancel(s.ctx)
+}
+
+// OnFinish задаёт функцию, которая вызывается после каждого запуска.
+func (s *Scheduler) OnFinish(fn func(name string, err error)) {
+	s.onFinish = fn
+}
+
+// Add регистрирует задачу. spec — стандартное cron-выражение из пяти полей или дескриптор (@daily, @every 1m).
+func (s *Scheduler) Add(name, spec string, job Job, opts ...Option) error {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return fmt.Errorf("job %s: invalid schedule %q: %w", name, spec, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[name]; ok {
+		return fmt.Errorf("job %s: already registered", name)
+	}
+	e := &entry{name: name, spec: spec, schedule: schedule, job: job}
+	for _, opt := range opts {
+		opt(e)
+	}
+	s.jobs[name] = e
+	s.order = append(s.order, name)
+	e.id = s.cron.Schedule(schedule, cron.FuncJob(func() {
+		if s.paused.Load() {
+			return
+		}
+		// Перед запуском cron записывает в Prev время, на которое запуск был назначен
+		s.run(s.currentLease(), e, models.JobTriggerSchedule, s.cron.Entry(e.id).Prev)
+	}))
+	return nil
+}
+
+// Start запускает расписание и догоняющие запуски. После отмены ctx новые запуски не начинаются,
+// а выполняющиеся задачи получают отменённый ctx.
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.cancelLease()
+	s.renewLease()
+	if s.paused.Load() {
+		s.cancelLease()
+	}
+	s.started = true
+	s.mu.Unlock()
+	now := time.Now()
+	s.cron.Start()
+	if !s.paused.Load() {
+		s.catchUp(now)
+	}
+	go func() {
+		<-ctx.Done()
+		s.Stop()
+	}()
+}
+
+// Stop останавливает расписание и ждёт завершения выполняющихся задач.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	<-s.cron.Stop().Done()
+	s.manual.Wait()
+}
+
+// Pause приостанавливает запуски по расписанию и догоняющие запуски и отменяет ctx уже выполняющихся.
+// Ручные запуски остаются доступны и не прерываются.
+func (s *Scheduler) Pause() {
+	s.paused.Store(true)
+	s.mu.Lock()
+	s.cancelLease()
+	s.mu.Unlock()
+}
+
+// Resume возобновляет запуски. Если планировщик уже работает, время по расписанию,
+// пропущенное за паузу, догоняется так же, как при старте.
+func (s *Scheduler) Resume() {
+	if !s.paused.Swap(false) {
+		return
+	}
+	s.mu.Lock()
+	s.renewLease()
+	running := s.started && !s.stopped
+	s.mu.Unlock()
+	if running {
+		s.catchUp(time.Now())
+	}
+}
+
+// Trigger запускает задачу вне расписания. Результат приходит в канал после завершения.
+func (s *Scheduler) Trigger(name string) (<-chan error, error) {
+	s.mu.RLock()
+	e, ok := s.jobs[name]
+	stopped := s.stopped
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownJob
+	}
+	if stopped {
+		return nil, ErrStopped
+	}
+	if e.running.Load() {
+		return nil, ErrJobRunning
+	}
+
+	done := make(chan error, 1)
+	s.manual.Add(1)
+	go func() {
+		defer s.manual.Done()
+		s.mu.RLock()
+		ctx := s.ctx
+		s.mu.RUnlock()
+		done <- s.run(ctx, e, models.JobTriggerManual, time.Now())
+	}()
+	return done, nil
+}
+
+// Jobs возвращает задачи в порядке регистрации с ближайшим запуском по расписанию.
+func (s *Scheduler) Jobs() []models.JobInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := time.Now().In(s.location)
+	jobs := make([]models.JobInfo, 0, len(s.order))
+	for _, name := range s.order {
+		e := s.jobs[name]
+		jobs = append(jobs, models.JobInfo{
+			Name:    e.name,
+			Spec:    e.spec,
+			Next:    e.schedule.Next(now),
+			Running: e.running.Load(),
+		})
+	}
+	return jobs
+}
+
+// Interval оценивает период задачи как расстояние между двумя ближайшими запусками.
+func (s *Scheduler) Interval(name string) time.Duration {
+	s.mu.RLock()
+	e, ok := s.jobs[name]
+	s.mu.RUnlock()
+	if !ok {
+		return 0
+	}
+	next := e.schedule.Next(time.Now().In(s.location))
+	return e.schedule.Next(next).Sub(next)
+}
+
+// catchUp запускает задачи за время по расписанию, пропущенное до now. Времена после now достанутся cron.
+func (s *Scheduler) catchUp(now time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, name := range s.order {
+		e := s.jobs[name]
+		if e.catchUpWindow <= 0 {
+			continue
+		}
+		missed, err := s.missedRuns(s.ctx, e, now)
+		if err != nil {
+			log.Errorf("Job %s: can't check missed runs: %v", e.name, err)
+			continue
+		}
+		if len(missed) == 0 {
+			continue
+		}
+		log.Printf("Job %s: catching up %d missed run(s) since %s", e.name, len(missed), missed[0].Format(time.RFC3339))
+		lease := s.lease
+		s.manual.Add(1)
+		go func() {
+			defer s.manual.Done()
+			for _, at := range missed {
+				if s.isStopped() || lease.Err() != nil {
+					return
+				}
+				s.run(lease, e, models.JobTriggerCatchUp, at)
+			}
+		}()
+	}
+}
+
+// missedRuns возвращает времена по расписанию между последним завершённым запуском и now,
+// но не раньше окна догоняющих запусков. Задача, которая ещё ни разу не выполнялась успешно, не догоняется.
+func (s *Scheduler) missedRuns(ctx context.Context, e *entry, now time.Time) ([]time.Time, error) {
+	last, err := s.store.GetLastScheduledRun(ctx, e.name)
+	if err != nil || last.IsZero() {
+		return nil, err
+	}
+	from := last.In(s.location)
+	if windowStart := now.Add(-e.catchUpWindow).In(s.location); windowStart.After(from) {
+		from = windowStart
+	}
+
+	var missed []time.Time
+	for at := e.schedule.Next(from); !at.After(now); at = e.schedule.Next(at) {
+		missed = append(missed, at)
+	}
+	if len(missed) > maxCatchUpRuns {
+		missed = missed[len(missed)-maxCatchUpRuns:]
+	}
+	return missed, nil
+}
+
+func (s *Scheduler) currentLease() context.Context {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lease
+}
+
+func (s *Scheduler) isStopped() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stopped
+}
+
+// run выполняет задачу с контекстом ctx, если предыдущий запуск уже закончился, и записывает результат.
+func (s *Scheduler) run(ctx context.Context, e *entry, trigger string, at time.Time) error {
+	if !e.running.CompareAndSwap(false, true) {
+		log.Warnf("Job %s is still running, %s run skipped", e.name, trigger)
+		return ErrJobRunning
+	}
+	defer e.running.Store(false)
+
+	// Запуск записывается и при остановке бота: отмена ctx прерывает задачу, но не запись её результата
+	storeCtx := context.WithoutCancel(ctx)
+	started := time.Now()
+	log.Printf("Running job %s (%s, %s)", e.name, trigger, at.Format(time.RFC3339))
+	runID, err := s.store.StartJobRun(storeCtx, e.name, trigger, at, started)
+	if err != nil {
+		log.Errorf("Job %s: can't record run start: %v", e.name, err)
+	}
+
+	err = safeRun(ctx, e, at)
+	status, errText := models.JobRunStatusSuccess, ""
+	if err != nil {
+		status, errText = models.JobRunStatusFailure, err.Error()
+		log.Errorf("Job %s failed: %v", e.name, err)
+	}
+	if runID != 0 {
+		if ferr := s.store.FinishJobRun(storeCtx, runID, status, errText, time.Now()); ferr != nil {
+			log.Errorf("Job %s: can't record run result: %v", e.name, ferr)
+		}
+	}
+
+	metrics.ObserveJob(e.name, started, err)
+	if s.onFinish != nil {
+		s.onFinish(e.name, err)
+	}
+	return err
+}
+
+// safeRun превращает панику задачи в ошибку, чтобы она не роняла бота.
+func safeRun(ctx context.Context, e *entry, at time.Time) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("Job %s panicked: %v\n%s", e.name, r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return e.job(ctx, at)
+}
@@ -0,0 +1,325 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"gift-bot/pkg/models"
+)
+
+// fakeStore отдаёт заданное время последнего запуска и запоминает начатые запуски.
+type fakeStore struct {
+	last    time.Time
+	lastErr error
+
+	mu   sync.Mutex
+	runs []fakeRun
+}
+
+type fakeRun struct {
+	job          string
+	trigger      string
+	scheduledFor time.Time
+}
+
+func (f *fakeStore) StartJobRun(ctx context.Context, job, trigger string, scheduledFor, startedAt time.Time) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.runs = append(f.runs, fakeRun{job: job, trigger: trigger, scheduledFor: scheduledFor})
+	return int64(len(f.runs)), nil
+}
+
+func (f *fakeStore) FinishJobRun(ctx context.Context, id int64, status, errText string, finishedAt time.Time) error {
+	return nil
+}
+
+func (f *fakeStore) GetLastScheduledRun(ctx context.Context, job string) (time.Time, error) {
+	return f.last, f.lastErr
+}
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("load location %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestMissedRuns(t *testing.T) {
+	moscow := mustLocation(t, "Europe/Moscow")
+	newYork := mustLocation(t, "America/New_York")
+	at := func(loc *time.Location, month time.Month, day, hour, min int) time.Time {
+		return time.Date(2026, month, day, hour, min, 0, 0, loc)
+	}
+
+	cases := []struct {
+		name   string
+		spec   string
+		loc    *time.Location
+		window time.Duration
+		last   time.Time
+		now    time.Time
+		want   []time.Time
+	}{
+		{
+			name:   "never ran",
+			spec:   "0 9 * * *",
+			loc:    moscow,
+			window: 72 * time.Hour,
+			now:    at(moscow, time.June, 10, 12, 0),
+		},
+		{
+			name:   "nothing missed",
+			spec:   "0 9 * * *",
+			loc:    moscow,
+			window: 72 * time.Hour,
+			last:   at(moscow, time.June, 10, 9, 0),
+			now:    at(moscow, time.June, 10, 12, 0),
+		},
+		{
+			name:   "missed days in schedule zone",
+			spec:   "0 9 * * *",
+			loc:    moscow,
+			window: 72 * time.Hour,
+			last:   at(moscow, time.June, 8, 9, 0),
+			now:    at(moscow, time.June, 10, 12, 0),
+			want:   []time.Time{at(moscow, time.June, 9, 9, 0), at(moscow, time.June, 10, 9, 0)},
+		},
+		{
+			// 09:00 по Москве — 06:00 UTC: последний запуск из базы приходит в UTC
+			name:   "last run stored in utc",
+			spec:   "0 9 * * *",
+			loc:    moscow,
+			window: 72 * time.Hour,
+			last:   time.Date(2026, time.June, 9, 6, 0, 0, 0, time.UTC),
+			now:    at(moscow, time.June, 10, 9, 0),
+			want:   []time.Time{at(moscow, time.June, 10, 9, 0)},
+		},
+		{
+			name:   "window cuts old runs",
+			spec:   "0 9 * * *",
+			loc:    moscow,
+			window: 24 * time.Hour,
+			last:   at(moscow, time.June, 1, 9, 0),
+			now:    at(moscow, time.June, 10, 12, 0),
+			want:   []time.Time{at(moscow, time.June, 10, 9, 0)},
+		},
+		{
+			name:   "limit keeps latest runs",
+			spec:   "0 * * * *",
+			loc:    moscow,
+			window: 24 * time.Hour,
+			last:   at(moscow, time.June, 10, 0, 0),
+			now:    at(moscow, time.June, 10, 15, 30),
+			want: []time.Time{
+				at(moscow, time.June, 10, 6, 0), at(moscow, time.June, 10, 7, 0), at(moscow, time.June, 10, 8, 0),
+				at(moscow, time.June, 10, 9, 0), at(moscow, time.June, 10, 10, 0), at(moscow, time.June, 10, 11, 0),
+				at(moscow, time.June, 10, 12, 0), at(moscow, time.June, 10, 13, 0), at(moscow, time.June, 10, 14, 0),
+				at(moscow, time.June, 10, 15, 0),
+			},
+		},
+		{
+			// Через переход на летнее время запуск остаётся в 09:00 по местному времени
+			name:   "spring forward keeps local time",
+			spec:   "0 9 * * *",
+			loc:    newYork,
+			window: 72 * time.Hour,
+			last:   at(newYork, time.March, 7, 9, 0),
+			now:    at(newYork, time.March, 9, 12, 0),
+			want:   []time.Time{at(newYork, time.March, 8, 9, 0), at(newYork, time.March, 9, 9, 0)},
+		},
+		{
+			// 02:30 8 марта не существует: cron его пропускает, и догонять его не нужно
+			name:   "spring forward skips missing hour",
+			spec:   "30 2 * * *",
+			loc:    newYork,
+			window: 72 * time.Hour,
+			last:   at(newYork, time.March, 7, 2, 30),
+			now:    at(newYork, time.March, 9, 12, 0),
+			want:   []time.Time{at(newYork, time.March, 9, 2, 30)},
+		},
+		{
+			// 01:30 1 ноября наступает дважды, как и у cron: сначала по EDT, затем по EST
+			name:   "fall back repeats hour",
+			spec:   "30 1 * * *",
+			loc:    newYork,
+			window: 72 * time.Hour,
+			last:   at(newYork, time.October, 31, 1, 30),
+			now:    at(newYork, time.November, 1, 12, 0),
+			want: []time.Time{
+				time.Date(2026, time.November, 1, 5, 30, 0, 0, time.UTC),
+				time.Date(2026, time.November, 1, 6, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:   "fall back after first occurrence",
+			spec:   "30 1 * * *",
+			loc:    newYork,
+			window: 72 * time.Hour,
+			last:   time.Date(2026, time.November, 1, 5, 30, 0, 0, time.UTC),
+			now:    at(newYork, time.November, 1, 12, 0),
+			want:   []time.Time{time.Date(2026, time.November, 1, 6, 30, 0, 0, time.UTC)},
+		},
+	}
+	for _, tc := range cases {
+		s := New(&fakeStore{last: tc.last}, tc.loc)
+		if err := s.Add("job", tc.spec, func(context.Context, time.Time) error { return nil }, CatchUp(tc.window)); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		got, err := s.missedRuns(context.Background(), s.jobs["job"], tc.now)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if !sameTimes(got, tc.want) {
+			t.Errorf("%s: missed runs = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestMissedRunsStoreError(t *testing.T) {
+	storeErr := errors.New("db is down")
+	s := New(&fakeStore{lastErr: storeErr}, time.UTC)
+	if err := s.Add("job", "@hourly", func(context.Context, time.Time) error { return nil }, CatchUp(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.missedRuns(context.Background(), s.jobs["job"], time.Now()); !errors.Is(err, storeErr) {
+		t.Fatalf("err = %v, want %v", err, storeErr)
+	}
+}
+
+func TestCatchUp(t *testing.T) {
+	moscow := mustLocation(t, "Europe/Moscow")
+	now := time.Date(2026, time.June, 10, 12, 0, 0, 0, moscow)
+	store := &fakeStore{last: time.Date(2026, time.June, 7, 9, 0, 0, 0, moscow)}
+	s := New(store, moscow)
+
+	var mu sync.Mutex
+	var ran []time.Time
+	job := func(ctx context.Context, at time.Time) error {
+		mu.Lock()
+		defer mu.Unlock()
+		ran = append(ran, at)
+		return nil
+	}
+	noop := func(context.Context, time.Time) error { return nil }
+	mustAdd(t, s.Add("daily", "0 9 * * *", job, CatchUp(48*time.Hour)))
+	mustAdd(t, s.Add("no catch-up", "0 9 * * *", noop))
+
+	s.catchUp(now)
+	s.manual.Wait()
+
+	want := []time.Time{
+		time.Date(2026, time.June, 9, 9, 0, 0, 0, moscow),
+		time.Date(2026, time.June, 10, 9, 0, 0, 0, moscow),
+	}
+	if !sameTimes(ran, want) {
+		t.Fatalf("job ran at %v, want %v", ran, want)
+	}
+	if len(store.runs) != len(want) {
+		t.Fatalf("recorded %d runs, want %d", len(store.runs), len(want))
+	}
+	for i, run := range store.runs {
+		if run.job != "daily" || run.trigger != models.JobTriggerCatchUp || !run.scheduledFor.Equal(want[i]) {
+			t.Errorf("run %d = %+v, want daily catch-up at %s", i, run, want[i])
+		}
+	}
+}
+
+func TestCatchUpPaused(t *testing.T) {
+	store := &fakeStore{last: time.Date(2026, time.June, 7, 9, 0, 0, 0, time.UTC)}
+	s := New(store, time.UTC)
+	mustAdd(t, s.Add("daily", "0 9 * * *", func(context.Context, time.Time) error { return nil }, CatchUp(72*time.Hour)))
+
+	s.Pause()
+	s.catchUp(time.Date(2026, time.June, 10, 12, 0, 0, 0, time.UTC))
+	s.manual.Wait()
+	if len(store.runs) != 0 {
+		t.Fatalf("paused scheduler caught up %d run(s)", len(store.runs))
+	}
+}
+
+func mustAdd(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func sameTimes(got, want []time.Time) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if !got[i].Equal(want[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPauseCancelsScheduledRuns(t *testing.T) {
+	store := &fakeStore{last: time.Date(2026, time.June, 9, 9, 0, 0, 0, time.UTC)}
+	s := New(store, time.UTC)
+
+	// Задача ждёт отмены ctx или сигнала release
+	blocking := func(started chan<- struct{}, release <-chan struct{}) Job {
+		return func(ctx context.Context, _ time.Time) error {
+			started <- struct{}{}
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-release:
+				return nil
+			}
+		}
+	}
+	catchUpStarted, manualStarted := make(chan struct{}, 1), make(chan struct{}, 1)
+	release := make(chan struct{})
+	mustAdd(t, s.Add("daily", "0 9 * * *", blocking(catchUpStarted, release), CatchUp(72*time.Hour)))
+	mustAdd(t, s.Add("manual", "0 9 * * *", blocking(manualStarted, release)))
+
+	var mu sync.Mutex
+	results := map[string]error{}
+	s.OnFinish(func(name string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		results[name] = err
+	})
+
+	s.catchUp(time.Date(2026, time.June, 10, 12, 0, 0, 0, time.UTC))
+	<-catchUpStarted
+	done, err := s.Trigger("manual")
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-manualStarted
+
+	s.Pause()
+	s.manual.Add(1)
+	go func() {
+		defer s.manual.Done()
+		// Ручной запуск не прерывается паузой и заканчивается сам
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	if err := <-done; err != nil {
+		t.Errorf("manual run err = %v, want nil", err)
+	}
+	s.manual.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !errors.Is(results["daily"], context.Canceled) {
+		t.Errorf("catch-up run err = %v, want %v", results["daily"], context.Canceled)
+	}
+
+	// После Resume запуски снова получают действующий ctx
+	s.Resume()
+	if err := s.currentLease().Err(); err != nil {
+		t.Errorf("lease after resume: %v", err)
+	}
+}
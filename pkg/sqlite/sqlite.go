@@ -0,0 +1,61 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Параметры соединения go-sqlite3:
+//   - WAL позволяет читать, пока другое соединение пишет;
+//   - busy_timeout ждёт освобождения блокировки вместо немедленной ошибки SQLITE_BUSY;
+//   - txlock=immediate берёт блокировку на запись в начале транзакции: иначе две транзакции,
+//     начавшие с чтения, не смогли бы обе перейти к записи;
+//   - loc=auto возвращает время в местном поясе, как его и записали.
+const dsnParams = "_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate&_foreign_keys=on&_loc=auto"
+
+// Manager — соединение со встроенной БД SQLite. В отличие от postgres.Manager не переподключается:
+// файл БД локальный, и потерять соединение с ним нельзя.
+type Manager struct {
+	db *sqlx.DB
+}
+
+// memoryPath — БД в памяти: живёт, пока открыто соединение, и пропадает при закрытии.
+const memoryPath = ":memory:"
+
+// Open открывает (и при необходимости создаёт) файл БД по пути path. Путь ":memory:" открывает БД в памяти.
+func Open(ctx context.Context, path string) (*Manager, error) {
+	if dir := filepath.Dir(path); dir != "." && path != memoryPath {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("create sqlite dir: %w", err)
+		}
+	}
+	dsn := fmt.Sprintf("file:%s?%s", path, dsnParams)
+	db, err := sqlx.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if path == memoryPath {
+		// У каждого соединения своя БД в памяти, поэтому пул держит ровно одно и не закрывает его
+		db.SetMaxOpenConns(1)
+		db.SetMaxIdleConns(1)
+		db.SetConnMaxLifetime(0)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &Manager{db: db}, nil
+}
+
+func (m *Manager) DB() *sqlx.DB {
+	return m.db
+}
+
+func (m *Manager) Close() error {
+	return m.db.Close()
+}
@@ -3,6 +3,7 @@ package wifi
 import (
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
+	"net"
 	"net/http"
 	"time"
 )
@@ -11,15 +12,19 @@ type Server struct {
 	httpServer *http.Server
 }
 
-func (s *Server) Run(port string, handler http.Handler) error {
+// Run запускает HTTP-сервер на host:port; пустой host — все интерфейсы, иначе опубликованный порт
+// контейнера не достучится до сервера. Контексты запросов наследуются от ctx, поэтому его отмена
+// прерывает запросы к БД в обработчиках.
+func (s *Server) Run(ctx context.Context, host, port string, handler http.Handler) error {
 	s.httpServer = &http.Server{
-		Addr:           "localhost:" + port,
+		Addr:           net.JoinHostPort(host, port),
 		Handler:        handler,
 		ReadTimeout:    10 * time.Second,
 		WriteTimeout:   10 * time.Second,
 		MaxHeaderBytes: 1 << 20,
+		BaseContext:    func(net.Listener) context.Context { return ctx },
 	}
-	log.Infof("Starting HTTP server on port %s", port)
+	log.Infof("Starting HTTP server on %s", s.httpServer.Addr)
 	return s.httpServer.ListenAndServe()
 }
 